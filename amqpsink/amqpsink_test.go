@@ -0,0 +1,117 @@
+package amqpsink
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var _ Channel = (*amqp.Channel)(nil)
+
+type publish struct {
+	exchange, key string
+	msg           amqp.Publishing
+}
+
+// channel confirms every publish unless withheld.
+type channel struct {
+	confirm   bool
+	confirms  chan amqp.Confirmation
+	published []publish
+	tag       uint64
+	nack      bool
+	withhold  []uint64
+}
+
+func (c *channel) Confirm(noWait bool) error {
+	c.confirm = true
+	return nil
+}
+
+func (c *channel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	c.confirms = confirm
+	return confirm
+}
+
+func (c *channel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	c.published = append(c.published, publish{exchange, key, msg})
+	c.tag++
+	if c.withhold != nil {
+		c.withhold = append(c.withhold, c.tag)
+	} else {
+		c.confirms <- amqp.Confirmation{DeliveryTag: c.tag, Ack: !c.nack}
+	}
+	return nil
+}
+
+// release sends the withheld confirms.
+func (c *channel) release() {
+	for _, tag := range c.withhold {
+		c.confirms <- amqp.Confirmation{DeliveryTag: tag, Ack: true}
+	}
+	c.withhold = nil
+}
+
+func TestWriteBatch(t *testing.T) {
+	ch := new(channel)
+	s, err := New(ch, Options{})
+	if err != nil || !ch.confirm {
+		t.Fatalf("expected confirm mode, got %v", err)
+	}
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC)
+	err = s.WriteBatch([]*module.Message{
+		{Module: "auth", Level: module.Warn, Time: t0, ID: "01GJ3Q2V4R8N6YH3T2KXW5Z9CD", RichError: errors.NewRich("login_failed", 1001, "Login failed", "", nil, nil)},
+		{Module: "billing.v2", Level: module.Error, Time: t0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ch.published) != 2 || ch.published[0].exchange != "amq.topic" || ch.published[0].key != "auth.warn" || ch.published[1].key != "billing_v2.error" {
+		t.Fatalf("unexpected publishes %v", ch.published)
+	}
+	p := ch.published[0].msg
+	if p.DeliveryMode != amqp.Persistent || p.MessageId != "01GJ3Q2V4R8N6YH3T2KXW5Z9CD" || p.Type != "login_failed" || !p.Timestamp.Equal(t0) {
+		t.Fatalf("unexpected publishing %+v", p)
+	}
+	if p.Headers["module"] != "auth" || p.Headers["level"] != "warn" || p.Headers["name"] != "login_failed" {
+		t.Fatalf("unexpected headers %v", p.Headers)
+	}
+	var msg module.Message
+	if err := json.Unmarshal(p.Body, &msg); err != nil || msg.Module != "auth" {
+		t.Fatalf("unexpected body %s, %v", p.Body, err)
+	}
+
+	ch.nack = true
+	if err := s.Write(&module.Message{Module: "auth", Level: module.Info, Time: t0}); err == nil {
+		t.Fatal("expected error of nack")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	ch := &channel{withhold: []uint64{}}
+	s, _ := New(ch, Options{Timeout: time.Millisecond, Transient: true})
+	msg := &module.Message{Module: "auth", Level: module.Info, Time: time.Now()}
+	if err := s.WriteBatch([]*module.Message{msg, msg}); err == nil {
+		t.Fatal("expected timeout")
+	}
+	if ch.published[0].msg.DeliveryMode != amqp.Transient {
+		t.Fatalf("unexpected delivery mode %d", ch.published[0].msg.DeliveryMode)
+	}
+
+	// the late confirms of the first batch must not be taken for the ones of the next
+	ch.release()
+	ch.withhold = nil
+	ch.nack = true
+	if err := s.Write(msg); err == nil {
+		t.Fatal("expected error of nack after late confirms")
+	}
+	ch.nack = false
+	if err := s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+}
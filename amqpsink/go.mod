@@ -0,0 +1,16 @@
+module github.com/halliday/go-module/amqpsink
+
+go 1.21
+
+require (
+	github.com/halliday/go-errors v0.0.0-20221117114904-701c88d594be
+	github.com/halliday/go-module v0.0.0-00010101000000-000000000000
+	github.com/rabbitmq/amqp091-go v1.9.0
+)
+
+require (
+	github.com/klauspost/compress v1.17.9 // indirect
+	golang.org/x/text v0.13.0 // indirect
+)
+
+replace github.com/halliday/go-module => ../
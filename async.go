@@ -0,0 +1,99 @@
+package module
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Copy returns a copy of msg that shares no Data or error fields with msg.
+// Values in Data and the causes are shared.
+func (msg *Message) Copy() *Message {
+	c := *msg
+	if msg.RichError != nil {
+		e := *msg.RichError
+		c.RichError = &e
+	}
+	if msg.Data != nil {
+		c.Data = make(map[string]interface{}, len(msg.Data))
+		for key, value := range msg.Data {
+			c.Data[key] = value
+		}
+		if c.RichError != nil {
+			c.RichError.Data = c.Data
+		}
+	}
+	c.keys = append([]string(nil), msg.keys...)
+	c.Frames = append([]Frame(nil), msg.Frames...)
+	return &c
+}
+
+// HookPool runs hooks asynchronously on a fixed number of workers, so slow integrations
+// like error trackers or webhooks never block logging.
+type HookPool struct {
+	jobs    chan hookJob
+	wg      sync.WaitGroup
+	closeMu sync.RWMutex
+	closed  bool
+	dropped int64
+}
+
+type hookJob struct {
+	hook Hook
+	msg  *Message
+}
+
+// NewHookPool starts workers goroutines with a queue of size messages.
+func NewHookPool(workers int, size int) *HookPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	p := &HookPool{jobs: make(chan hookJob, size)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				reporter(job.msg).callHook(context.Background(), job.hook, job.msg)
+			}
+		}()
+	}
+	return p
+}
+
+// Async returns a Hook that passes a copy of every message to hook on the pool and returns
+// the message unchanged, so hook can neither modify nor drop it. A panic of hook is recovered
+// and reported like one of a synchronous hook.
+// If the queue is full or the pool closed, the message is dropped for hook, see Dropped.
+func (p *HookPool) Async(hook Hook) Hook {
+	return func(msg *Message) *Message {
+		p.closeMu.RLock()
+		defer p.closeMu.RUnlock()
+		if p.closed {
+			atomic.AddInt64(&p.dropped, 1)
+			return msg
+		}
+		select {
+		case p.jobs <- hookJob{hook, msg.Copy()}:
+		default:
+			atomic.AddInt64(&p.dropped, 1)
+		}
+		return msg
+	}
+}
+
+// Dropped returns the number of messages that were not passed to async hooks.
+func (p *HookPool) Dropped() int64 {
+	return atomic.LoadInt64(&p.dropped)
+}
+
+// Close stops accepting messages and waits for the queued ones.
+func (p *HookPool) Close() {
+	p.closeMu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.closeMu.Unlock()
+	p.wg.Wait()
+}
@@ -0,0 +1,78 @@
+package module
+
+import (
+	"bytes"
+	"log"
+	"sync"
+	"testing"
+)
+
+func TestHookPool(t *testing.T) {
+	p := NewHookPool(2, 10)
+	var mu sync.Mutex
+	var names []string
+	release := make(chan struct{})
+	m := NewWithOptions("module", messages, WithLogger(log.New(&bytes.Buffer{}, "", 0)), WithHook(p.Async(func(msg *Message) *Message {
+		<-release
+		msg.Set("changed", true)
+		mu.Lock()
+		names = append(names, msg.Name)
+		mu.Unlock()
+		return nil
+	})), WithSinks(SinkFunc(func(msg *Message) error {
+		if _, ok := msg.Data["changed"]; ok {
+			t.Error("async hook changed the message")
+		}
+		return nil
+	})))
+
+	for i := 0; i < 15; i++ {
+		m.Info("test", "A", i)
+	}
+	close(release)
+	p.Close()
+	m.Info("test", "A", 0)
+
+	if len(names) < 10 || int64(len(names))+p.Dropped() != 16 {
+		t.Fatalf("%d messages handled, %d dropped", len(names), p.Dropped())
+	}
+}
+
+func TestCopy(t *testing.T) {
+	m := NewWithOptions("module", messages, WithLogger(log.New(&bytes.Buffer{}, "", 0)))
+	var msg *Message
+	m.Sinks = []Sink{SinkFunc(func(m *Message) error {
+		msg = m
+		return nil
+	})}
+	m.Info("test", "A", 1)
+
+	c := msg.Copy()
+	c.Set("B", 2)
+	c.Desc = "changed"
+	if _, ok := msg.Data["B"]; ok || msg.Desc == "changed" || c.RichError.Data.(map[string]interface{})["B"] != 2 {
+		t.Fatalf("copy shares fields: %v %v", msg, c)
+	}
+}
+
+func TestHookPoolPanic(t *testing.T) {
+	p := NewHookPool(1, 10)
+	var mu sync.Mutex
+	var sunk []string
+	m := NewWithOptions("module", messages, WithLogger(log.New(&bytes.Buffer{}, "", 0)), WithHook(p.Async(panickingHook)),
+		WithSinks(SinkFunc(func(msg *Message) error {
+			mu.Lock()
+			sunk = append(sunk, msg.Name)
+			mu.Unlock()
+			return nil
+		})))
+
+	m.Info("test", "A", 1)
+	p.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sunk) != 2 || sunk[0] != "test" || sunk[1] != "hook_panic" {
+		t.Fatalf("unexpected messages %v", sunk)
+	}
+}
@@ -0,0 +1,80 @@
+package module
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Keys of the audit data contract.
+const (
+	KeyActor    = "actor"
+	KeyAction   = "action"
+	KeyResource = "resource"
+)
+
+// AuditModule is a Module for security relevant events.
+// Its messages are never filtered by the Mask or dropped by processors and hooks, carry a
+// monotonic sequence number and are written to the given sinks, usually append-only files.
+type AuditModule struct {
+	*Module
+}
+
+func NewAudit(name string, messages string, sinks ...Sink) *AuditModule {
+	_, _, m := New(name, messages)
+	m.audit = true
+	m.Sinks = sinks
+	return &AuditModule{m}
+}
+
+// Audit logs an audit event. actor, action and resource must not be empty.
+// The remaining arguments are used like in Log, but must not use the keys of the audit data contract.
+// Audit returns an error if the event could not be written to all sinks.
+func (a *AuditModule) Audit(name string, actor string, action string, resource string, args ...interface{}) error {
+	var missing []string
+	if actor == "" {
+		missing = append(missing, KeyActor)
+	}
+	if action == "" {
+		missing = append(missing, KeyAction)
+	}
+	if resource == "" {
+		missing = append(missing, KeyResource)
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("audit %q: missing %s", name, strings.Join(missing, ", "))
+	}
+	code, desc, link, tail, ctx, causedBy := a.lookup(nil, Info, name, args)
+	tail = pairs(tail)
+	for i := 0; i < len(tail); i += 2 {
+		switch key := tail[i]; key {
+		case KeyActor, KeyAction, KeyResource:
+			return fmt.Errorf("audit %q: reserved key %q", name, key)
+		}
+	}
+	tail = append([]interface{}{KeyActor, actor, KeyAction, action, KeyResource, resource}, tail...)
+	return a.log(ctx, Info, name, code, desc, link, tail, causedBy)
+}
+
+// pairs returns args as key/value pairs, unpacking a single map or slice argument.
+func pairs(args []interface{}) []interface{} {
+	if len(args) != 1 {
+		return args
+	}
+	switch arg := args[0].(type) {
+	case []interface{}:
+		return pairs(arg)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(arg))
+		for key := range arg {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		p := make([]interface{}, 0, 2*len(keys))
+		for _, key := range keys {
+			p = append(p, key, arg[key])
+		}
+		return p
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package module
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := OpenFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	a := NewAudit("audit", messages, sink)
+	a.Logger = log.New(&b, "", 0)
+	a.Mask = 0
+
+	if err := a.Audit("test", "bob", "", "file"); err == nil {
+		t.Fatal("missing action was accepted")
+	}
+	if err := a.Audit("test", "bob", "delete", "file", "actor", "alice"); err == nil {
+		t.Fatal("reserved key was accepted")
+	}
+	if err := a.Audit("test", "bob", "delete", "file", map[string]interface{}{"resource": "other"}); err == nil {
+		t.Fatal("reserved key was accepted")
+	}
+	if err := a.Audit("test", "bob", "delete", "file", "A", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Audit("test2", "bob", "delete", "dir"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.String() != "[INFO ] This is a test message actor=bob action=delete resource=file A=1\n[INFO ] This is a another test message actor=bob action=delete resource=dir\n" {
+		t.Fatalf("unexpected log output: %q", b.String())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var seq uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg struct {
+			Seq  uint64                 `json:"seq"`
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Seq != seq+1 || msg.Data[KeyActor] != "bob" {
+			t.Fatalf("unexpected record: %s", scanner.Bytes())
+		}
+		seq = msg.Seq
+	}
+	if seq != 2 {
+		t.Fatalf("expected 2 records, found %d", seq)
+	}
+}
+
+func TestAuditNotDropped(t *testing.T) {
+	var n int
+	a := NewAudit("audit", messages, SinkFunc(func(msg *Message) error {
+		n++
+		return nil
+	}))
+	a.Logger = log.New(&bytes.Buffer{}, "", 0)
+	a.Processors = append(a.Processors, Drop(func(msg *Message) bool { return true }))
+	a.AddHook(0, func(msg *Message) *Message { return nil })
+
+	if err := a.Audit("test", "bob", "delete", "file", "A", 1); err != nil || n != 1 {
+		t.Fatalf("audit message dropped, %d written: %v", n, err)
+	}
+}
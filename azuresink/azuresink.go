@@ -0,0 +1,163 @@
+// Package azuresink sends module messages to Azure Monitor Logs with the Logs Ingestion API.
+//
+// The messages are posted to a stream of a data collection rule (DCR), which transforms them into
+// a Log Analytics table. The columns of the stream declaration are listed at Record:
+//
+//	sink := azuresink.New(azuresink.Options{
+//		Endpoint: "https://shop-dce-a1b2.westeurope-1.ingest.monitor.azure.com",
+//		RuleID:   "dcr-00000000000000000000000000000000",
+//		Stream:   "Custom-ModuleLogs",
+//		Token:    azuresink.ManagedIdentity(""),
+//	})
+//	m.Sinks = append(m.Sinks, module.NewBatchSink(sink, module.BatchOptions{}))
+package azuresink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	module "github.com/halliday/go-module"
+)
+
+// maxBody is the largest request the API accepts.
+const maxBody = 1 << 20
+
+type Options struct {
+	// Endpoint is the logs ingestion endpoint of the DCR or of a data collection endpoint. Required.
+	Endpoint string
+	// RuleID is the immutable ID of the DCR, like "dcr-...". Required.
+	RuleID string
+	// Stream is the stream declared in the DCR, like "Custom-ModuleLogs". Required.
+	Stream string
+	// Token authenticates the requests, see ManagedIdentity and ClientSecret. Required.
+	Token TokenSource
+	// Client sends the requests. Default http.DefaultClient.
+	Client *http.Client
+	// Context is used to get tokens. Default context.Background().
+	Context context.Context
+}
+
+// Sink is a module.Sink and module.BatchWriter posting messages as Records.
+// Batches are split into requests of at most 1 MB, which are compressed with gzip.
+type Sink struct {
+	url  string
+	opts Options
+}
+
+// New returns a Sink for opts. It panics if a required option is missing.
+func New(opts Options) *Sink {
+	if opts.Endpoint == "" || opts.RuleID == "" || opts.Stream == "" || opts.Token == nil {
+		panic("azuresink.New: Endpoint, RuleID, Stream and Token are required")
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
+	u := opts.Endpoint + "/dataCollectionRules/" + url.PathEscape(opts.RuleID) + "/streams/" + url.PathEscape(opts.Stream) + "?api-version=2023-01-01"
+	return &Sink{url: u, opts: opts}
+}
+
+// Record is a message as sent to the stream, which must declare these columns:
+// TimeGenerated (datetime), Module, Level, Name (string), Code (int), Description, Link, Id, Caller,
+// CausedBy (string) and Data (dynamic).
+type Record struct {
+	TimeGenerated string                 `json:"TimeGenerated"`
+	Module        string                 `json:"Module"`
+	Level         string                 `json:"Level"`
+	Name          string                 `json:"Name,omitempty"`
+	Code          int                    `json:"Code,omitempty"`
+	Description   string                 `json:"Description"`
+	Link          string                 `json:"Link,omitempty"`
+	ID            string                 `json:"Id,omitempty"`
+	Caller        string                 `json:"Caller,omitempty"`
+	CausedBy      string                 `json:"CausedBy,omitempty"`
+	Data          map[string]interface{} `json:"Data,omitempty"`
+}
+
+// NewRecord returns the Record of msg.
+func NewRecord(msg *module.Message) *Record {
+	r := &Record{
+		TimeGenerated: msg.Time.UTC().Format(time.RFC3339Nano),
+		Module:        msg.Module,
+		Level:         msg.Level.String(),
+		ID:            msg.ID,
+		Caller:        msg.Caller,
+		Data:          msg.Data,
+	}
+	if msg.RichError != nil {
+		r.Name, r.Code, r.Description, r.Link = msg.Name, msg.Code, msg.Desc, msg.Link
+		if msg.CausedBy != nil {
+			r.CausedBy = msg.CausedBy.Error()
+		}
+	}
+	return r
+}
+
+func (s *Sink) Write(msg *module.Message) error {
+	return s.WriteBatch([]*module.Message{msg})
+}
+
+func (s *Sink) WriteBatch(msgs []*module.Message) error {
+	var records []json.RawMessage
+	size := 2
+	for _, msg := range msgs {
+		b, err := json.Marshal(NewRecord(msg))
+		if err != nil {
+			return err
+		}
+		if len(records) > 0 && size+len(b)+1 > maxBody {
+			if err := s.post(records); err != nil {
+				return err
+			}
+			records, size = nil, 2
+		}
+		records = append(records, b)
+		size += len(b) + 1
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return s.post(records)
+}
+
+func (s *Sink) post(records []json.RawMessage) error {
+	body, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	if body, err = module.Compress(body, module.Gzip, 0); err != nil {
+		return err
+	}
+	token, err := s.opts.Token.Token(s.opts.Context)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return module.Permanent(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	resp.Body = io.NopCloser(io.LimitReader(resp.Body, 512))
+	err = module.ResponseError(resp)
+	if c, ok := s.opts.Token.(*cachedToken); ok && resp.StatusCode == http.StatusUnauthorized {
+		// the next attempt gets a new token
+		c.reset()
+		return errors.Unwrap(err)
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package azuresink
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+func TestWriteBatch(t *testing.T) {
+	var records [][]Record
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dataCollectionRules/dcr-1/streams/Custom-ModuleLogs" || r.URL.Query().Get("api-version") != "2023-01-01" {
+			t.Errorf("unexpected URL %s", r.URL)
+		}
+		auth = append(auth, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") == "Bearer old" {
+			http.Error(w, "token expired", http.StatusUnauthorized)
+			return
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var batch []Record
+		if err := json.NewDecoder(zr).Decode(&batch); err != nil {
+			t.Error(err)
+		}
+		records = append(records, batch)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	tokens := []string{"old", "new"}
+	var calls int32
+	source := Cache(func(ctx context.Context) (string, time.Time, error) {
+		n := atomic.AddInt32(&calls, 1)
+		return tokens[n-1], time.Now().Add(time.Hour), nil
+	})
+	s := New(Options{Endpoint: srv.URL, RuleID: "dcr-1", Stream: "Custom-ModuleLogs", Token: source})
+
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC)
+	msg := &module.Message{
+		Module:    "auth",
+		Level:     module.Warn,
+		Time:      t0,
+		RichError: errors.NewRich("login_failed", 1001, "Login failed", "", nil, errors.New("bad password")),
+		Data:      map[string]interface{}{"user": "bob"},
+	}
+	err := s.Write(msg)
+	if err == nil || module.IsPermanent(err) {
+		t.Fatalf("expected temporary error for the expired token, got %v", err)
+	}
+	if err := s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(auth) != "[Bearer old Bearer new]" {
+		t.Fatalf("unexpected authorization %v", auth)
+	}
+	want := Record{TimeGenerated: "2022-11-17T12:00:00Z", Module: "auth", Level: "warn", Name: "login_failed", Code: 1001,
+		Description: "Login failed", CausedBy: "bad password", Data: map[string]interface{}{"user": "bob"}}
+	if len(records) != 1 || fmt.Sprint(records[0][0]) != fmt.Sprint(want) {
+		t.Fatalf("unexpected records %v", records)
+	}
+
+	// batches are split at 1 MB
+	records = nil
+	big := strings.Repeat("x", 300<<10)
+	var msgs []*module.Message
+	for i := 0; i < 7; i++ {
+		msgs = append(msgs, &module.Message{Module: "auth", Level: module.Info, Time: t0, Data: map[string]interface{}{"blob": big}})
+	}
+	if err := s.WriteBatch(msgs); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || len(records[0]) != 3 || len(records[2]) != 1 {
+		t.Fatalf("unexpected split into %d requests", len(records))
+	}
+}
@@ -0,0 +1,148 @@
+package azuresink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	module "github.com/halliday/go-module"
+)
+
+// Resource is the audience of the tokens for the Logs Ingestion API.
+const Resource = "https://monitor.azure.com"
+
+// A TokenSource returns access tokens for Resource.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenFunc returns a token and its expiry.
+type TokenFunc func(ctx context.Context) (token string, expires time.Time, err error)
+
+// Cache returns a TokenSource that calls f only when the last token expires within 5 minutes.
+func Cache(f TokenFunc) TokenSource {
+	return &cachedToken{f: f}
+}
+
+type cachedToken struct {
+	f TokenFunc
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (c *cachedToken) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Until(c.expires) > 5*time.Minute {
+		return c.token, nil
+	}
+	token, expires, err := c.f(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expires = token, expires
+	return token, nil
+}
+
+func (c *cachedToken) reset() {
+	c.mu.Lock()
+	c.token = ""
+	c.mu.Unlock()
+}
+
+// imdsURL is the token endpoint of the Azure Instance Metadata Service.
+var imdsURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// ManagedIdentity returns the tokens of the managed identity of the VM, AKS pod, App Service, Functions app
+// or Container App. clientID selects a user-assigned identity; empty means the system-assigned one.
+func ManagedIdentity(clientID string) TokenSource {
+	return Cache(func(ctx context.Context) (string, time.Time, error) {
+		q := url.Values{"resource": {Resource}}
+		if clientID != "" {
+			q.Set("client_id", clientID)
+		}
+		var req *http.Request
+		var err error
+		if endpoint := os.Getenv("IDENTITY_ENDPOINT"); endpoint != "" {
+			// App Service, Functions and Container Apps
+			q.Set("api-version", "2019-08-01")
+			req, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+q.Encode(), nil)
+			if err == nil {
+				req.Header.Set("X-IDENTITY-HEADER", os.Getenv("IDENTITY_HEADER"))
+			}
+		} else {
+			q.Set("api-version", "2018-02-01")
+			req, err = http.NewRequestWithContext(ctx, http.MethodGet, imdsURL+"?"+q.Encode(), nil)
+			if err == nil {
+				req.Header.Set("Metadata", "true")
+			}
+		}
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return requestToken(req)
+	})
+}
+
+// loginURL is the Microsoft identity platform.
+var loginURL = "https://login.microsoftonline.com/"
+
+// ClientSecret returns the tokens of an app registration with a client secret, for hosts outside of Azure.
+func ClientSecret(tenantID, clientID, secret string) TokenSource {
+	return Cache(func(ctx context.Context) (string, time.Time, error) {
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {clientID},
+			"client_secret": {secret},
+			"scope":         {Resource + "/.default"},
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, loginURL+url.PathEscape(tenantID)+"/oauth2/v2.0/token", strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return requestToken(req)
+	})
+}
+
+// requestToken sends req and decodes the access token of the response.
+// The managed identity endpoints send expires_on, the identity platform expires_in.
+func requestToken(req *http.Request) (string, time.Time, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		resp.Body = io.NopCloser(io.LimitReader(resp.Body, 512))
+		return "", time.Time{}, module.ResponseError(resp)
+	}
+	var r struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresOn   json.Number `json:"expires_on"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", time.Time{}, err
+	}
+	if r.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("%s: no access token", req.URL.Redacted())
+	}
+	var expires time.Time
+	if sec, err := strconv.ParseInt(r.ExpiresOn.String(), 10, 64); err == nil {
+		expires = time.Unix(sec, 0)
+	} else if sec, err := strconv.ParseInt(r.ExpiresIn.String(), 10, 64); err == nil {
+		expires = time.Now().Add(time.Duration(sec) * time.Second)
+	}
+	return r.AccessToken, expires, nil
+}
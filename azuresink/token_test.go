@@ -0,0 +1,67 @@
+package azuresink
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestManagedIdentity(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Unix()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("resource") != Resource || q.Get("client_id") != "client-1" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		switch {
+		case r.URL.Path == "/imds" && r.Header.Get("Metadata") == "true" && q.Get("api-version") == "2018-02-01":
+			fmt.Fprintf(w, `{"access_token":"vm-token","expires_on":"%d"}`, expires)
+		case r.URL.Path == "/identity" && r.Header.Get("X-IDENTITY-HEADER") == "secret" && q.Get("api-version") == "2019-08-01":
+			fmt.Fprintf(w, `{"access_token":"app-token","expires_on":%d}`, expires)
+		default:
+			http.Error(w, "bad request", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	defer func(u string) { imdsURL = u }(imdsURL)
+	imdsURL = srv.URL + "/imds"
+
+	t.Setenv("IDENTITY_ENDPOINT", "")
+	token, err := ManagedIdentity("client-1").Token(context.Background())
+	if err != nil || token != "vm-token" {
+		t.Fatalf("unexpected token %q, %v", token, err)
+	}
+
+	t.Setenv("IDENTITY_ENDPOINT", srv.URL+"/identity")
+	t.Setenv("IDENTITY_HEADER", "secret")
+	token, err = ManagedIdentity("client-1").Token(context.Background())
+	if err != nil || token != "app-token" {
+		t.Fatalf("unexpected token %q, %v", token, err)
+	}
+}
+
+func TestClientSecret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.URL.Path != "/tenant-1/oauth2/v2.0/token" || r.Form.Get("client_secret") != "s3cret" || r.Form.Get("scope") != Resource+"/.default" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"sp-token","expires_in":3599}`)
+	}))
+	defer srv.Close()
+	defer func(u string) { loginURL = u }(loginURL)
+	loginURL = srv.URL + "/"
+
+	source := ClientSecret("tenant-1", "client-1", "s3cret")
+	token, err := source.Token(context.Background())
+	if err != nil || token != "sp-token" {
+		t.Fatalf("unexpected token %q, %v", token, err)
+	}
+	if c := source.(*cachedToken); time.Until(c.expires) < 59*time.Minute {
+		t.Fatalf("unexpected expiry %v", c.expires)
+	}
+}
@@ -0,0 +1,178 @@
+package module
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/halliday/go-errors"
+)
+
+// A BatchWriter writes many messages at once, like a network sink with a bulk API.
+type BatchWriter interface {
+	WriteBatch(msgs []*Message) error
+}
+
+type BatchWriterFunc func(msgs []*Message) error
+
+func (f BatchWriterFunc) WriteBatch(msgs []*Message) error {
+	return f(msgs)
+}
+
+type BatchOptions struct {
+	// MaxSize is the number of messages that triggers a write. Default 100.
+	MaxSize int
+	// MaxDelay is the longest time a message waits for its batch to fill up. Default 1s.
+	MaxDelay time.Duration
+	// MaxInFlight is the number of batches written concurrently. Write blocks while all are busy,
+	// so a slow backend slows down logging instead of growing memory. Default 1, which keeps batches in order.
+	MaxInFlight int
+	// Clock is used for MaxDelay. Default SystemClock.
+	Clock Clock
+	// OnError is called with the messages of a batch that failed to write.
+	OnError func(err error, msgs []*Message)
+	// Name identifies the sink in metrics. Default "batch(<writer>)" with the name or type of the writer.
+	Name string
+}
+
+// BatchSink collects messages into batches for a BatchWriter and writes them in the background.
+// Errors of the writes are passed to OnError and returned by the next Flush.
+type BatchSink struct {
+	w    BatchWriter
+	opts BatchOptions
+	sem  chan struct{}
+	wg   sync.WaitGroup
+
+	mu      sync.Mutex
+	pending []*Message
+	timer   Timer
+	closed  bool
+	errs    errors.Multi
+	lastErr error
+	failing bool
+}
+
+func NewBatchSink(w BatchWriter, opts BatchOptions) *BatchSink {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 100
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = time.Second
+	}
+	if opts.MaxInFlight <= 0 {
+		opts.MaxInFlight = 1
+	}
+	opts.Clock = clockOrSystem(opts.Clock)
+	return &BatchSink{w: w, opts: opts, sem: make(chan struct{}, opts.MaxInFlight)}
+}
+
+// Name returns BatchOptions.Name, or "batch(<writer>)".
+func (s *BatchSink) Name() string {
+	if s.opts.Name != "" {
+		return s.opts.Name
+	}
+	return "batch(" + writerName(s.w) + ")"
+}
+
+// Write adds msg to the current batch and starts writing the batch if it is full.
+func (s *BatchSink) Write(msg *Message) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return os.ErrClosed
+	}
+	s.pending = append(s.pending, msg)
+	var batch []*Message
+	if len(s.pending) >= s.opts.MaxSize {
+		batch = s.take()
+	} else if s.timer == nil {
+		s.timer = s.opts.Clock.AfterFunc(s.opts.MaxDelay, s.timeout)
+	}
+	s.mu.Unlock()
+	if batch != nil {
+		s.send(batch)
+	}
+	return nil
+}
+
+// take returns the current batch and starts a new one. s.mu must be held.
+func (s *BatchSink) take() []*Message {
+	batch := s.pending
+	s.pending = nil
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	return batch
+}
+
+func (s *BatchSink) timeout() {
+	s.mu.Lock()
+	batch := s.take()
+	s.mu.Unlock()
+	if len(batch) > 0 {
+		s.send(batch)
+	}
+}
+
+// send writes batch in the background, once one of MaxInFlight slots is free.
+func (s *BatchSink) send(batch []*Message) {
+	s.wg.Add(1)
+	s.sem <- struct{}{}
+	go func() {
+		defer s.wg.Done()
+		panicked, err := writeBatch(s.w, batch)
+
+		s.mu.Lock()
+		s.failing = err != nil
+		if err != nil {
+			s.lastErr = err
+			s.errs.Append(err)
+		}
+		s.mu.Unlock()
+		if err != nil && s.opts.OnError != nil {
+			s.opts.OnError(err, batch)
+		}
+		<-s.sem
+		// after the slot is free, as the report is written to this sink too
+		if panicked {
+			reportBatchPanic(s.w, batch, err)
+		}
+	}()
+}
+
+// Flush writes the current batch, waits for all writes to finish and returns their errors since the last Flush.
+func (s *BatchSink) Flush() error {
+	s.mu.Lock()
+	batch := s.take()
+	s.mu.Unlock()
+	if len(batch) > 0 {
+		s.send(batch)
+	}
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.errs.Reduce()
+	s.errs = nil
+	return err
+}
+
+// Close flushes the sink. Later writes fail with os.ErrClosed.
+func (s *BatchSink) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return s.Flush()
+}
+
+// Health reports the sink Degraded while the last batch failed, with the pending messages as queue depth.
+func (s *BatchSink) Health() SinkHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := SinkHealth{Sink: s, LastError: s.lastErr, QueueDepth: len(s.pending)}
+	if s.failing {
+		h.Status = Degraded
+	}
+	return h
+}
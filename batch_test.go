@@ -0,0 +1,128 @@
+package module
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchSink(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	var mu sync.Mutex
+	var batches []int
+	s := NewBatchSink(BatchWriterFunc(func(msgs []*Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, len(msgs))
+		return nil
+	}), BatchOptions{MaxSize: 3, MaxDelay: time.Second, Clock: clock})
+
+	for i := 0; i < 7; i++ {
+		s.Write(&Message{})
+	}
+	clock.Advance(500 * time.Millisecond)
+	if h := s.Health(); h.QueueDepth != 1 {
+		t.Fatalf("unexpected queue depth %d", h.QueueDepth)
+	}
+	clock.Advance(500 * time.Millisecond)
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if len(batches) != 3 || batches[0] != 3 || batches[1] != 3 || batches[2] != 1 {
+		t.Fatalf("unexpected batches %v", batches)
+	}
+	mu.Unlock()
+
+	if err := s.Close(); err != nil || s.Write(&Message{}) != os.ErrClosed {
+		t.Fatalf("unexpected write after close")
+	}
+}
+
+func TestBatchSinkErrors(t *testing.T) {
+	fail := errors.New("down")
+	var failed int
+	s := NewBatchSink(BatchWriterFunc(func(msgs []*Message) error {
+		return fail
+	}), BatchOptions{MaxSize: 2, OnError: func(err error, msgs []*Message) { failed += len(msgs) }})
+
+	s.Write(&Message{})
+	s.Write(&Message{})
+	s.Write(&Message{})
+	if err := s.Flush(); err == nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if failed != 3 || s.Health().Status != Degraded {
+		t.Fatalf("unexpected failed %d, health %v", failed, s.Health())
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("errors reported twice: %v", err)
+	}
+}
+
+func TestBatchSinkInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s := NewBatchSink(BatchWriterFunc(func(msgs []*Message) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}), BatchOptions{MaxSize: 1, MaxInFlight: 2})
+
+	written := make(chan int, 3)
+	go func() {
+		for i := 0; i < 3; i++ {
+			s.Write(&Message{})
+			written <- i
+		}
+	}()
+	<-started
+	<-started
+	<-written
+	<-written
+	select {
+	case <-written:
+		t.Fatal("third write did not block with 2 batches in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+	release <- struct{}{}
+	<-started
+	<-written
+	close(release)
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatchSinkPanic(t *testing.T) {
+	var mu sync.Mutex
+	var sunk []string
+	s := NewBatchSink(BatchWriterFunc(func(msgs []*Message) error {
+		panic("boom")
+	}), BatchOptions{MaxSize: 10})
+	m := NewWithOptions("module", messages, WithLogger(log.New(&bytes.Buffer{}, "", 0)), WithSinks(s, SinkFunc(func(msg *Message) error {
+		mu.Lock()
+		sunk = append(sunk, msg.Name)
+		mu.Unlock()
+		return nil
+	})))
+
+	m.Info("test", "A", 1)
+	if err := s.Flush(); err == nil || !strings.HasSuffix(err.Error(), "panicked: boom") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// the batch with the internal message only is not reported again
+	if err := s.Flush(); err == nil {
+		t.Fatalf("expected error")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sunk) != 2 || sunk[0] != "test" || sunk[1] != "sink_panic" {
+		t.Fatalf("unexpected messages %v", sunk)
+	}
+}
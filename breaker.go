@@ -0,0 +1,134 @@
+package module
+
+import (
+	"sync"
+	"time"
+
+	"github.com/halliday/go-errors"
+)
+
+var ErrCircuitOpen = errors.New("circuit open")
+
+type BreakerOptions struct {
+	// Failures is the number of consecutive failures that opens the circuit. Default 5.
+	Failures int
+	// Latency counts writes that take longer as failures, even if they succeed. 0 disables the check.
+	Latency time.Duration
+	// OpenFor is the time the circuit stays open before a single write probes the sink. Default 10s.
+	OpenFor time.Duration
+	// Clock is used for OpenFor and Latency. Default SystemClock.
+	Clock Clock
+	// Fallback receives the messages while the circuit is open, e.g. a DeadLetterSink.
+	// Without Fallback, these messages are dropped and Write returns ErrCircuitOpen.
+	Fallback Sink
+}
+
+type BreakerState int
+
+const (
+	Closed BreakerState = iota
+	Open
+	HalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerSink wraps a Sink with a circuit breaker, so a dead or slow backend
+// does not stall every log call.
+type BreakerSink struct {
+	sink Sink
+	opts BreakerOptions
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	opened   time.Time
+	lastErr  error
+}
+
+func NewBreakerSink(sink Sink, opts BreakerOptions) *BreakerSink {
+	if opts.Failures <= 0 {
+		opts.Failures = 5
+	}
+	if opts.OpenFor <= 0 {
+		opts.OpenFor = 10 * time.Second
+	}
+	opts.Clock = clockOrSystem(opts.Clock)
+	return &BreakerSink{sink: sink, opts: opts}
+}
+
+func (s *BreakerSink) Write(msg *Message) error {
+	s.mu.Lock()
+	if s.state == Open && s.opts.Clock.Now().Sub(s.opened) >= s.opts.OpenFor {
+		s.state = HalfOpen
+		s.mu.Unlock()
+		return s.write(msg)
+	}
+	if s.state != Closed {
+		s.mu.Unlock()
+		if s.opts.Fallback != nil {
+			return s.opts.Fallback.Write(msg)
+		}
+		return ErrCircuitOpen
+	}
+	s.mu.Unlock()
+	return s.write(msg)
+}
+
+func (s *BreakerSink) write(msg *Message) error {
+	start := s.opts.Clock.Now()
+	panicked := true
+	defer func() {
+		// a panic counts as failure, so that a probe does not stay HalfOpen, and continues to the Module
+		if panicked {
+			s.mu.Lock()
+			s.fail(errors.New("sink panicked"))
+			s.mu.Unlock()
+		}
+	}()
+	err := s.sink.Write(msg)
+	panicked = false
+	took := s.opts.Clock.Now().Sub(start)
+	slow := s.opts.Latency > 0 && took > s.opts.Latency
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil && !slow {
+		s.state = Closed
+		s.failures = 0
+		return nil
+	}
+	if err != nil {
+		s.fail(err)
+	} else {
+		s.fail(errors.New("write took %s", took))
+	}
+	return err
+}
+
+// fail records a failed write. s.mu must be held.
+func (s *BreakerSink) fail(err error) {
+	s.lastErr = err
+	s.failures++
+	if s.state == HalfOpen || s.failures >= s.opts.Failures {
+		s.state = Open
+		s.opened = s.opts.Clock.Now()
+	}
+}
+
+// State returns the current state of the circuit and the last error of the sink.
+func (s *BreakerSink) State() (state BreakerState, lastErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state, s.lastErr
+}
@@ -0,0 +1,73 @@
+package module
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreakerSink(t *testing.T) {
+	down := true
+	attempts := 0
+	var fallback int
+	clock := NewManualClock(time.Time{})
+	sink := NewBreakerSink(SinkFunc(func(msg *Message) error {
+		attempts++
+		if down {
+			return errors.New("down")
+		}
+		return nil
+	}), BreakerOptions{
+		Failures: 2,
+		OpenFor:  10 * time.Second,
+		Clock:    clock,
+		Fallback: SinkFunc(func(msg *Message) error {
+			fallback++
+			return nil
+		}),
+	})
+
+	sink.Write(&Message{})
+	sink.Write(&Message{})
+	if state, err := sink.State(); state != Open || err == nil {
+		t.Fatalf("unexpected state %s", state)
+	}
+	sink.Write(&Message{})
+	if attempts != 2 || fallback != 1 {
+		t.Fatalf("open circuit wrote to sink: %d attempts, %d fallback", attempts, fallback)
+	}
+
+	clock.Advance(10 * time.Second)
+	down = false
+	if err := sink.Write(&Message{}); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := sink.State(); state != Closed || attempts != 3 {
+		t.Fatalf("unexpected state %s after probe", state)
+	}
+}
+
+func TestBreakerSinkPanic(t *testing.T) {
+	clock := NewManualClock(time.Time{})
+	sink := NewBreakerSink(SinkFunc(func(msg *Message) error {
+		panic("boom")
+	}), BreakerOptions{Failures: 1, OpenFor: time.Second, Clock: clock})
+	write := func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic was swallowed")
+			}
+		}()
+		sink.Write(&Message{})
+	}
+
+	write()
+	if state, err := sink.State(); state != Open || err == nil {
+		t.Fatalf("unexpected state %s after panic", state)
+	}
+	clock.Advance(time.Second)
+	write()
+	if state, _ := sink.State(); state != Open {
+		t.Fatalf("unexpected state %s after panicking probe", state)
+	}
+}
@@ -0,0 +1,47 @@
+package module
+
+import (
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const pkgPrefix = "github.com/halliday/go-module."
+
+// A Frame is a function call of a stack.
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"` // "dir/file.go"
+	Line     int    `json:"line"`
+}
+
+func (f Frame) String() string {
+	return f.File + ":" + strconv.Itoa(f.Line)
+}
+
+// maxStack is the maximum number of frames captured by stackOf.
+const maxStack = 32
+
+// stackOf returns up to max frames of the call stack, starting at the first caller outside of this package
+// and the runtime, which is the function that panicked when called while panicking.
+func stackOf(max int) []Frame {
+	var pcs [maxStack + 16]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []Frame
+	for len(stack) < max {
+		frame, more := frames.Next()
+		if len(stack) != 0 || !strings.HasPrefix(frame.Function, pkgPrefix) && !strings.HasPrefix(frame.Function, "runtime.") || strings.HasSuffix(frame.File, "_test.go") {
+			stack = append(stack, Frame{
+				Function: frame.Function,
+				File:     path.Base(path.Dir(frame.File)) + "/" + path.Base(frame.File),
+				Line:     frame.Line,
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
@@ -0,0 +1,33 @@
+package module
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFrames(t *testing.T) {
+	var msgs []*Message
+	m := NewWithOptions("module", messages, WithCaller(), WithStack(), WithMask(AllLevels|Debug), WithSinks(SinkFunc(func(msg *Message) error {
+		msgs = append(msgs, msg)
+		return nil
+	})))
+	m.Info("test", "A", 1)
+	m.Err("test2")
+
+	if len(msgs[0].Frames) != 1 || msgs[0].Caller != msgs[0].Frames[0].String() {
+		t.Fatalf("unexpected frames %v for caller %q", msgs[0].Frames, msgs[0].Caller)
+	}
+	f := msgs[0].Frames[0]
+	if f.Function != pkgPrefix+"TestFrames" || !strings.HasSuffix(f.File, "/caller_test.go") {
+		t.Fatalf("unexpected frame %+v", f)
+	}
+	if len(msgs[1].Frames) < 2 || msgs[1].Frames[1].Function != "testing.tRunner" {
+		t.Fatalf("unexpected stack %v", msgs[1].Frames)
+	}
+
+	data, _ := json.Marshal(msgs[0])
+	if !strings.Contains(string(data), `"frames":[{"function":"`+pkgPrefix+`TestFrames","file":"`+f.File+`","line":`) {
+		t.Fatalf("unexpected JSON %s", data)
+	}
+}
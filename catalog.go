@@ -0,0 +1,114 @@
+package module
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type entry struct {
+	name string
+	code int
+	desc string
+	link string
+	file string
+	line int
+
+	// nargs is the number of arguments consumed by the verbs of desc.
+	// With explicit indexes it is the highest index, so "%[1]s %[1]q" consumes one
+	// argument and arguments following it are left for the tail.
+	nargs int
+	// verbs are the placeholders of desc.
+	verbs []verb
+}
+
+type catalog struct {
+	entries []entry
+	index   map[string]int
+}
+
+func parseCatalog(src string) (*catalog, error) {
+	c := &catalog{index: make(map[string]int)}
+	var line string
+	for n, lines := 1, src; lines != ""; n++ {
+		i := strings.IndexByte(lines, '\n')
+		if i == -1 {
+			line = lines
+			lines = ""
+		} else {
+			line = lines[:i]
+			lines = lines[i+1:]
+		}
+		if len(line) > 0 && line[0] == '#' {
+			continue
+		}
+		fields := strings.SplitN(line, ";", 4)
+		if len(fields) == 1 {
+			continue
+		}
+		name := strings.TrimSpace(fields[0])
+		if len(fields) == 2 {
+			return nil, fmt.Errorf("line %d: %q: bad line", n, name)
+		}
+		code, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %q: bad code", n, name)
+		}
+		e := entry{
+			name: name,
+			code: code,
+			desc: strings.TrimSpace(fields[2]),
+			line: n,
+		}
+		if len(fields) == 4 {
+			e.link = strings.TrimSpace(fields[3])
+		}
+		var bad string
+		if e.verbs, e.nargs, bad = scanPattern(e.desc); bad != "" {
+			return nil, fmt.Errorf("%s: %q: bad fmt verb %q", e.pos(), name, bad)
+		}
+		if _, ok := c.index[name]; !ok {
+			c.index[name] = len(c.entries)
+		}
+		c.entries = append(c.entries, e)
+	}
+	return c, nil
+}
+
+// pos returns the position of the entry for error messages, "line 3" or "messages/auth.csv:3".
+func (e *entry) pos() string {
+	if e.file == "" {
+		return fmt.Sprintf("line %d", e.line)
+	}
+	return fmt.Sprintf("%s:%d", e.file, e.line)
+}
+
+// match looks up name, falling back to the closest wildcard entry: for "db.conn.lost"
+// these are "db.conn.*", "db.*" and "*".
+func (c *catalog) match(name string) (e *entry, ok bool) {
+	if e, ok = c.lookup(name); ok {
+		return e, true
+	}
+	for parent := name; parent != ""; {
+		parent = parentName(parent)
+		wildcard := "*"
+		if parent != "" {
+			wildcard = parent + ".*"
+		}
+		if e, ok = c.lookup(wildcard); ok {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+func (c *catalog) lookup(name string) (e *entry, ok bool) {
+	if c == nil {
+		return nil, false
+	}
+	i, ok := c.index[name]
+	if !ok {
+		return nil, false
+	}
+	return &c.entries[i], true
+}
@@ -0,0 +1,101 @@
+package module
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CatalogHandler returns a handler serving the catalog as JSON like ExportCatalog.
+// The locale is negotiated with the Accept-Language header, falling back to the base catalog.
+// With prefixes, only the entries below one of them are served, e.g. the ones meant for clients.
+// Responses carry an ETag and are answered with 304 Not Modified if it matches If-None-Match.
+func (m *Module) CatalogHandler(prefixes ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		locale := m.negotiateLocale(r.Header.Get("Accept-Language"))
+		data, err := m.exportCatalog(locale, prefixes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sum := sha256.Sum256(data)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+		h := w.Header()
+		h.Set("Vary", "Accept-Language")
+		h.Set("ETag", etag)
+		if locale != "" {
+			h.Set("Content-Language", locale)
+		}
+		if matchETag(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		h.Set("Content-Type", "application/json")
+		h.Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	})
+}
+
+// negotiateLocale returns the locale with the highest quality in header that the module has,
+// or "" for the base catalog.
+func (m *Module) negotiateLocale(header string) string {
+	type choice struct {
+		lang string
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		lang := strings.TrimSpace(fields[0])
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			choices = append(choices, choice{lang, q})
+		}
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	for _, c := range choices {
+		if _, ok := m.locales[c.lang]; ok {
+			return c.lang
+		}
+		if i := strings.IndexByte(c.lang, '-'); i != -1 {
+			if _, ok := m.locales[c.lang[:i]]; ok {
+				return c.lang[:i]
+			}
+		}
+	}
+	return ""
+}
+
+func matchETag(header string, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package module
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCatalogHandler(t *testing.T) {
+	m := NewWithOptions("module", "client.a;1;A\nclient.b;2;B %s\ninternal;3;I\n",
+		WithLocale("de", "client.a;1;A auf Deutsch\n"),
+	)
+	h := m.CatalogHandler("client")
+
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/catalog", nil)
+		r.Header = header
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for lang, expected := range map[string]string{
+		"":                          `{"client.a":{"code":1,"description":"A"},"client.b":{"code":2,"description":"B %s"}}`,
+		"fr, de-AT;q=0.8, en;q=0.5": `{"client.a":{"code":1,"description":"A auf Deutsch"}}`,
+		"de;q=0, fr":                `{"client.a":{"code":1,"description":"A"},"client.b":{"code":2,"description":"B %s"}}`,
+	} {
+		w := get(http.Header{"Accept-Language": {lang}})
+		if w.Code != http.StatusOK || w.Body.String() != expected {
+			t.Fatalf("Accept-Language %q: %d %s, expected %s", lang, w.Code, w.Body, expected)
+		}
+	}
+
+	w := get(http.Header{"Accept-Language": {"de"}})
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Content-Language") != "de" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+	if w := get(http.Header{"Accept-Language": {"de"}, "If-None-Match": {etag}}); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body)
+	}
+	if w := get(http.Header{"If-None-Match": {etag}}); w.Code != http.StatusOK {
+		t.Fatalf("ETag of locale matched base catalog")
+	}
+}
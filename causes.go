@@ -0,0 +1,105 @@
+package module
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/halliday/go-errors"
+)
+
+// unwrapMulti returns the errors joined in err, like errors.Join (Go 1.20) or errors.Multi, or nil.
+func unwrapMulti(err error) []error {
+	switch e := err.(type) {
+	case errors.Multi:
+		return e
+	case interface{ Unwrap() []error }:
+		return e.Unwrap()
+	}
+	return nil
+}
+
+// walkErrors calls f for err and its causes, depth first through all branches of joined errors,
+// until f returns false.
+func walkErrors(err error, f func(err error) bool) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if !f(err) {
+			return false
+		}
+		if errs := unwrapMulti(err); errs != nil {
+			for _, e := range errs {
+				if !walkErrors(e, f) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return true
+}
+
+type richCause interface {
+	errors.NameError
+	errors.CodeError
+	errors.DescError
+}
+
+// writeCause writes err for the text log, with its causes nested in "(caused by ...)"
+// and the branches of joined errors separated by "; ".
+// Errors of NewError are written without their causes, which RichError.Error would repeat.
+func writeCause(b *strings.Builder, err error) {
+	if errs := unwrapMulti(err); errs != nil {
+		for i, e := range errs {
+			if i != 0 {
+				b.WriteString("; ")
+			}
+			writeCause(b, e)
+		}
+		return
+	}
+	r, ok := err.(richCause)
+	if !ok {
+		b.WriteString(err.Error())
+		return
+	}
+	b.WriteString(strconv.Itoa(r.ErrorCode()))
+	if name := r.ErrorName(); name != "" {
+		b.WriteByte(' ')
+		b.WriteString(name)
+	}
+	if desc := r.ErrorDescription(); desc != "" {
+		b.WriteByte(' ')
+		b.WriteString(desc)
+	}
+	if cause := errors.Unwrap(err); cause != nil {
+		b.WriteString(" (caused by ")
+		writeCause(b, cause)
+		b.WriteByte(')')
+	}
+}
+
+// reportable returns err as RichError for Report. Joined errors are reported with
+// a summary as description and the joined errors as cause.
+func reportable(err error) *errors.RichError {
+	if r, ok := err.(*errors.RichError); ok {
+		return r
+	}
+	r := errors.Rich(err).(*errors.RichError)
+	if errs := unwrapMulti(err); errs != nil {
+		return &errors.RichError{
+			Name:     r.Name,
+			Code:     r.Code,
+			Desc:     strconv.Itoa(len(errs)) + " errors",
+			CausedBy: err,
+		}
+	}
+	if cause := errors.Unwrap(err); cause != nil && hasMulti(cause) {
+		r.CausedBy = cause
+	}
+	return r
+}
+
+func hasMulti(err error) bool {
+	return !walkErrors(err, func(err error) bool {
+		return unwrapMulti(err) == nil
+	})
+}
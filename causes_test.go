@@ -0,0 +1,64 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/halliday/go-errors"
+)
+
+// joinError unwraps like the errors of errors.Join.
+type joinError []error
+
+func (j joinError) Error() string {
+	var s []string
+	for _, err := range j {
+		s = append(s, err.Error())
+	}
+	return strings.Join(s, "\n")
+}
+
+func (j joinError) Unwrap() []error { return j }
+
+func TestJoinedCauses(t *testing.T) {
+	var b bytes.Buffer
+	_, e, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+
+	joined := joinError{e("test2"), errors.NewRich("", 0, "", "", map[string]interface{}{KeyCorrelationID: "c1"}, errors.New("eof"))}
+	m.Err("test", e("test4", joined))
+	if want := "[ERR  ] This is a test message correlation_id=c1 (caused by 345 test4 A test message with a link (caused by 234 test2 This is a another test message; 0 (caused by eof)))\n"; b.String() != want {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+
+	b.Reset()
+	m.Report(joined)
+	if want := "[ERR  ] 2 errors correlation_id=c1 (caused by 234 test2 This is a another test message; 0 (caused by eof))\n"; b.String() != want {
+		t.Fatalf("unexpected report output %q", b.String())
+	}
+
+	if n := NamedError(joinError{errors.New("plain"), e("test2")}); n == nil || n.(errors.NameError).ErrorName() != "test2" {
+		t.Fatalf("NamedError: %v", n)
+	}
+
+	decoded := DecodeError(EncodeError(e("test4", joined)))
+	causes := unwrapMulti(errors.Unwrap(decoded))
+	if len(causes) != 2 || causes[0].(errors.NameError).ErrorName() != "test2" {
+		t.Fatalf("decoded causes: %v", causes)
+	}
+}
+
+func TestJoinedCausesJSON(t *testing.T) {
+	_, e, _ := New("module", messages)
+	chain := causeChain(e("test4", joinError{e("test2"), errors.New("eof")}))
+	data, err := json.Marshal(chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 2 || len(chain[1].Joined) != 2 || chain[1].Joined[0][0].Name != "test2" || chain[1].Joined[1][0].Error != "eof" {
+		t.Fatalf("unexpected chain %s", data)
+	}
+}
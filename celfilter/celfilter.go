@@ -0,0 +1,112 @@
+// Package celfilter evaluates CEL (https://github.com/google/cel-go) expressions over messages,
+// for routing and filtering rules defined by operators:
+//
+//	f, err := celfilter.Compile(`level in ["warn", "error"] && data.tenant != "internal"`)
+//	...
+//	m.Sinks = append(m.Sinks, module.FilterSink(alerts, f.Match))
+//
+// Expressions see the variables level, module, name, desc, link, id and caller (strings),
+// code (int), seq (uint), time (timestamp) and data (map of string to dyn).
+package celfilter
+
+import (
+	"fmt"
+
+	module "github.com/halliday/go-module"
+
+	"github.com/google/cel-go/cel"
+)
+
+var env *cel.Env
+
+func init() {
+	var err error
+	env, err = cel.NewEnv(
+		cel.Variable("level", cel.StringType),
+		cel.Variable("module", cel.StringType),
+		cel.Variable("name", cel.StringType),
+		cel.Variable("desc", cel.StringType),
+		cel.Variable("link", cel.StringType),
+		cel.Variable("id", cel.StringType),
+		cel.Variable("caller", cel.StringType),
+		cel.Variable("code", cel.IntType),
+		cel.Variable("seq", cel.UintType),
+		cel.Variable("time", cel.TimestampType),
+		cel.Variable("data", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// A Filter is a compiled CEL expression.
+type Filter struct {
+	src string
+	prg cel.Program
+}
+
+// Compile compiles the CEL expression src, which must evaluate to a bool.
+func Compile(src string) (*Filter, error) {
+	ast, iss := env.Compile(src)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("celfilter %q: %w", src, iss.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("celfilter %q: result is %s, not bool", src, ast.OutputType())
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("celfilter %q: %w", src, err)
+	}
+	return &Filter{src, prg}, nil
+}
+
+// MustCompile is like Compile but panics if src is not valid.
+func MustCompile(src string) *Filter {
+	f, err := Compile(src)
+	if err != nil {
+		panic(err.Error())
+	}
+	return f
+}
+
+// Match reports whether the expression is true for msg.
+// Evaluation errors, like a missing data key, count as false.
+func (f *Filter) Match(msg *module.Message) bool {
+	out, _, err := f.prg.Eval(vars(msg))
+	if err != nil {
+		return false
+	}
+	b, ok := out.Value().(bool)
+	return ok && b
+}
+
+func (f *Filter) String() string {
+	return f.src
+}
+
+func vars(msg *module.Message) map[string]interface{} {
+	v := map[string]interface{}{
+		"level":  msg.Level.String(),
+		"module": msg.Module,
+		"name":   "",
+		"desc":   "",
+		"link":   "",
+		"id":     msg.ID,
+		"caller": msg.Caller,
+		"code":   0,
+		"seq":    msg.Seq,
+		"time":   msg.Time,
+		"data":   msg.Data,
+	}
+	if msg.RichError != nil {
+		v["name"] = msg.Name
+		v["desc"] = msg.Desc
+		v["link"] = msg.Link
+		v["code"] = msg.Code
+	}
+	if msg.Data == nil {
+		v["data"] = map[string]interface{}{}
+	}
+	return v
+}
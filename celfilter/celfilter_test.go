@@ -0,0 +1,50 @@
+package celfilter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+func TestMatch(t *testing.T) {
+	msg := &module.Message{
+		Module:    "auth",
+		Level:     module.Warn,
+		Time:      time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC),
+		RichError: errors.NewRich("login_failed", 1001, "login failed", "", nil, nil),
+		Data:      map[string]interface{}{"tenant": "acme", "attempts": 3},
+	}
+	for src, expected := range map[string]bool{
+		`level in ["warn", "error"] && data.tenant != "internal"`: true,
+		`module == "auth" && code >= 1000`:                        true,
+		`name.startsWith("login_") && data.attempts > 5`:          false,
+		`has(data.ip)`:          false,
+		`data.ip == "10.0.0.1"`: false,
+		`time.getHours() == 12`: true,
+	} {
+		f, err := Compile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Match(msg) != expected {
+			t.Errorf("%s: expected %v", src, expected)
+		}
+	}
+
+	if !MustCompile(`name == "" && size(data) == 0`).Match(&module.Message{}) {
+		t.Error("message without error and data did not match")
+	}
+
+	for src, expected := range map[string]string{
+		`level ==`:  "Syntax error",
+		`code + 1`:  "result is int, not bool",
+		`unknown()`: "undeclared reference",
+	} {
+		if _, err := Compile(src); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: unexpected error %v", src, err)
+		}
+	}
+}
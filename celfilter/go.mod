@@ -0,0 +1,23 @@
+module github.com/halliday/go-module/celfilter
+
+go 1.21
+
+require (
+	github.com/google/cel-go v0.15.3
+	github.com/halliday/go-errors v0.0.0-20221117114904-701c88d594be
+	github.com/halliday/go-module v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/antlr/antlr4/runtime/Go/antlr/v4 v4.0.0-20230305170008-8188dc5388df // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/stoewer/go-strcase v1.2.0 // indirect
+	github.com/stretchr/testify v1.9.0 // indirect
+	golang.org/x/exp v0.0.0-20220722155223-a9213eeb770e // indirect
+	golang.org/x/text v0.13.0 // indirect
+	google.golang.org/genproto v0.0.0-20230306155012-7f2fa6fef1f4 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
+
+replace github.com/halliday/go-module => ../
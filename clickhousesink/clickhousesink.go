@@ -0,0 +1,188 @@
+// Package clickhousesink writes module messages into ClickHouse over its HTTP interface,
+// for services logging tens of thousands of messages per second.
+//
+// Batches are sent column by column in the JSONColumns format, which ClickHouse inserts without
+// converting rows:
+//
+//	sink := clickhousesink.New("http://clickhouse:8123", clickhousesink.Options{User: "logs", Password: pw, Compress: module.Zstd})
+//	m.Sinks = append(m.Sinks, module.NewBatchSink(sink, module.BatchOptions{MaxSize: 10000}))
+//
+// CreateTable returns a matching table definition.
+package clickhousesink
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+
+	module "github.com/halliday/go-module"
+)
+
+// DefaultTable is the table used if Options.Table is empty.
+const DefaultTable = "messages"
+
+type Options struct {
+	// Client sends the requests. Default http.DefaultClient.
+	Client *http.Client
+	// User and Password authenticate with the X-ClickHouse-User and X-ClickHouse-Key headers.
+	User, Password string
+	// Database is the database of Table. Default the default database of the user.
+	Database string
+	// Table is the table to insert into. Default DefaultTable.
+	Table string
+	// Compress compresses the batches, module.Zstd is recommended.
+	Compress module.Compression
+	// AsyncInsert lets the server buffer small batches of many clients, see async_insert.
+	AsyncInsert bool
+}
+
+// Sink is a module.Sink and module.BatchWriter inserting messages into a ClickHouse table.
+// Errors are classified like module.ResponseError.
+type Sink struct {
+	url  string
+	opts Options
+}
+
+func New(serverURL string, opts Options) *Sink {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Table == "" {
+		opts.Table = DefaultTable
+	}
+	q := url.Values{"query": {"INSERT INTO " + opts.Table + " FORMAT JSONColumns"}}
+	if opts.Database != "" {
+		q.Set("database", opts.Database)
+	}
+	if opts.AsyncInsert {
+		q.Set("async_insert", "1")
+		q.Set("wait_for_async_insert", "1")
+	}
+	return &Sink{url: serverURL + "/?" + q.Encode(), opts: opts}
+}
+
+// CreateTable returns the statement that creates table with the columns written by the sink.
+// Low cardinality columns are dictionary encoded and the table is partitioned by day.
+func CreateTable(table string) string {
+	if table == "" {
+		table = DefaultTable
+	}
+	return "CREATE TABLE IF NOT EXISTS " + table + ` (
+	time DateTime64(9, 'UTC'),
+	module LowCardinality(String),
+	level LowCardinality(String),
+	name LowCardinality(String),
+	code UInt32,
+	description String,
+	link String,
+	id String,
+	seq UInt64,
+	epoch LowCardinality(String),
+	data Map(LowCardinality(String), String),
+	caller String,
+	message String
+) ENGINE = MergeTree
+PARTITION BY toDate(time)
+ORDER BY (module, level, name, time)`
+}
+
+// columns is a batch in the JSONColumns format.
+type columns struct {
+	Time        []string            `json:"time"`
+	Module      []string            `json:"module"`
+	Level       []string            `json:"level"`
+	Name        []string            `json:"name"`
+	Code        []int               `json:"code"`
+	Description []string            `json:"description"`
+	Link        []string            `json:"link"`
+	ID          []string            `json:"id"`
+	Seq         []uint64            `json:"seq"`
+	Epoch       []string            `json:"epoch"`
+	Data        []map[string]string `json:"data"`
+	Caller      []string            `json:"caller"`
+	Message     []json.RawMessage   `json:"message"`
+}
+
+// timeFormat is the format of DateTime64(9) values, which the basic date_time_input_format parses.
+const timeFormat = "2006-01-02 15:04:05.000000000"
+
+func (c *columns) add(msg *module.Message) error {
+	full, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	var data map[string]string
+	if len(msg.Data) > 0 {
+		data = make(map[string]string, len(msg.Data))
+		for key, value := range msg.Data {
+			if s, ok := value.(string); ok {
+				data[key] = s
+				continue
+			}
+			b, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			data[key] = string(b)
+		}
+	}
+	var name, desc, link string
+	var code int
+	if msg.RichError != nil {
+		name, code, desc, link = msg.Name, msg.Code, msg.Desc, msg.Link
+	}
+	c.Time = append(c.Time, msg.Time.UTC().Format(timeFormat))
+	c.Module = append(c.Module, msg.Module)
+	c.Level = append(c.Level, msg.Level.String())
+	c.Name = append(c.Name, name)
+	c.Code = append(c.Code, code)
+	c.Description = append(c.Description, desc)
+	c.Link = append(c.Link, link)
+	c.ID = append(c.ID, msg.ID)
+	c.Seq = append(c.Seq, msg.Seq)
+	c.Epoch = append(c.Epoch, msg.Epoch)
+	c.Data = append(c.Data, data)
+	c.Caller = append(c.Caller, msg.Caller)
+	c.Message = append(c.Message, full)
+	return nil
+}
+
+func (s *Sink) Write(msg *module.Message) error {
+	return s.WriteBatch([]*module.Message{msg})
+}
+
+func (s *Sink) WriteBatch(msgs []*module.Message) error {
+	var c columns
+	for _, msg := range msgs {
+		if err := c.add(msg); err != nil {
+			return err
+		}
+	}
+	body, err := json.Marshal(&c)
+	if err != nil {
+		return err
+	}
+	if body, err = module.Compress(body, s.opts.Compress, 0); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return module.Permanent(err)
+	}
+	if s.opts.User != "" {
+		req.Header.Set("X-ClickHouse-User", s.opts.User)
+		req.Header.Set("X-ClickHouse-Key", s.opts.Password)
+	}
+	if s.opts.Compress != module.NoCompression {
+		req.Header.Set("Content-Encoding", s.opts.Compress.Encoding())
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	resp.Body = io.NopCloser(io.LimitReader(resp.Body, 512))
+	return module.ResponseError(resp)
+}
@@ -0,0 +1,81 @@
+package clickhousesink
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestWriteBatch(t *testing.T) {
+	var query, user, encoding string
+	var got map[string][]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		user = r.Header.Get("X-ClickHouse-User")
+		encoding = r.Header.Get("Content-Encoding")
+		dec, err := zstd.NewReader(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer dec.Close()
+		body, _ := io.ReadAll(dec)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	s := New(srv.URL, Options{User: "logs", Password: "secret", Database: "app", Compress: module.Zstd, AsyncInsert: true})
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 123456789, time.UTC)
+	err := s.WriteBatch([]*module.Message{
+		{Module: "auth", Level: module.Warn, Time: t0, RichError: errors.NewRich("login_failed", 1001, "Login failed", "", nil, nil), Data: map[string]interface{}{"user": "bob", "attempts": 3}},
+		{Module: "db", Level: module.Error, Time: t0.Add(time.Second), Seq: 7},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "async_insert=1&database=app&query=INSERT+INTO+messages+FORMAT+JSONColumns&wait_for_async_insert=1"; query != want {
+		t.Fatalf("unexpected query %q", query)
+	}
+	if user != "logs" || encoding != "zstd" {
+		t.Fatalf("unexpected headers %q %q", user, encoding)
+	}
+	for col, want := range map[string][]interface{}{
+		"time":   {"2022-11-17 12:00:00.123456789", "2022-11-17 12:00:01.123456789"},
+		"module": {"auth", "db"},
+		"level":  {"warn", "error"},
+		"name":   {"login_failed", ""},
+		"code":   {1001.0, 0.0},
+		"seq":    {0.0, 7.0},
+	} {
+		if len(got[col]) != 2 || got[col][0] != want[0] || got[col][1] != want[1] {
+			t.Fatalf("unexpected column %s: %v", col, got[col])
+		}
+	}
+	if data := got["data"][0].(map[string]interface{}); data["user"] != "bob" || data["attempts"] != "3" || got["data"][1] != nil {
+		t.Fatalf("unexpected data %v", got["data"])
+	}
+	if msg := got["message"][1].(map[string]interface{}); msg["module"] != "db" || msg["seq"] != 7.0 {
+		t.Fatalf("unexpected message %v", msg)
+	}
+}
+
+func TestError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Code: 60. DB::Exception: Table default.messages does not exist.", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	err := New(srv.URL, Options{}).Write(&module.Message{Module: "auth", Level: module.Info, Time: time.Now()})
+	if err == nil || !module.IsPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package module
+
+// Clone returns an independent Module that shares the catalog of m and starts with a copy of its
+// configuration: mask, tenant and prefix masks, hooks, sinks, verbosity and throttling. opts are applied to the clone.
+// Counters like sequence numbers and throttling state are not copied.
+func (m *Module) Clone(opts ...Option) *Module {
+	m.configMu.RLock()
+	hook, logger, slogger := m.Hook, m.Logger, m.slog
+	m.configMu.RUnlock()
+	c := &Module{
+		Name:          m.Name,
+		catalog:       m.catalog,
+		Mask:          m.GetMask(),
+		Hook:          hook,
+		NoGlobalHooks: m.NoGlobalHooks,
+		Processors:    append([]Processor(nil), m.Processors...),
+		Sinks:         append([]Sink(nil), m.Sinks...),
+		OnSinkHealth:  m.OnSinkHealth,
+		Signer:        m.Signer,
+		Metrics:       m.Metrics,
+		Logger:        logger,
+		Clock:         m.Clock,
+		Unknown:       m.Unknown,
+		StrictFormat:  m.StrictFormat,
+		Caller:        m.Caller,
+		Stack:         m.Stack,
+		JSON:          m.JSON,
+		ShowID:        m.ShowID,
+		ProfileLabels: m.ProfileLabels,
+		audit:         m.audit,
+		slog:          slogger,
+	}
+	c.SetVerbosity(m.Verbosity())
+	m.hooks.clone(&c.hooks)
+	m.configMu.RLock()
+	for lang, locale := range m.locales {
+		c.addLocale(lang, locale)
+	}
+	m.configMu.RUnlock()
+
+	m.maskMu.RLock()
+	c.maskSet = m.maskSet
+	for tenant, mask := range m.tenantMasks {
+		c.SetTenantMask(tenant, mask)
+	}
+	for prefix, mask := range m.prefixMasks {
+		c.SetPrefixMask(prefix, mask)
+	}
+	m.maskMu.RUnlock()
+
+	m.throttleMu.Lock()
+	throttle := m.throttle
+	m.throttleMu.Unlock()
+	if throttle.Rate > 0 {
+		c.Throttle(throttle)
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.registerOrWarn()
+	return c
+}
@@ -0,0 +1,30 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	var b bytes.Buffer
+	m := NewWithOptions("module", messages, WithLogger(log.New(&b, "", 0)), WithMask(Error))
+	m.SetTenantMask("acme", AllLevels)
+
+	var sunk int
+	c := m.Clone(WithMask(AllLevels), WithSinks(SinkFunc(func(msg *Message) error {
+		sunk++
+		return nil
+	})))
+	c.Info("test")
+	m.Info("test")
+	m.SetTenantMask("acme", Error)
+
+	if b.String() != "[INFO ] This is a test message\n" || sunk != 1 || len(m.Sinks) != 0 {
+		t.Fatalf("clone is not independent: %q, %d", b.String(), sunk)
+	}
+	if c.mask(CtxWithTenant(context.Background(), "acme"), "test") != AllLevels {
+		t.Fatal("tenant masks were not copied")
+	}
+}
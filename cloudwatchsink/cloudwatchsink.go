@@ -0,0 +1,255 @@
+// Package cloudwatchsink sends module messages to AWS CloudWatch Logs, for Lambda, ECS and EC2 deployments.
+//
+// Credentials come from the aws.Config, so the IAM role of the function, task or instance is used:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	sink := cloudwatchsink.NewFromConfig(cfg, cloudwatchsink.Options{Group: "/app/shop", Create: true})
+//	m.Sinks = append(m.Sinks, module.NewBatchSink(sink, module.BatchOptions{MaxSize: 1000}))
+//
+// Every message is one log event with its JSON encoding as text.
+package cloudwatchsink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	module "github.com/halliday/go-module"
+)
+
+// Limits of PutLogEvents.
+const (
+	maxEvents     = 10000
+	maxBatchBytes = 1048576
+	eventOverhead = 26
+	maxEventBytes = 262144 - eventOverhead
+	maxSpan       = 24 * time.Hour
+)
+
+// API is the part of *cloudwatchlogs.Client used by the Sink.
+type API interface {
+	CreateLogGroup(ctx context.Context, in *cloudwatchlogs.CreateLogGroupInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogGroupOutput, error)
+	PutRetentionPolicy(ctx context.Context, in *cloudwatchlogs.PutRetentionPolicyInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutRetentionPolicyOutput, error)
+	CreateLogStream(ctx context.Context, in *cloudwatchlogs.CreateLogStreamInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error)
+	PutLogEvents(ctx context.Context, in *cloudwatchlogs.PutLogEventsInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error)
+}
+
+type Options struct {
+	// Group is the log group. Required.
+	Group string
+	// Stream is the log stream. Default the Lambda log stream name, or the host name.
+	Stream string
+	// Create creates the group and stream when they do not exist.
+	// Otherwise only the stream is created, which needs just logs:CreateLogStream.
+	Create bool
+	// RetentionDays is set as the retention of a group created by the sink. Default never expire.
+	RetentionDays int32
+	// Context is used for the API calls. Default context.Background().
+	Context context.Context
+}
+
+// Sink is a module.Sink and module.BatchWriter that sends messages with PutLogEvents.
+// Batches are split to the limits of the API and sent one at a time, with the sequence token
+// of the stream for older accounts that still require it.
+type Sink struct {
+	api  API
+	opts Options
+
+	mu    sync.Mutex
+	token *string
+}
+
+// New returns a Sink using client. It panics if opts.Group is empty.
+func New(client API, opts Options) *Sink {
+	if opts.Group == "" {
+		panic("cloudwatchsink.New: empty log group")
+	}
+	if opts.Stream == "" {
+		opts.Stream = defaultStream()
+	}
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
+	return &Sink{api: client, opts: opts}
+}
+
+// NewFromConfig returns a Sink with a client for cfg.
+func NewFromConfig(cfg aws.Config, opts Options) *Sink {
+	return New(cloudwatchlogs.NewFromConfig(cfg), opts)
+}
+
+func defaultStream() string {
+	if s := os.Getenv("AWS_LAMBDA_LOG_STREAM_NAME"); s != "" {
+		return s
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return "module"
+	}
+	return host
+}
+
+func (s *Sink) Write(msg *module.Message) error {
+	return s.WriteBatch([]*module.Message{msg})
+}
+
+func (s *Sink) WriteBatch(msgs []*module.Message) error {
+	events := make([]types.InputLogEvent, 0, len(msgs))
+	for _, msg := range msgs {
+		b, err := encode(msg)
+		if err != nil {
+			return err
+		}
+		events = append(events, types.InputLogEvent{Message: aws.String(string(b)), Timestamp: aws.Int64(msg.Time.UnixMilli())})
+	}
+	// the events of a request must be in chronological order
+	sort.SliceStable(events, func(i, j int) bool { return *events[i].Timestamp < *events[j].Timestamp })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(events) > 0 {
+		n := split(events)
+		if err := s.put(events[:n]); err != nil {
+			return err
+		}
+		events = events[n:]
+	}
+	return nil
+}
+
+// encode returns the JSON encoding of msg. If it is too large for an event, the data, frames and
+// causes are replaced by {"truncated":true} and the description is shortened until it fits.
+func encode(msg *module.Message) ([]byte, error) {
+	b, err := json.Marshal(msg)
+	if err != nil || len(b) <= maxEventBytes {
+		return b, err
+	}
+	short := *msg
+	short.Data = map[string]interface{}{"truncated": true}
+	short.Frames = nil
+	if msg.RichError != nil {
+		e := *msg.RichError
+		e.CausedBy = nil
+		short.RichError = &e
+	}
+	for {
+		b, err = json.Marshal(&short)
+		if err != nil || len(b) <= maxEventBytes || short.RichError == nil || short.Desc == "" {
+			return b, err
+		}
+		// escaping makes the encoded description longer, so shorten it in proportion
+		d, _ := json.Marshal(short.Desc)
+		n := 0
+		if over := len(b) - maxEventBytes; over < len(d) {
+			n = len(short.Desc) * (len(d) - over) / len(d)
+		}
+		for n > 0 && !utf8.RuneStart(short.Desc[n]) {
+			n--
+		}
+		short.Desc = short.Desc[:n]
+	}
+}
+
+// split returns the number of events that fit into one request.
+func split(events []types.InputLogEvent) int {
+	size := 0
+	first := time.UnixMilli(*events[0].Timestamp)
+	for i, e := range events {
+		size += len(*e.Message) + eventOverhead
+		if i == maxEvents || size > maxBatchBytes || time.UnixMilli(*e.Timestamp).Sub(first) >= maxSpan {
+			return i
+		}
+	}
+	return len(events)
+}
+
+// put sends events, creating the stream and fixing the sequence token if necessary. s.mu must be held.
+func (s *Sink) put(events []types.InputLogEvent) error {
+	created := false
+	for attempt := 0; ; attempt++ {
+		out, err := s.api.PutLogEvents(s.opts.Context, &cloudwatchlogs.PutLogEventsInput{
+			LogGroupName:  aws.String(s.opts.Group),
+			LogStreamName: aws.String(s.opts.Stream),
+			LogEvents:     events,
+			SequenceToken: s.token,
+		})
+		if err == nil {
+			s.token = out.NextSequenceToken
+			return rejected(out.RejectedLogEventsInfo, len(events))
+		}
+		var accepted *types.DataAlreadyAcceptedException
+		var invalidToken *types.InvalidSequenceTokenException
+		var notFound *types.ResourceNotFoundException
+		switch {
+		case errors.As(err, &accepted):
+			s.token = accepted.ExpectedSequenceToken
+			return nil
+		case errors.As(err, &invalidToken) && attempt == 0:
+			s.token = invalidToken.ExpectedSequenceToken
+		case errors.As(err, &notFound) && !created:
+			if err := s.create(); err != nil {
+				return err
+			}
+			created = true
+		default:
+			return err
+		}
+	}
+}
+
+// create creates the stream, and the group with Options.Create. s.mu must be held.
+func (s *Sink) create() error {
+	ctx := s.opts.Context
+	var exists *types.ResourceAlreadyExistsException
+	if s.opts.Create {
+		_, err := s.api.CreateLogGroup(ctx, &cloudwatchlogs.CreateLogGroupInput{LogGroupName: aws.String(s.opts.Group)})
+		if err == nil && s.opts.RetentionDays > 0 {
+			_, err = s.api.PutRetentionPolicy(ctx, &cloudwatchlogs.PutRetentionPolicyInput{
+				LogGroupName:    aws.String(s.opts.Group),
+				RetentionInDays: aws.Int32(s.opts.RetentionDays),
+			})
+		}
+		if err != nil && !errors.As(err, &exists) {
+			return err
+		}
+	}
+	_, err := s.api.CreateLogStream(ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(s.opts.Group),
+		LogStreamName: aws.String(s.opts.Stream),
+	})
+	if err != nil && !errors.As(err, &exists) {
+		return err
+	}
+	s.token = nil
+	return nil
+}
+
+// rejected returns a Permanent error for events that CloudWatch dropped for their time.
+func rejected(info *types.RejectedLogEventsInfo, n int) error {
+	if info == nil {
+		return nil
+	}
+	var tooOld, expired, tooNew int
+	if info.TooOldLogEventEndIndex != nil {
+		tooOld = int(*info.TooOldLogEventEndIndex)
+	}
+	if info.ExpiredLogEventEndIndex != nil {
+		expired = int(*info.ExpiredLogEventEndIndex)
+	}
+	if info.TooNewLogEventStartIndex != nil {
+		tooNew = n - int(*info.TooNewLogEventStartIndex)
+	}
+	if tooOld+expired+tooNew == 0 {
+		return nil
+	}
+	return module.Permanent(fmt.Errorf("cloudwatch rejected log events: %d too old, %d expired, %d too new", tooOld, expired, tooNew))
+}
@@ -0,0 +1,148 @@
+package cloudwatchsink
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+// fakeLogs is a CloudWatch Logs with one stream that requires sequence tokens.
+type fakeLogs struct {
+	calls  []string
+	group  bool
+	stream bool
+	token  int
+	events []types.InputLogEvent
+	puts   int
+}
+
+func (f *fakeLogs) CreateLogGroup(ctx context.Context, in *cloudwatchlogs.CreateLogGroupInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogGroupOutput, error) {
+	f.calls = append(f.calls, "CreateLogGroup "+*in.LogGroupName)
+	f.group = true
+	return &cloudwatchlogs.CreateLogGroupOutput{}, nil
+}
+
+func (f *fakeLogs) PutRetentionPolicy(ctx context.Context, in *cloudwatchlogs.PutRetentionPolicyInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutRetentionPolicyOutput, error) {
+	f.calls = append(f.calls, "PutRetentionPolicy")
+	return &cloudwatchlogs.PutRetentionPolicyOutput{}, nil
+}
+
+func (f *fakeLogs) CreateLogStream(ctx context.Context, in *cloudwatchlogs.CreateLogStreamInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	f.calls = append(f.calls, "CreateLogStream "+*in.LogStreamName)
+	if !f.group {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("group")}
+	}
+	f.stream = true
+	return &cloudwatchlogs.CreateLogStreamOutput{}, nil
+}
+
+func (f *fakeLogs) PutLogEvents(ctx context.Context, in *cloudwatchlogs.PutLogEventsInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	f.calls = append(f.calls, "PutLogEvents")
+	if !f.stream {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("stream")}
+	}
+	expected := aws.String(strings.Repeat("t", f.token))
+	if f.token == 0 {
+		expected = nil
+	}
+	if aws.ToString(in.SequenceToken) != aws.ToString(expected) {
+		return nil, &types.InvalidSequenceTokenException{ExpectedSequenceToken: expected}
+	}
+	for i := 1; i < len(in.LogEvents); i++ {
+		if *in.LogEvents[i].Timestamp < *in.LogEvents[i-1].Timestamp {
+			return nil, &types.InvalidParameterException{Message: aws.String("not in order")}
+		}
+	}
+	f.puts++
+	f.events = append(f.events, in.LogEvents...)
+	f.token++
+	return &cloudwatchlogs.PutLogEventsOutput{NextSequenceToken: aws.String(strings.Repeat("t", f.token))}, nil
+}
+
+func TestWriteBatch(t *testing.T) {
+	f := new(fakeLogs)
+	s := New(f, Options{Group: "/app/shop", Stream: "host-1", Create: true, RetentionDays: 7})
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC)
+	msgs := []*module.Message{
+		{Module: "auth", Level: module.Info, Time: t0.Add(time.Second)},
+		{Module: "auth", Level: module.Warn, Time: t0},
+	}
+	if err := s.WriteBatch(msgs); err != nil {
+		t.Fatal(err)
+	}
+	want := "PutLogEvents,CreateLogGroup /app/shop,PutRetentionPolicy,CreateLogStream host-1,PutLogEvents"
+	if got := strings.Join(f.calls, ","); got != want {
+		t.Fatalf("unexpected calls %s", got)
+	}
+	if len(f.events) != 2 || !strings.Contains(*f.events[0].Message, `"level":"warn"`) || *f.events[0].Timestamp != t0.UnixMilli() {
+		t.Fatalf("unexpected events %v", f.events)
+	}
+
+	// another writer used the stream
+	f.token += 2
+	if err := s.Write(msgs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(f.events))
+	}
+}
+
+func TestSplit(t *testing.T) {
+	f := &fakeLogs{group: true, stream: true}
+	s := New(f, Options{Group: "g", Stream: "s"})
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC)
+	var msgs []*module.Message
+	for i := 0; i < maxEvents+2; i++ {
+		msgs = append(msgs, &module.Message{Module: "m", Level: module.Info, Time: t0})
+	}
+	msgs[len(msgs)-1].Time = t0.Add(25 * time.Hour)
+	if err := s.WriteBatch(msgs); err != nil {
+		t.Fatal(err)
+	}
+	if f.puts != 3 || len(f.events) != len(msgs) {
+		t.Fatalf("expected 3 requests with %d events, got %d with %d", len(msgs), f.puts, len(f.events))
+	}
+}
+
+func TestEncodeLarge(t *testing.T) {
+	desc := strings.Repeat("ä<", maxEventBytes/3)
+	msg := &module.Message{
+		Module:    "m",
+		Level:     module.Error,
+		RichError: errors.NewRich("big", 1, desc, "", nil, nil),
+		Data:      map[string]interface{}{"dump": strings.Repeat("x", maxEventBytes)},
+	}
+	b, err := encode(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var j struct {
+		Desc string                 `json:"desc"`
+		Data map[string]interface{} `json:"data"`
+	}
+	if len(b) > maxEventBytes || json.Unmarshal(b, &j) != nil {
+		t.Fatalf("invalid event of %d bytes", len(b))
+	}
+	if j.Data["truncated"] != true || j.Desc == "" || !strings.HasPrefix(desc, j.Desc) {
+		t.Fatalf("unexpected event %.100s", b)
+	}
+	if len(msg.Data) != 1 || msg.Desc != desc {
+		t.Fatal("message was modified")
+	}
+}
+
+func TestRejected(t *testing.T) {
+	err := rejected(&types.RejectedLogEventsInfo{TooOldLogEventEndIndex: aws.Int32(2), TooNewLogEventStartIndex: aws.Int32(9)}, 10)
+	if err == nil || !module.IsPermanent(err) || err.Error() != "cloudwatch rejected log events: 2 too old, 0 expired, 1 too new" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+module github.com/halliday/go-module/cloudwatchsink
+
+go 1.21
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.31.0
+	github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs v1.40.0
+	github.com/halliday/go-errors v0.0.0-20221117114904-701c88d594be
+	github.com/halliday/go-module v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream v1.6.5 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.18 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.18 // indirect
+	github.com/aws/smithy-go v1.21.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	golang.org/x/text v0.13.0 // indirect
+)
+
+replace github.com/halliday/go-module => ../
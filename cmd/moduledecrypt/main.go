@@ -0,0 +1,47 @@
+// Command moduledecrypt decrypts files written by an EncryptedFileSink
+// and prints the records as JSON lines.
+//
+// Usage:
+//
+//	MODULE_KEY=<hex key> moduledecrypt file...
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	module "github.com/halliday/go-module"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: MODULE_KEY=<hex key> moduledecrypt file...")
+		os.Exit(2)
+	}
+	key, err := hex.DecodeString(os.Getenv("MODULE_KEY"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "MODULE_KEY:", err)
+		os.Exit(2)
+	}
+	for _, file := range os.Args[1:] {
+		if err := decrypt(file, key); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func decrypt(file string, key []byte) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := module.NewDecryptReader(f, key)
+	if err != nil {
+		return err
+	}
+	_, err = r.WriteTo(os.Stdout)
+	return err
+}
@@ -0,0 +1,56 @@
+// Command modulelint checks message catalogs for common mistakes.
+//
+// Usage:
+//
+//	modulelint [-links] catalog.csv...
+//
+// The files are checked as one catalog, so names and codes must be unique across them;
+// run it once per locale. Each problem is printed as file:line: message. The exit code
+// is 1 if any problem was found and 2 if a catalog could not be read.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	module "github.com/halliday/go-module"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("modulelint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	links := flags.Bool("links", false, "report entries without a link")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: modulelint [-links] catalog.csv...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	var files []module.LintFile
+	for _, file := range flags.Args() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		files = append(files, module.LintFile{Name: file, Src: string(data)})
+	}
+	status := 0
+	for _, d := range module.LintFiles(files, module.LintOptions{RequireLinks: *links}) {
+		fmt.Fprintln(stdout, d)
+		status = 1
+	}
+	return status
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.csv")
+	b := filepath.Join(dir, "b.csv")
+	os.WriteFile(a, []byte("a;1;A;https://example.com/a\n"), 0o644)
+	os.WriteFile(b, []byte("b;1;B\n"), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{a}, &stdout, &stderr); status != 0 || stdout.Len() != 0 {
+		t.Fatalf("status %d: %s", status, stdout.String())
+	}
+	expected := b + `:1: "b": duplicate code 1, already used by "a" on ` + a + ":1\n" + b + `:1: "b": missing link` + "\n"
+	if status := run([]string{"-links", a, b}, &stdout, &stderr); status != 1 || stdout.String() != expected {
+		t.Fatalf("status %d: %q", status, stdout.String())
+	}
+	if status := run([]string{filepath.Join(dir, "missing.csv")}, &stdout, &stderr); status != 2 {
+		t.Fatalf("status %d for a missing file", status)
+	}
+	if status := run(nil, &stdout, &stderr); status != 2 {
+		t.Fatalf("status %d without files", status)
+	}
+}
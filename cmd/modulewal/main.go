@@ -0,0 +1,62 @@
+// Command modulewal verifies and dumps the write-ahead logs of audit modules (see module.WAL).
+//
+// Usage:
+//
+//	modulewal [-dump] dir...
+//
+// For each directory it prints the number of segments and records, and each problem found.
+// With -dump, the messages are printed as JSON lines instead. The exit code is 1 if any
+// problem was found and 2 if a directory could not be read.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	module "github.com/halliday/go-module"
+)
+
+func main() {
+	dump := flag.Bool("dump", false, "print the messages as JSON lines")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: modulewal [-dump] dir...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	status := 0
+	for _, dir := range flag.Args() {
+		if _, err := os.Stat(dir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if *dump {
+			enc := json.NewEncoder(os.Stdout)
+			if err := module.ReadWAL(dir, func(msg *module.Message) error { return enc.Encode(msg) }); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", dir, err)
+				status = 1
+			}
+			continue
+		}
+		report, err := module.VerifyWAL(dir)
+		fmt.Printf("%s: %d segment(s), %d record(s)", dir, report.Segments, report.Records)
+		if report.Torn {
+			fmt.Print(", torn last record")
+		}
+		fmt.Println()
+		if err != nil {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				fmt.Println(line)
+			}
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
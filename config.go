@@ -0,0 +1,50 @@
+package module
+
+import "log"
+
+// SetMask sets the Mask. Unlike assigning the field, it is safe while the Module is in use,
+// e.g. when the level is changed by an admin endpoint or on SIGHUP.
+func (m *Module) SetMask(mask Level) {
+	m.maskMu.Lock()
+	m.Mask = mask
+	m.maskSet = true
+	m.maskMu.Unlock()
+}
+
+// GetMask returns the Mask set by SetMask.
+func (m *Module) GetMask() Level {
+	m.maskMu.RLock()
+	defer m.maskMu.RUnlock()
+	return m.Mask
+}
+
+// SetHook sets the Hook. Unlike assigning the field, it is safe while the Module is in use.
+func (m *Module) SetHook(hook Hook) {
+	m.configMu.Lock()
+	m.Hook = hook
+	m.configMu.Unlock()
+}
+
+func (m *Module) hook() Hook {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	return m.Hook
+}
+
+// SetLogger sets the Logger. Unlike assigning the field, it is safe while the Module is in use.
+func (m *Module) SetLogger(logger *log.Logger) {
+	m.configMu.Lock()
+	m.Logger = logger
+	m.configMu.Unlock()
+}
+
+// logger returns the Logger, or log.Default for a zero Module.
+func (m *Module) logger() *log.Logger {
+	m.configMu.RLock()
+	logger := m.Logger
+	m.configMu.RUnlock()
+	if logger == nil {
+		return log.Default()
+	}
+	return logger
+}
@@ -0,0 +1,52 @@
+package module
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSetConcurrently(t *testing.T) {
+	_, _, m := New("module", messages)
+	m.Logger = log.New(io.Discard, "", 0)
+
+	var hooked int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Info("test", "A", j)
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		m.SetMask(Error)
+		m.SetMask(AllLevels)
+		m.SetHook(func(msg *Message) *Message {
+			atomic.AddInt32(&hooked, 1)
+			return msg
+		})
+		m.SetLogger(log.New(io.Discard, "", 0))
+	}
+	wg.Wait()
+
+	var b bytes.Buffer
+	m.SetLogger(log.New(&b, "", 0))
+	m.SetMask(Warn)
+	if m.GetMask() != Warn {
+		t.Fatal("mask was not set")
+	}
+	m.Info("test", "A", 1)
+	m.Warn("test", "A", 2)
+	if want := "[WARN ] This is a test message A=2\n"; b.String() != want {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+	if atomic.LoadInt32(&hooked) == 0 {
+		t.Fatal("hook was not called")
+	}
+}
@@ -0,0 +1,144 @@
+// Package connecterr maps module errors to Connect errors.
+//
+// The name, code, link and data of the first named error in the chain travel as error metadata,
+// which Connect sends as headers or trailers:
+//
+//	return nil, connecterr.Error(err)
+//
+// and on the client side:
+//
+//	err = connecterr.FromError(err)
+//
+// The Connect code is derived from the error, see Code.
+package connecterr
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	rich "github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+
+	"connectrpc.com/connect"
+)
+
+// Metadata keys of the error. Data is JSON encoded.
+const (
+	KeyName = "Module-Name"
+	KeyCode = "Module-Code"
+	KeyLink = "Module-Link"
+	KeyData = "Module-Data"
+)
+
+// CodeOf, if set, maps a module error to a Connect code before Code does, e.g. for catalog codes
+// that are not HTTP statuses. It returns false for the default mapping. Set it during initialization.
+var CodeOf func(e *module.EncodedError) (connect.Code, bool)
+
+// httpCodes maps HTTP statuses to Connect codes.
+var httpCodes = map[int]connect.Code{
+	http.StatusBadRequest:                   connect.CodeInvalidArgument,
+	http.StatusUnauthorized:                 connect.CodeUnauthenticated,
+	http.StatusForbidden:                    connect.CodePermissionDenied,
+	http.StatusNotFound:                     connect.CodeNotFound,
+	http.StatusRequestTimeout:               connect.CodeDeadlineExceeded,
+	http.StatusConflict:                     connect.CodeAlreadyExists,
+	http.StatusPreconditionFailed:           connect.CodeFailedPrecondition,
+	http.StatusRequestedRangeNotSatisfiable: connect.CodeOutOfRange,
+	http.StatusTooManyRequests:              connect.CodeResourceExhausted,
+	499:                                     connect.CodeCanceled,
+	http.StatusInternalServerError:          connect.CodeInternal,
+	http.StatusNotImplemented:               connect.CodeUnimplemented,
+	http.StatusServiceUnavailable:           connect.CodeUnavailable,
+	http.StatusGatewayTimeout:               connect.CodeDeadlineExceeded,
+}
+
+// Code returns the Connect code of the first named error in the chain of err: the code of CodeOf,
+// the code for its catalog code as HTTP status (404 is CodeNotFound), or the code its name ends with
+// ("user_not_found" is CodeNotFound). Otherwise it is CodeUnknown.
+func Code(err error) connect.Code {
+	named := module.NamedError(err)
+	if named == nil {
+		return connect.CodeUnknown
+	}
+	e := module.EncodeError(named)
+	if CodeOf != nil {
+		if code, ok := CodeOf(e); ok {
+			return code
+		}
+	}
+	if code, ok := httpCodes[e.Code]; ok {
+		return code
+	}
+	for code := connect.CodeCanceled; code <= connect.CodeUnauthenticated; code++ {
+		if name := code.String(); code != connect.CodeUnknown && (e.Name == name || strings.HasSuffix(e.Name, "_"+name) || strings.HasSuffix(e.Name, "."+name)) {
+			return code
+		}
+	}
+	return connect.CodeUnknown
+}
+
+// Error returns a Connect error with the Code of err wrapping err, or nil for a nil err. The message
+// of the Connect error is the description of the first named error in the chain of err, its name,
+// code, link and data are added as metadata.
+func Error(err error) *connect.Error {
+	if err == nil {
+		return nil
+	}
+	code := Code(err)
+	named := module.NamedError(err)
+	if named == nil {
+		return connect.NewError(code, err)
+	}
+	e := module.EncodeError(named)
+	ce := connect.NewError(code, &described{err, e.Desc})
+	meta := ce.Meta()
+	meta.Set(KeyName, e.Name)
+	if e.Code != 0 {
+		meta.Set(KeyCode, strconv.Itoa(e.Code))
+	}
+	if e.Link != "" {
+		meta.Set(KeyLink, e.Link)
+	}
+	if e.Data != nil {
+		if data, err := json.Marshal(e.Data); err == nil {
+			meta.Set(KeyData, string(data))
+		}
+	}
+	return ce
+}
+
+// described replaces the message of an error and keeps the chain for errors.Is and errors.As.
+type described struct {
+	err  error
+	desc string
+}
+
+func (d *described) Error() string { return d.desc }
+func (d *described) Unwrap() error { return d.err }
+
+// FromError reconstructs the RichError described by the metadata of the Connect error in the chain of err.
+// Other errors are returned unchanged.
+func FromError(err error) error {
+	var ce *connect.Error
+	if !errors.As(err, &ce) {
+		return err
+	}
+	meta := ce.Meta()
+	name := meta.Get(KeyName)
+	if name == "" {
+		return err
+	}
+	e := &rich.RichError{
+		Name: name,
+		Desc: ce.Message(),
+		Link: meta.Get(KeyLink),
+	}
+	e.Code, _ = strconv.Atoi(meta.Get(KeyCode))
+	if data := meta.Get(KeyData); data != "" {
+		json.Unmarshal([]byte(data), &e.Data)
+	}
+	return e
+}
@@ -0,0 +1,64 @@
+package connecterr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	rich "github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+
+	"connectrpc.com/connect"
+)
+
+func TestRoundTrip(t *testing.T) {
+	m := module.NewWithOptions("auth", "user_not_found;404;user %s not found;https://example.com/404\n")
+	err := fmt.Errorf("lookup: %w", m.NewError("user_not_found", "bob", "tenant", "acme"))
+
+	ce := Error(err)
+	if ce.Code() != connect.CodeNotFound || ce.Message() != "user bob not found" || !errors.Is(ce, err) {
+		t.Fatalf("unexpected error %v", ce)
+	}
+	if ce.Meta().Get(KeyName) != "user_not_found" || ce.Meta().Get(KeyCode) != "404" {
+		t.Fatalf("unexpected metadata %v", ce.Meta())
+	}
+
+	received := FromError(ce)
+	expected := &rich.RichError{
+		Name: "user_not_found",
+		Code: 404,
+		Desc: "user bob not found",
+		Link: "https://example.com/404",
+		Data: map[string]interface{}{"tenant": "acme", module.KeyFormatArgs: []interface{}{"bob"}},
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Fatalf("unexpected error %#v", received)
+	}
+
+	plain := connect.NewError(connect.CodeInternal, errors.New("boom"))
+	if FromError(plain) != error(plain) {
+		t.Fatal("error without metadata was replaced")
+	}
+}
+
+func TestCode(t *testing.T) {
+	m := module.NewWithOptions("auth", "user_not_found;404;user not found\ndb.unavailable;0;database down\nlocked;7;account locked\n")
+	CodeOf = func(e *module.EncodedError) (connect.Code, bool) {
+		return connect.CodePermissionDenied, e.Code == 7
+	}
+	defer func() { CodeOf = nil }()
+	for err, expected := range map[error]connect.Code{
+		errors.New("boom"):                        connect.CodeUnknown,
+		m.NewError("user_not_found"):              connect.CodeNotFound,
+		m.NewError("db.unavailable"):              connect.CodeUnavailable,
+		fmt.Errorf("x: %w", m.NewError("locked")): connect.CodePermissionDenied,
+	} {
+		if code := Code(err); code != expected {
+			t.Errorf("Code(%v) = %v, expected %v", err, code, expected)
+		}
+	}
+	if Error(nil) != nil {
+		t.Fatal("error for nil error")
+	}
+}
@@ -0,0 +1,18 @@
+module github.com/halliday/go-module/connecterr
+
+go 1.21
+
+require (
+	connectrpc.com/connect v1.5.2
+	github.com/halliday/go-errors v0.0.0-20221117114904-701c88d594be
+	github.com/halliday/go-module v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	golang.org/x/text v0.13.0 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
+
+replace github.com/halliday/go-module => ../
@@ -0,0 +1,41 @@
+package module
+
+import (
+	"context"
+
+	"github.com/halliday/go-errors"
+)
+
+const KeyCorrelationID = "correlation_id"
+
+// CtxWithCorrelationID tags the context with a correlation ID. NewError copies it onto new errors,
+// so it survives when the error is passed up and reported elsewhere.
+func CtxWithCorrelationID(ctx context.Context, id string) context.Context {
+	return CtxTag(ctx, KeyCorrelationID, id)
+}
+
+// CorrelationID returns the first correlation ID found in the Data of err and its causes.
+func CorrelationID(err error) (id string, ok bool) {
+	walkErrors(err, func(err error) bool {
+		if d, ok := err.(errors.DataError); ok {
+			if data, ok := d.ErrorData().(map[string]interface{}); ok {
+				id, _ = data[KeyCorrelationID].(string)
+			}
+		}
+		return id == ""
+	})
+	return id, id != ""
+}
+
+// correlate returns the correlation ID for a new error or message: the one of the cause, or of the context.
+func correlate(ctx context.Context, causedBy error) (id string, ok bool) {
+	if id, ok = CorrelationID(causedBy); ok {
+		return id, true
+	}
+	if ctx == nil {
+		return "", false
+	}
+	v, _ := CtxTagValue(ctx, KeyCorrelationID)
+	id, ok = v.(string)
+	return id, ok && id != ""
+}
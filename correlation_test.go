@@ -0,0 +1,32 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+)
+
+func TestCorrelationID(t *testing.T) {
+	var b bytes.Buffer
+	l, e, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+
+	ctx := CtxWithCorrelationID(context.Background(), "c1")
+	inner := e("test2", ctx)
+	outer := e("test", inner)
+	if id, _ := CorrelationID(outer); id != "c1" {
+		t.Fatalf("correlation id was not propagated: %q", id)
+	}
+
+	l.Err("test3", outer)
+	if b.String() != "[ERR  ] Some more tests over here. correlation_id=c1 (caused by 123 test This is a test message (caused by 234 test2 This is a another test message))\n" {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+
+	b.Reset()
+	m.Report(outer)
+	if b.String() != "[ERR  ] This is a test message correlation_id=c1 (caused by 234 test2 This is a another test message)\n" {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+}
@@ -0,0 +1,125 @@
+package module
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// maxEncryptedRecord limits the size of a record, so that a corrupt length can not make
+// DecryptReader allocate up to 4 GiB.
+const maxEncryptedRecord = 16 << 20
+
+// EncryptedFileSink appends messages to a file, each JSON record encrypted with AES-GCM.
+// Every record is stored as a 4 byte big endian length, followed by the nonce and the sealed JSON.
+// Use DecryptReader to read the records back.
+type EncryptedFileSink struct {
+	*FileSink
+	aead cipher.AEAD
+}
+
+// OpenEncryptedFileSink opens (or creates) the file for appending.
+// The key must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+func OpenEncryptedFileSink(path string, key []byte) (*EncryptedFileSink, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	s, err := OpenFileSink(path)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedFileSink{s, aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *EncryptedFileSink) Write(msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	size := s.aead.NonceSize() + len(data) + s.aead.Overhead()
+	if size > maxEncryptedRecord {
+		return Permanent(fmt.Errorf("encrypted record of %d bytes exceeds %d", size, maxEncryptedRecord))
+	}
+	record := make([]byte, 4+s.aead.NonceSize(), 4+size)
+	binary.BigEndian.PutUint32(record, uint32(size))
+	nonce := record[4:]
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	record = s.aead.Seal(record, nonce, data, nil)
+	return s.write(record)
+}
+
+// DecryptReader reads the records written by an EncryptedFileSink.
+type DecryptReader struct {
+	r    *bufio.Reader
+	aead cipher.AEAD
+	n    int
+}
+
+func NewDecryptReader(r io.Reader, key []byte) (*DecryptReader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &DecryptReader{r: bufio.NewReader(r), aead: aead}, nil
+}
+
+// Next returns the JSON of the next record, or io.EOF after the last record.
+func (d *DecryptReader) Next() ([]byte, error) {
+	var head [4]byte
+	if _, err := io.ReadFull(d.r, head[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("record %d: truncated", d.n+1)
+		}
+		return nil, err
+	}
+	d.n++
+	size := int(binary.BigEndian.Uint32(head[:]))
+	if size < d.aead.NonceSize()+d.aead.Overhead() || size > maxEncryptedRecord {
+		return nil, fmt.Errorf("record %d: bad size %d", d.n, size)
+	}
+	record := make([]byte, size)
+	if _, err := io.ReadFull(d.r, record); err != nil {
+		return nil, fmt.Errorf("record %d: truncated", d.n)
+	}
+	nonce := record[:d.aead.NonceSize()]
+	data, err := d.aead.Open(record[len(nonce):len(nonce)], nonce, record[len(nonce):], nil)
+	if err != nil {
+		return nil, fmt.Errorf("record %d: %w", d.n, err)
+	}
+	return data, nil
+}
+
+// WriteTo decrypts all remaining records and writes them to w as JSON lines.
+func (d *DecryptReader) WriteTo(w io.Writer) (n int64, err error) {
+	for {
+		data, err := d.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		data = append(data, '\n')
+		m, err := w.Write(data)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package module
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedFileSink(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	path := filepath.Join(t.TempDir(), "log.enc")
+	sink, err := OpenEncryptedFileSink(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _, m := New("module", messages)
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+	m.Sinks = []Sink{sink}
+	l.Info("test", "A", 1)
+	l.Warn("test2")
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("test message")) {
+		t.Fatal("file is not encrypted")
+	}
+
+	r, err := NewDecryptReader(bytes.NewReader(data), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if _, err := r.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"name":"test"`) || !strings.Contains(lines[1], `"name":"test2"`) {
+		t.Fatalf("unexpected records:\n%s", out.String())
+	}
+
+	r, _ = NewDecryptReader(bytes.NewReader(data), bytes.Repeat([]byte{8}, 32))
+	if _, err := r.Next(); err == nil {
+		t.Fatal("wrong key was accepted")
+	}
+
+	// a corrupt length is rejected before allocating
+	r, _ = NewDecryptReader(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 1, 2, 3}), key)
+	if _, err := r.Next(); err == nil || !strings.Contains(err.Error(), "bad size") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package module
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// csvColumns are the columns of a CSVWriter before the data columns.
+var csvColumns = []string{"time", "module", "level", "name", "code", "desc", "id", "caller", "caused_by"}
+
+// CSVWriter writes messages as CSV rows for the analysis in a spreadsheet, with a header row first.
+// The columns are time, module, level, name, code, desc, id, caller and caused_by, followed by
+// the selected data keys, or by a data column with all data as JSON if no keys were selected.
+// Values that a spreadsheet would run as a formula get a leading "'".
+// It is a Sink and a BatchWriter; rows are buffered until Flush.
+type CSVWriter struct {
+	data []string
+
+	mu     sync.Mutex
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVWriter returns a CSVWriter with a column for each of the data keys.
+func NewCSVWriter(w io.Writer, data ...string) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w), data: data}
+}
+
+// WriteCSV writes msgs, e.g. messages captured in a test, as CSV, see CSVWriter.
+func WriteCSV(w io.Writer, msgs []*Message, data ...string) error {
+	c := NewCSVWriter(w, data...)
+	if err := c.WriteBatch(msgs); err != nil {
+		return err
+	}
+	return c.Flush()
+}
+
+func (c *CSVWriter) Write(msg *Message) error {
+	return c.WriteBatch([]*Message{msg})
+}
+
+func (c *CSVWriter) WriteBatch(msgs []*Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.header {
+		header := append([]string(nil), csvColumns...)
+		if len(c.data) == 0 {
+			header = append(header, "data")
+		}
+		if err := c.w.Write(append(header, c.data...)); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	for _, msg := range msgs {
+		record, err := c.record(msg)
+		if err != nil {
+			return err
+		}
+		if err := c.w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CSVWriter) record(msg *Message) ([]string, error) {
+	record := make([]string, len(csvColumns), len(csvColumns)+max(1, len(c.data)))
+	record[0] = msg.Time.UTC().Format(time.RFC3339Nano)
+	record[1] = csvValue(msg.Module)
+	record[2] = msg.Level.String()
+	if msg.RichError != nil {
+		record[3] = csvValue(msg.Name)
+		if msg.Code != 0 {
+			record[4] = strconv.Itoa(msg.Code)
+		}
+		record[5] = csvValue(msg.Desc)
+		if msg.CausedBy != nil {
+			record[8] = csvValue(msg.CausedBy.Error())
+		}
+	}
+	record[6] = msg.ID
+	record[7] = csvValue(msg.Caller)
+	if len(c.data) == 0 {
+		var data string
+		if len(msg.Data) > 0 {
+			b, err := json.Marshal(msg.Data)
+			if err != nil {
+				return nil, err
+			}
+			data = string(b)
+		}
+		return append(record, csvValue(data)), nil
+	}
+	for _, key := range c.data {
+		var value string
+		switch v := msg.Data[key].(type) {
+		case nil:
+		case string:
+			value = v
+		case time.Time:
+			value = v.Format(time.RFC3339Nano)
+		default:
+			value = fmt.Sprint(v)
+		}
+		record = append(record, csvValue(value))
+	}
+	return record, nil
+}
+
+// csvValue prefixes values starting with '=', '+', '-', '@', tab or CR with "'", unless they are numbers,
+// so a spreadsheet shows them as text instead of running them as formulas.
+func csvValue(s string) string {
+	if s == "" || !strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return s
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s
+	}
+	return "'" + s
+}
+
+// Flush writes the buffered rows.
+func (c *CSVWriter) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.w.Flush()
+	return c.w.Error()
+}
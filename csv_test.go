@@ -0,0 +1,48 @@
+package module
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+)
+
+func TestWriteCSV(t *testing.T) {
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC)
+	msgs := []*Message{
+		{
+			Module:    "auth",
+			Level:     Warn,
+			Time:      t0,
+			ID:        "01GJ3Q2V4R8N6YH3T2KXW5Z9CD",
+			RichError: errors.NewRich("login_failed", 1001, "Login failed, \"bob\"", "", nil, errors.New("db down")),
+			Data:      map[string]interface{}{"user": "=HYPERLINK(\"x\")", "attempts": 3, "delta": -1},
+		},
+		{Module: "billing", Level: Info, Time: t0},
+	}
+	var b strings.Builder
+	if err := WriteCSV(&b, msgs, "user", "attempts", "delta"); err != nil {
+		t.Fatal(err)
+	}
+	want := `time,module,level,name,code,desc,id,caller,caused_by,user,attempts,delta
+2022-11-17T12:00:00Z,auth,warn,login_failed,1001,"Login failed, ""bob""",01GJ3Q2V4R8N6YH3T2KXW5Z9CD,,db down,"'=HYPERLINK(""x"")",3,-1
+2022-11-17T12:00:00Z,billing,info,,,,,,,,,
+`
+	if b.String() != want {
+		t.Fatalf("unexpected CSV:\n%s", b.String())
+	}
+
+	b.Reset()
+	w := NewCSVWriter(&b)
+	for _, msg := range msgs {
+		if err := w.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Flush()
+	lines := strings.Split(b.String(), "\n")
+	if !strings.HasSuffix(lines[0], ",caused_by,data") || !strings.HasSuffix(lines[1], `,"{""attempts"":3,""delta"":-1,""user"":""=HYPERLINK(\""x\"")""}"`) {
+		t.Fatalf("unexpected CSV:\n%s", b.String())
+	}
+}
@@ -0,0 +1,36 @@
+package module
+
+import "context"
+
+// LogCtx logs like Log with an explicit ctx. The args are not searched for a context:
+// they are the format arguments, optionally followed by the cause and the key/value pairs.
+func (m *Module) LogCtx(ctx context.Context, level Level, name string, args ...interface{}) {
+	if !m.allow(name) {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	code, desc, link, tail, ctx, causedBy := m.lookup(ctx, level, name, args)
+	m.log(ctx, level, name, code, desc, link, tail, causedBy)
+}
+
+func (m *Module) DebugCtx(ctx context.Context, name string, args ...interface{}) {
+	m.LogCtx(ctx, Debug, name, args...)
+}
+
+func (m *Module) TraceCtx(ctx context.Context, name string, args ...interface{}) {
+	m.LogCtx(ctx, Trace, name, args...)
+}
+
+func (m *Module) InfoCtx(ctx context.Context, name string, args ...interface{}) {
+	m.LogCtx(ctx, Info, name, args...)
+}
+
+func (m *Module) WarnCtx(ctx context.Context, name string, args ...interface{}) {
+	m.LogCtx(ctx, Warn, name, args...)
+}
+
+func (m *Module) ErrCtx(ctx context.Context, name string, args ...interface{}) {
+	m.LogCtx(ctx, Error, name, args...)
+}
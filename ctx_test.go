@@ -0,0 +1,40 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+)
+
+func TestLogCtx(t *testing.T) {
+	var b bytes.Buffer
+	_, e, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+	var tenant string
+	m.Hook = func(msg *Message) *Message {
+		tenant, _ = msg.Data[KeyTenant].(string)
+		return msg
+	}
+
+	ctx := CtxWithTenant(context.Background(), "acme")
+	m.InfoCtx(ctx, "test", "A", 1)
+	if tenant != "acme" {
+		t.Fatalf("context was not used: %q", tenant)
+	}
+	m.ErrCtx(ctx, "test", e("test2"), "A", 2)
+	m.WarnCtx(nil, "test", "A", 3)
+	m.DebugCtx(ctx, "test", "A", 4)
+	if want := "[INFO ] This is a test message A=1 tenant=acme\n" +
+		"[ERR  ] This is a test message A=2 tenant=acme (caused by 234 test2 This is a another test message)\n" +
+		"[WARN ] This is a test message A=3\n"; b.String() != want {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+
+	m.Unknown = UnknownFallback
+	b.Reset()
+	m.InfoCtx(ctx, "missing", context.Background())
+	if tenant != "acme" {
+		t.Fatal("context argument of unknown message replaced the explicit context")
+	}
+}
@@ -0,0 +1,224 @@
+// Package datadogsink sends module messages to the Datadog HTTP logs intake.
+//
+//	sink := datadogsink.New(datadogsink.Options{APIKey: os.Getenv("DD_API_KEY"), Tags: []string{"env:prod"}})
+//	m.Sinks = append(m.Sinks, module.NewBatchSink(sink, module.BatchOptions{}))
+//
+// The service of a message is its module, the status its level, and its tags include
+// "module", "level" and "name". The trace_id and span_id of messages become dd.trace_id
+// and dd.span_id, which links the logs to APM traces.
+package datadogsink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	module "github.com/halliday/go-module"
+)
+
+// Limits of the intake.
+const (
+	maxEntries = 1000
+	maxBody    = 5 << 20
+)
+
+type Options struct {
+	// APIKey is sent as DD-API-KEY. Required.
+	APIKey string
+	// Site is the Datadog site, like "datadoghq.eu". Default "datadoghq.com".
+	Site string
+	// URL replaces the intake URL of Site, for example with a proxy.
+	URL string
+	// Service is the service of all messages. Default the module of each message.
+	Service string
+	// Source is the ddsource, which selects the log pipeline. Default "go".
+	Source string
+	// Hostname is the host of the messages. Default os.Hostname.
+	Hostname string
+	// Tags are added to the tags of every message, like "env:prod".
+	Tags []string
+	// TagKeys are data keys that become tags of the messages that have them.
+	TagKeys []string
+	// Compress compresses the requests.
+	Compress module.Compression
+	// Client sends the requests. Default http.DefaultClient.
+	Client *http.Client
+}
+
+// Sink is a module.Sink and module.BatchWriter sending messages to the intake.
+// Batches are split to the limits of 1000 entries and 5 MB per request.
+type Sink struct {
+	url  string
+	opts Options
+}
+
+// New returns a Sink for opts. It panics if opts.APIKey is empty.
+func New(opts Options) *Sink {
+	if opts.APIKey == "" {
+		panic("datadogsink.New: empty API key")
+	}
+	if opts.Site == "" {
+		opts.Site = "datadoghq.com"
+	}
+	if opts.Source == "" {
+		opts.Source = "go"
+	}
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.URL == "" {
+		opts.URL = "https://http-intake.logs." + opts.Site + "/api/v2/logs"
+	}
+	return &Sink{url: opts.URL, opts: opts}
+}
+
+// Status returns the Datadog status of level.
+func Status(level module.Level) string {
+	switch level {
+	case module.Trace, module.Debug:
+		return "debug"
+	case module.Warn:
+		return "warning"
+	case module.Error:
+		return "error"
+	}
+	return "info"
+}
+
+type entry struct {
+	Source    string                 `json:"ddsource"`
+	Tags      string                 `json:"ddtags"`
+	Hostname  string                 `json:"hostname,omitempty"`
+	Service   string                 `json:"service"`
+	Status    string                 `json:"status"`
+	Message   string                 `json:"message"`
+	Timestamp int64                  `json:"timestamp"`
+	Module    string                 `json:"module"`
+	Name      string                 `json:"name,omitempty"`
+	Code      int                    `json:"code,omitempty"`
+	Link      string                 `json:"link,omitempty"`
+	ID        string                 `json:"id,omitempty"`
+	Caller    string                 `json:"caller,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Error     *errorAttr             `json:"error,omitempty"`
+	TraceID   string                 `json:"dd.trace_id,omitempty"`
+	SpanID    string                 `json:"dd.span_id,omitempty"`
+}
+
+type errorAttr struct {
+	Kind    string `json:"kind,omitempty"`
+	Message string `json:"message"`
+}
+
+func (s *Sink) entry(msg *module.Message) *entry {
+	e := &entry{
+		Source:    s.opts.Source,
+		Hostname:  s.opts.Hostname,
+		Service:   s.opts.Service,
+		Status:    Status(msg.Level),
+		Timestamp: msg.Time.UnixMilli(),
+		Module:    msg.Module,
+		ID:        msg.ID,
+		Caller:    msg.Caller,
+		Data:      msg.Data,
+	}
+	if e.Service == "" {
+		e.Service = msg.Module
+	}
+	tags := append([]string{"module:" + msg.Module, "level:" + msg.Level.String()}, s.opts.Tags...)
+	if msg.RichError != nil {
+		e.Name, e.Code, e.Message, e.Link = msg.Name, msg.Code, msg.Desc, msg.Link
+		if msg.Name != "" {
+			tags = append(tags, "name:"+msg.Name)
+		}
+		if msg.CausedBy != nil {
+			e.Error = &errorAttr{Kind: msg.Name, Message: msg.CausedBy.Error()}
+		}
+	}
+	for _, key := range s.opts.TagKeys {
+		if value, ok := msg.Data[key]; ok {
+			tags = append(tags, key+":"+fmt.Sprint(value))
+		}
+	}
+	e.Tags = strings.Join(tags, ",")
+	if traceID, ok := msg.Data[module.KeyTraceID].(string); ok {
+		e.TraceID = ddID(traceID)
+		if spanID, ok := msg.Data[module.KeySpanID].(string); ok {
+			e.SpanID = ddID(spanID)
+		}
+	}
+	return e
+}
+
+// ddID returns the decimal of the lower 64 bits of a hex trace or span ID, as Datadog expects them.
+func ddID(hex string) string {
+	if len(hex) > 16 {
+		hex = hex[len(hex)-16:]
+	}
+	id, err := strconv.ParseUint(hex, 16, 64)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatUint(id, 10)
+}
+
+func (s *Sink) Write(msg *module.Message) error {
+	return s.WriteBatch([]*module.Message{msg})
+}
+
+func (s *Sink) WriteBatch(msgs []*module.Message) error {
+	var entries []json.RawMessage
+	size := 2
+	for _, msg := range msgs {
+		b, err := json.Marshal(s.entry(msg))
+		if err != nil {
+			return err
+		}
+		if len(entries) == maxEntries || len(entries) > 0 && size+len(b)+1 > maxBody {
+			if err := s.post(entries); err != nil {
+				return err
+			}
+			entries, size = nil, 2
+		}
+		entries = append(entries, b)
+		size += len(b) + 1
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return s.post(entries)
+}
+
+func (s *Sink) post(entries []json.RawMessage) error {
+	body, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if body, err = module.Compress(body, s.opts.Compress, 0); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return module.Permanent(err)
+	}
+	req.Header.Set("DD-API-KEY", s.opts.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	if s.opts.Compress != module.NoCompression {
+		req.Header.Set("Content-Encoding", s.opts.Compress.Encoding())
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	resp.Body = io.NopCloser(io.LimitReader(resp.Body, 512))
+	return module.ResponseError(resp)
+}
@@ -0,0 +1,89 @@
+package datadogsink
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+func TestWriteBatch(t *testing.T) {
+	var requests [][]map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("DD-API-KEY") != "key" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		var entries []map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, entries)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	s := New(Options{APIKey: "key", URL: srv.URL, Hostname: "host-1", Tags: []string{"env:prod"}, TagKeys: []string{"tenant"}})
+	msg := &module.Message{
+		Module:    "auth",
+		Level:     module.Warn,
+		Time:      time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC),
+		RichError: errors.NewRich("login_failed", 1001, "Login failed", "", nil, errors.New("bad password")),
+		Data: map[string]interface{}{
+			"tenant":          "acme",
+			module.KeyTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			module.KeySpanID:  "00f067aa0ba902b7",
+		},
+	}
+	if err := s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	e := requests[0][0]
+	for key, want := range map[string]interface{}{
+		"ddsource":    "go",
+		"ddtags":      "module:auth,level:warn,env:prod,name:login_failed,tenant:acme",
+		"hostname":    "host-1",
+		"service":     "auth",
+		"status":      "warning",
+		"message":     "Login failed",
+		"timestamp":   float64(msg.Time.UnixMilli()),
+		"code":        1001.0,
+		"dd.trace_id": "11803532876627986230",
+		"dd.span_id":  "67667974448284343",
+	} {
+		if e[key] != want {
+			t.Fatalf("%s = %v, want %v", key, e[key], want)
+		}
+	}
+	if e["error"].(map[string]interface{})["message"] != "bad password" {
+		t.Fatalf("unexpected error %v", e["error"])
+	}
+
+	requests = nil
+	msgs := make([]*module.Message, maxEntries+1)
+	for i := range msgs {
+		msgs[i] = &module.Message{Module: "auth", Level: module.Info, Time: msg.Time}
+	}
+	if err := s.WriteBatch(msgs); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || len(requests[1]) != 1 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+
+	if err := New(Options{APIKey: "wrong", URL: srv.URL}).Write(msg); !module.IsPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	for level, want := range map[module.Level]string{module.Trace: "debug", module.Debug: "debug", module.None: "info", module.Info: "info", module.Warn: "warning", module.Error: "error"} {
+		if got := Status(level); got != want {
+			t.Fatalf("Status(%v) = %s, want %s", level, got, want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package module
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type DeadLetterOptions struct {
+	// Size is the number of messages kept in the buffer.
+	Size int
+	// RetryEvery is the delay before the buffered messages are replayed after a failure. Default 1s.
+	RetryEvery time.Duration
+	// Clock is used for RetryEvery. Default SystemClock.
+	Clock Clock
+}
+
+// DeadLetterSink wraps a Sink and keeps messages that failed to write in a bounded in-memory buffer.
+// The buffered messages are replayed in order in the background, RetryEvery after a failure, until
+// the sink recovers; messages written meanwhile are buffered behind them, so the order is kept.
+// If the buffer is full, the oldest message is dropped.
+type DeadLetterSink struct {
+	sink Sink
+	opts DeadLetterOptions
+
+	replayMu sync.Mutex // serializes replays
+
+	mu        sync.Mutex
+	buf       []*Message
+	dropped   int64
+	replaying bool
+	timer     Timer
+}
+
+func NewDeadLetterSink(sink Sink, size int) *DeadLetterSink {
+	return NewDeadLetterSinkWithOptions(sink, DeadLetterOptions{Size: size})
+}
+
+func NewDeadLetterSinkWithOptions(sink Sink, opts DeadLetterOptions) *DeadLetterSink {
+	if opts.RetryEvery <= 0 {
+		opts.RetryEvery = time.Second
+	}
+	opts.Clock = clockOrSystem(opts.Clock)
+	return &DeadLetterSink{sink: sink, opts: opts}
+}
+
+// Write writes msg to the sink, or buffers it while there are buffered messages or the write fails.
+// It returns nil unless the buffer overflowed.
+func (s *DeadLetterSink) Write(msg *Message) error {
+	s.mu.Lock()
+	if len(s.buf) == 0 && !s.replaying {
+		s.mu.Unlock()
+		if err := s.sink.Write(msg); err == nil {
+			return nil
+		}
+		s.mu.Lock()
+	}
+	defer s.mu.Unlock()
+	s.buf = append(s.buf, msg)
+	s.schedule()
+	if len(s.buf) > s.opts.Size {
+		n := len(s.buf) - s.opts.Size
+		s.buf = append(s.buf[:0], s.buf[n:]...)
+		s.dropped += int64(n)
+		return fmt.Errorf("dead letter buffer full, dropped %d message(s)", n)
+	}
+	return nil
+}
+
+// schedule starts the timer for a replay, unless one is pending. s.mu must be held.
+func (s *DeadLetterSink) schedule() {
+	if s.timer == nil && !s.replaying {
+		s.timer = s.opts.Clock.AfterFunc(s.opts.RetryEvery, func() {
+			s.replay()
+		})
+	}
+}
+
+// Flush replays the buffered messages and returns the first error.
+func (s *DeadLetterSink) Flush() error {
+	return s.replay()
+}
+
+// replay writes the buffered messages in order. s.mu is not held during the writes.
+func (s *DeadLetterSink) replay() error {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+	s.mu.Lock()
+	s.replaying = true
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	for len(s.buf) > 0 {
+		msg := s.buf[0]
+		s.mu.Unlock()
+		err := s.sink.Write(msg)
+		s.mu.Lock()
+		if err != nil {
+			s.replaying = false
+			s.schedule()
+			s.mu.Unlock()
+			return err
+		}
+		// unless an overflow dropped it meanwhile
+		if len(s.buf) > 0 && s.buf[0] == msg {
+			s.buf[0] = nil
+			s.buf = s.buf[1:]
+		}
+	}
+	s.buf = nil
+	s.replaying = false
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of buffered messages.
+func (s *DeadLetterSink) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buf)
+}
+
+// Dropped returns the number of messages dropped because the buffer was full.
+func (s *DeadLetterSink) Dropped() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
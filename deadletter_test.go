@@ -0,0 +1,71 @@
+package module
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeadLetterSink(t *testing.T) {
+	var mu sync.Mutex
+	down := true
+	var written []uint64
+	clock := NewManualClock(time.Time{})
+	sink := NewDeadLetterSinkWithOptions(SinkFunc(func(msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			return errors.New("down")
+		}
+		written = append(written, msg.Seq)
+		return nil
+	}), DeadLetterOptions{Size: 3, RetryEvery: time.Second, Clock: clock})
+
+	sink.Write(&Message{Seq: 1})
+	sink.Write(&Message{Seq: 2})
+	// a failed replay is retried later
+	clock.Advance(time.Second)
+	if sink.Len() != 2 || len(clock.timers) != 1 {
+		t.Fatalf("unexpected buffer state: %d buffered", sink.Len())
+	}
+
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	// buffered behind the messages before it
+	if err := sink.Write(&Message{Seq: 3}); err != nil || len(written) != 0 {
+		t.Fatalf("message was written before the buffer: %v", err)
+	}
+	if err := sink.Write(&Message{Seq: 4}); err == nil {
+		t.Fatal("buffer overflow was not reported")
+	}
+	if sink.Len() != 3 || sink.Dropped() != 1 {
+		t.Fatalf("unexpected buffer state: %d buffered, %d dropped", sink.Len(), sink.Dropped())
+	}
+	clock.Advance(time.Second)
+	if len(written) != 3 || written[0] != 2 || written[1] != 3 || written[2] != 4 || sink.Len() != 0 {
+		t.Fatalf("unexpected messages written: %v", written)
+	}
+	if err := sink.Write(&Message{Seq: 5}); err != nil || len(written) != 4 {
+		t.Fatalf("recovered sink was not written: %v", err)
+	}
+}
+
+func TestDeadLetterSinkFlush(t *testing.T) {
+	down := true
+	sink := NewDeadLetterSink(SinkFunc(func(msg *Message) error {
+		if down {
+			return errors.New("down")
+		}
+		return nil
+	}), 10)
+	sink.Write(&Message{})
+	if err := sink.Flush(); err == nil || sink.Len() != 1 {
+		t.Fatalf("flush of a failing sink: %v", err)
+	}
+	down = false
+	if err := sink.Flush(); err != nil || sink.Len() != 0 {
+		t.Fatalf("flush: %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+// Package elasticsink indexes module messages in Elasticsearch or OpenSearch with the _bulk API.
+//
+//	sink := elasticsink.New("https://es:9200", elasticsink.Options{Index: elasticsink.Daily("logs")})
+//	m.Sinks = append(m.Sinks, module.NewBatchSink(sink, module.BatchOptions{MaxSize: 500}))
+//
+// Documents use ECS-like field names that map well without a template:
+//
+//	{"@timestamp": "2022-11-17T11:49:04.123456789Z", "module": "auth", "level": "warn",
+//	 "name": "login_failed", "code": 1001, "message": "Login failed", "data": {"user": "bob"}, ...}
+//
+// The message ID is the document _id, so a batch written again after an error does not
+// duplicate documents.
+package elasticsink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+type Options struct {
+	// Client sends the requests. Default http.DefaultClient.
+	Client *http.Client
+	// Header is added to every request, e.g. "Authorization: ApiKey ...".
+	Header http.Header
+	// Index returns the index of a message. Default Daily("logs").
+	Index func(msg *module.Message) string
+	// DataStream writes with the "create" action that data streams require, instead of "index".
+	DataStream bool
+	// StringData indexes all data values as strings, so that a key used with different types
+	// by different modules does not cause mapping conflicts.
+	StringData bool
+	// Compress compresses the requests.
+	Compress module.Compression
+	// Retries is the number of times documents rejected with 429 Too Many Requests or 5xx are sent again
+	// within WriteBatch, after a delay of Backoff that doubles for every retry or the Retry-After of the response.
+	// The delays make a BatchSink block when the cluster cannot keep up. Default 3 retries after 500ms.
+	Retries int
+	Backoff time.Duration
+}
+
+// Daily returns an Index function for indices like "logs-2022.11.17", by the day of the message (UTC).
+func Daily(prefix string) func(msg *module.Message) string {
+	return func(msg *module.Message) string {
+		return prefix + "-" + msg.Time.UTC().Format("2006.01.02")
+	}
+}
+
+// ByModule returns an Index function for indices like "logs-auth".
+func ByModule(prefix string) func(msg *module.Message) string {
+	return func(msg *module.Message) string {
+		return prefix + "-" + msg.Module
+	}
+}
+
+// Sink is a module.Sink and module.BatchWriter indexing messages with the _bulk API.
+// Documents rejected for other reasons than load, like mapping conflicts, make a Permanent error.
+type Sink struct {
+	url  string
+	opts Options
+}
+
+func New(url string, opts Options) *Sink {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Index == nil {
+		opts.Index = Daily("logs")
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = 3
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 500 * time.Millisecond
+	}
+	return &Sink{url: url + "/_bulk", opts: opts}
+}
+
+// document is a message as indexed.
+type document struct {
+	Timestamp string                 `json:"@timestamp"`
+	Module    string                 `json:"module"`
+	Level     string                 `json:"level"`
+	Name      string                 `json:"name,omitempty"`
+	Code      int                    `json:"code,omitempty"`
+	Message   string                 `json:"message"`
+	Link      string                 `json:"link,omitempty"`
+	ID        string                 `json:"id,omitempty"`
+	Seq       uint64                 `json:"seq,omitempty"`
+	Epoch     string                 `json:"epoch,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Caller    string                 `json:"caller,omitempty"`
+	CausedBy  string                 `json:"caused_by,omitempty"`
+}
+
+func (s *Sink) document(msg *module.Message) *document {
+	d := &document{
+		Timestamp: msg.Time.UTC().Format(time.RFC3339Nano),
+		Module:    msg.Module,
+		Level:     msg.Level.String(),
+		ID:        msg.ID,
+		Seq:       msg.Seq,
+		Epoch:     msg.Epoch,
+		Data:      msg.Data,
+		Caller:    msg.Caller,
+	}
+	if msg.RichError != nil {
+		d.Name, d.Code, d.Message, d.Link = msg.Name, msg.Code, msg.Desc, msg.Link
+		if msg.CausedBy != nil {
+			d.CausedBy = msg.CausedBy.Error()
+		}
+	}
+	if s.opts.StringData && len(msg.Data) > 0 {
+		d.Data = make(map[string]interface{}, len(msg.Data))
+		for key, value := range msg.Data {
+			if _, ok := value.(string); !ok {
+				b, err := json.Marshal(value)
+				if err != nil {
+					value = fmt.Sprint(value)
+				} else {
+					value = string(b)
+				}
+			}
+			d.Data[key] = value
+		}
+	}
+	return d
+}
+
+type action struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id,omitempty"`
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+func (s *Sink) Write(msg *module.Message) error {
+	return s.WriteBatch([]*module.Message{msg})
+}
+
+func (s *Sink) WriteBatch(msgs []*module.Message) error {
+	op := "index"
+	if s.opts.DataStream {
+		op = "create"
+	}
+	backoff := s.opts.Backoff
+	var failed errors.Multi
+	for retry := 0; ; retry++ {
+		var body bytes.Buffer
+		enc := json.NewEncoder(&body)
+		for _, msg := range msgs {
+			enc.Encode(map[string]action{op: {Index: s.opts.Index(msg), ID: msg.ID}})
+			if err := enc.Encode(s.document(msg)); err != nil {
+				return err
+			}
+		}
+		rejected, delay, err := s.bulk(body.Bytes(), msgs, &failed)
+		if err != nil && (len(rejected) == 0 || retry == s.opts.Retries) {
+			return errors.Join(err, failed.Reduce())
+		}
+		if len(rejected) == 0 {
+			return module.Permanent(failed.Reduce())
+		}
+		if delay == 0 {
+			delay = backoff
+		}
+		time.Sleep(delay)
+		backoff *= 2
+		msgs = rejected
+	}
+}
+
+// bulk sends a _bulk request for msgs. It returns the messages to retry because of load,
+// the delay from Retry-After and an error for them, and adds documents that failed for other reasons to failed.
+func (s *Sink) bulk(body []byte, msgs []*module.Message, failed *errors.Multi) (rejected []*module.Message, delay time.Duration, err error) {
+	body, err = module.Compress(body, s.opts.Compress, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, module.Permanent(err)
+	}
+	for key, values := range s.opts.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.opts.Compress != module.NoCompression {
+		req.Header.Set("Content-Encoding", s.opts.Compress.Encoding())
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if sec, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			delay = time.Duration(sec) * time.Second
+		}
+		resp.Body = io.NopCloser(io.LimitReader(resp.Body, 512))
+		return msgs, delay, module.ResponseError(resp)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body = io.NopCloser(io.LimitReader(resp.Body, 512))
+		return nil, 0, module.ResponseError(resp)
+	}
+
+	var r bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, 0, err
+	}
+	if !r.Errors {
+		return nil, 0, nil
+	}
+	for i, item := range r.Items {
+		if i >= len(msgs) {
+			break
+		}
+		for _, result := range item {
+			switch {
+			case result.Status < 300:
+			case result.Status == http.StatusConflict && s.opts.DataStream:
+				// created by an earlier attempt
+			case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+				rejected = append(rejected, msgs[i])
+			default:
+				failed.Append(fmt.Errorf("%s: %d %s: %s", s.opts.Index(msgs[i]), result.Status, result.Error.Type, result.Error.Reason))
+			}
+		}
+	}
+	if len(rejected) > 0 {
+		return rejected, 0, fmt.Errorf("%d of %d documents rejected by the cluster", len(rejected), len(msgs))
+	}
+	return nil, 0, nil
+}
@@ -0,0 +1,121 @@
+package elasticsink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+func messages() []*module.Message {
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC)
+	var msgs []*module.Message
+	for i, name := range []string{"a", "b", "c"} {
+		msgs = append(msgs, &module.Message{
+			Module:    "auth",
+			Level:     module.Warn,
+			Time:      t0,
+			ID:        "id-" + name,
+			RichError: errors.NewRich(name, 1000+i, "Message "+name, "", nil, nil),
+			Data:      map[string]interface{}{"n": i},
+		})
+	}
+	return msgs
+}
+
+// bulkServer answers every _bulk request with the statuses of status for the IDs of the documents.
+func bulkServer(t *testing.T, status func(attempt int, id string) int) (*httptest.Server, *[][]string) {
+	var requests [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		var ids []string
+		var items []string
+		errs := false
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var meta map[string]action
+			json.Unmarshal(scanner.Bytes(), &meta)
+			scanner.Scan()
+			id := meta["index"].ID
+			ids = append(ids, id)
+			code := status(len(requests), id)
+			errs = errs || code >= 300
+			items = append(items, fmt.Sprintf(`{"index":{"_id":%q,"status":%d,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`, id, code))
+		}
+		requests = append(requests, ids)
+		fmt.Fprintf(w, `{"errors":%t,"items":[%s]}`, errs, strings.Join(items, ","))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestWriteBatch(t *testing.T) {
+	var doc map[string]interface{}
+	var meta map[string]action
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dec := json.NewDecoder(r.Body)
+		dec.Decode(&meta)
+		dec.Decode(&doc)
+		fmt.Fprint(w, `{"errors":false,"items":[{"index":{"status":201}}]}`)
+	}))
+	defer srv.Close()
+
+	s := New(srv.URL, Options{Index: ByModule("logs"), StringData: true})
+	if err := s.Write(messages()[1]); err != nil {
+		t.Fatal(err)
+	}
+	if meta["index"] != (action{Index: "logs-auth", ID: "id-b"}) {
+		t.Fatalf("unexpected action %v", meta)
+	}
+	want := map[string]interface{}{"@timestamp": "2022-11-17T12:00:00Z", "module": "auth", "level": "warn", "name": "b", "code": 1001.0,
+		"message": "Message b", "id": "id-b", "data": map[string]interface{}{"n": "1"}}
+	if fmt.Sprint(doc) != fmt.Sprint(want) {
+		t.Fatalf("unexpected document %v", doc)
+	}
+	if Daily("logs")(messages()[0]) != "logs-2022.11.17" {
+		t.Fatal("unexpected daily index")
+	}
+}
+
+func TestBackpressure(t *testing.T) {
+	srv, requests := bulkServer(t, func(attempt int, id string) int {
+		switch {
+		case id == "id-b" && attempt < 2:
+			return http.StatusTooManyRequests
+		case id == "id-c":
+			return http.StatusBadRequest
+		}
+		return http.StatusCreated
+	})
+	s := New(srv.URL, Options{Backoff: time.Millisecond})
+	err := s.WriteBatch(messages())
+	if err == nil || !module.IsPermanent(err) || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Fatalf("expected permanent error for id-c, got %v", err)
+	}
+	if fmt.Sprint(*requests) != "[[id-a id-b id-c] [id-b] [id-b]]" {
+		t.Fatalf("unexpected requests %v", *requests)
+	}
+}
+
+func TestRejected(t *testing.T) {
+	srv, requests := bulkServer(t, func(attempt int, id string) int {
+		return http.StatusTooManyRequests
+	})
+	s := New(srv.URL, Options{Retries: 1, Backoff: time.Millisecond})
+	err := s.WriteBatch(messages())
+	if err == nil || module.IsPermanent(err) {
+		t.Fatalf("expected temporary error, got %v", err)
+	}
+	if len(*requests) != 2 {
+		t.Fatalf("expected 2 requests, got %v", *requests)
+	}
+}
@@ -0,0 +1,48 @@
+package module
+
+import "context"
+
+// Enabled reports whether messages of level pass the Mask and are written to the Logger.
+// Use it to skip expensive preparation of arguments:
+//
+//	if m.Enabled(module.Debug) {
+//		m.Debug("cache_state", "entries", dump(cache))
+//	}
+//
+// Hooks and sinks receive masked messages too, so only skip work that is needed for the log output.
+// Tenant masks and CtxVerbose are not considered, as there is no context.
+func (m *Module) Enabled(level Level) bool {
+	return m.audit || level&m.mask(context.Background(), "") != 0
+}
+
+// NameEnabled is like Enabled for the message name, considering the prefix masks of SetPrefixMask.
+func (m *Module) NameEnabled(level Level, name string) bool {
+	return m.audit || level&m.mask(context.Background(), name) != 0
+}
+
+// IfDebug calls f with the Module if Debug messages are enabled, to guard blocks of diagnostic work:
+//
+//	m.IfDebug(func(l module.DebugLogger) {
+//		for _, c := range conns {
+//			l.Debug("conn_state", "addr", c.Addr(), "state", c.State())
+//		}
+//	})
+func (m *Module) IfDebug(f func(l DebugLogger)) { m.ifEnabled(Debug, f) }
+
+// IfTrace calls f with the Module if Trace messages are enabled, see IfDebug.
+func (m *Module) IfTrace(f func(l DebugLogger)) { m.ifEnabled(Trace, f) }
+
+// IfInfo calls f with the Module if Info messages are enabled, see IfDebug.
+func (m *Module) IfInfo(f func(l DebugLogger)) { m.ifEnabled(Info, f) }
+
+// IfWarn calls f with the Module if Warn messages are enabled, see IfDebug.
+func (m *Module) IfWarn(f func(l DebugLogger)) { m.ifEnabled(Warn, f) }
+
+// IfErr calls f with the Module if Error messages are enabled, see IfDebug.
+func (m *Module) IfErr(f func(l DebugLogger)) { m.ifEnabled(Error, f) }
+
+func (m *Module) ifEnabled(level Level, f func(l DebugLogger)) {
+	if m.Enabled(level) {
+		f(m)
+	}
+}
@@ -0,0 +1,33 @@
+package module
+
+import "testing"
+
+func TestEnabled(t *testing.T) {
+	_, _, m := New("module", messages)
+	if m.Enabled(Debug) || !m.Enabled(Info) || !m.Enabled(Error) {
+		t.Fatal("default mask")
+	}
+	m.Mask = Error
+	if m.Enabled(Warn) || !m.Enabled(Error) {
+		t.Fatal("error mask")
+	}
+	m.SetPrefixMask("db", AllLevels|Debug)
+	if !m.NameEnabled(Debug, "db.query") || m.NameEnabled(Debug, "http.request") {
+		t.Fatal("prefix mask")
+	}
+
+	var called []Level
+	for _, level := range []Level{Trace, Debug, Info, Warn, Error} {
+		level := level
+		guard := map[Level]func(func(DebugLogger)){Trace: m.IfTrace, Debug: m.IfDebug, Info: m.IfInfo, Warn: m.IfWarn, Error: m.IfErr}[level]
+		guard(func(l DebugLogger) {
+			if l != DebugLogger(m) {
+				t.Fatal("guard passed another logger")
+			}
+			called = append(called, level)
+		})
+	}
+	if len(called) != 1 || called[0] != Error {
+		t.Fatalf("guards called for %v", called)
+	}
+}
@@ -0,0 +1,281 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+)
+
+const (
+	KeyHost       = "host"
+	KeyPID        = "pid"
+	KeyExecutable = "executable"
+	KeyVersion    = "version"
+	KeyGoroutine  = "goroutine"
+	KeyDeadline   = "deadline_ms"
+
+	KeyK8sNamespace = "k8s.namespace"
+	KeyK8sPod       = "k8s.pod"
+	KeyK8sNode      = "k8s.node"
+
+	KeyContainerID      = "container.id"
+	KeyContainerRuntime = "container.runtime"
+
+	KeyBuildPath     = "build.path"
+	KeyBuildVersion  = "build.version"
+	KeyBuildRevision = "build.revision"
+	// KeyLabelPrefix is prepended to the keys of pprof labels by Goroutine.
+	KeyLabelPrefix = "pprof."
+)
+
+// Goroutine adds the id of the logging goroutine as "goroutine" and the pprof labels of ctx,
+// like "pprof.worker", to every message. It is meant for debugging interleaved concurrent logs
+// during development: goroutine ids are not stable and reading them is slow.
+func Goroutine() Processor {
+	return ProcessorFunc(func(ctx context.Context, msg *Message) *Message {
+		msg.Set(KeyGoroutine, goroutineID())
+		pprof.ForLabels(ctx, func(key, value string) bool {
+			msg.Set(KeyLabelPrefix+key, value)
+			return true
+		})
+		return msg
+	})
+}
+
+// Deadline adds the time left until the deadline of ctx in milliseconds as "deadline_ms",
+// negative once it passed, to messages logged with a context that has a deadline.
+// It shows how timeouts cascade across calls and services.
+func Deadline() Processor {
+	return ProcessorFunc(func(ctx context.Context, msg *Message) *Message {
+		if deadline, ok := ctx.Deadline(); ok {
+			msg.Set(KeyDeadline, deadline.Sub(msg.Time).Milliseconds())
+		}
+		return msg
+	})
+}
+
+// goroutineID parses the id from the "goroutine 42 [running]:" header of the stack trace.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i != -1 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// ProcessInfo adds the hostname, process id, executable name and the service version to every message,
+// as "host", "pid", "executable" and "version". The values are computed once; keys that a message
+// has already and values that are unknown or empty are left out.
+func ProcessInfo(version string) Processor {
+	var fields []interface{}
+	if host, err := os.Hostname(); err == nil {
+		fields = append(fields, KeyHost, host)
+	}
+	fields = append(fields, KeyPID, os.Getpid())
+	if exe, err := os.Executable(); err == nil {
+		fields = append(fields, KeyExecutable, filepath.Base(exe))
+	}
+	if version != "" {
+		fields = append(fields, KeyVersion, version)
+	}
+	return enrichAll(fields)
+}
+
+// BuildInfo adds the main module path, its version and the VCS revision of the binary
+// from debug.ReadBuildInfo to every Error message, as "build.path", "build.version" and "build.revision".
+// Values missing from the build info are left out.
+func BuildInfo() Processor {
+	info, _ := debug.ReadBuildInfo()
+	return buildInfo(info)
+}
+
+func buildInfo(info *debug.BuildInfo) Processor {
+	p := enrichAll(buildInfoFields(info))
+	return ProcessorFunc(func(ctx context.Context, msg *Message) *Message {
+		if msg.Level != Error {
+			return msg
+		}
+		return p.Process(ctx, msg)
+	})
+}
+
+func buildInfoFields(info *debug.BuildInfo) (fields []interface{}) {
+	if info == nil {
+		return nil
+	}
+	if info.Main.Path != "" {
+		fields = append(fields, KeyBuildPath, info.Main.Path)
+	}
+	if info.Main.Version != "" {
+		fields = append(fields, KeyBuildVersion, info.Main.Version)
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && s.Value != "" {
+			fields = append(fields, KeyBuildRevision, s.Value)
+		}
+	}
+	return fields
+}
+
+// EnvFields adds the values of the environment variables names to every message, with the names as keys.
+// The variables are read once; unset variables are left out.
+func EnvFields(names ...string) Processor {
+	var fields []interface{}
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			fields = append(fields, name, value)
+		}
+	}
+	return enrichAll(fields)
+}
+
+// Kubernetes adds the namespace, pod and node name to every message, as "k8s.namespace", "k8s.pod" and "k8s.node".
+// They are read once from the environment variables commonly set with the downward API
+// (POD_NAMESPACE, POD_NAME, NODE_NAME or with a K8S_ prefix), falling back to the files of a
+// downward API volume at /etc/podinfo, the namespace of the service account and, inside a cluster, HOSTNAME.
+func Kubernetes() Processor {
+	return enrichAll(kubernetesFields(os.Getenv, os.DirFS("/")))
+}
+
+func kubernetesFields(getenv func(string) string, root fs.FS) (fields []interface{}) {
+	first := func(key string, env []string, files []string) {
+		for _, name := range env {
+			if v := getenv(name); v != "" {
+				fields = append(fields, key, v)
+				return
+			}
+		}
+		for _, file := range files {
+			if b, err := fs.ReadFile(root, file); err == nil && len(bytes.TrimSpace(b)) > 0 {
+				fields = append(fields, key, string(bytes.TrimSpace(b)))
+				return
+			}
+		}
+	}
+	first(KeyK8sNamespace, []string{"POD_NAMESPACE", "K8S_NAMESPACE"}, []string{"etc/podinfo/namespace", "var/run/secrets/kubernetes.io/serviceaccount/namespace"})
+	podEnv := []string{"POD_NAME", "K8S_POD_NAME"}
+	if getenv("KUBERNETES_SERVICE_HOST") != "" {
+		podEnv = append(podEnv, "HOSTNAME")
+	}
+	first(KeyK8sPod, podEnv, []string{"etc/podinfo/name"})
+	first(KeyK8sNode, []string{"NODE_NAME", "K8S_NODE_NAME"}, nil)
+	return fields
+}
+
+// Container adds the id and runtime of the container the process runs in to every message,
+// as "container.id" and "container.runtime" ("docker", "containerd", "crio" or "podman", if known).
+// They are detected once from /proc/self/cgroup (cgroup v1 and v2) and, for cgroup namespaces
+// that hide the path, from the runtime files mounted at /etc/hostname, /etc/hosts and /etc/resolv.conf
+// in /proc/self/mountinfo. Outside of containers nothing is added.
+func Container() Processor {
+	return enrichAll(containerFields(os.DirFS("/")))
+}
+
+// containerRuntimes maps path markers to runtimes, e.g. ".../docker-<id>.scope" or "/docker/<id>".
+var containerRuntimes = []struct{ marker, runtime string }{
+	{"cri-containerd", "containerd"},
+	{"containerd", "containerd"},
+	{"crio", "crio"},
+	{"libpod", "podman"},
+	{"docker", "docker"},
+}
+
+// containerMounts are the directories in which runtimes keep the files they mount at containerFiles,
+// like "/var/lib/docker/containers/<id>/hostname". The runtime is empty if several runtimes use the directory.
+var containerMounts = []struct{ dir, runtime string }{
+	{"/docker/containers/", "docker"},
+	{"/containers/storage/overlay-containers/", ""},
+}
+
+var containerFiles = map[string]bool{"/etc/hostname": true, "/etc/hosts": true, "/etc/resolv.conf": true}
+
+func containerFields(root fs.FS) (fields []interface{}) {
+	if b, err := fs.ReadFile(root, "proc/self/cgroup"); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			id, i := containerID(line)
+			if id == "" {
+				continue
+			}
+			fields = append(fields, KeyContainerID, id)
+			for _, r := range containerRuntimes {
+				if strings.Contains(line[:i], r.marker) {
+					return append(fields, KeyContainerRuntime, r.runtime)
+				}
+			}
+			return fields
+		}
+	}
+	b, err := fs.ReadFile(root, "proc/self/mountinfo")
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		// the fourth field is the mounted path, the fifth the mount point
+		f := strings.Fields(line)
+		if len(f) < 5 || !containerFiles[f[4]] {
+			continue
+		}
+		for _, mount := range containerMounts {
+			i := strings.Index(f[3], mount.dir)
+			if i == -1 {
+				continue
+			}
+			id, _, _ := strings.Cut(f[3][i+len(mount.dir):], "/")
+			if len(id) != 64 {
+				continue
+			}
+			if id, _ := containerID(id); id == "" {
+				continue
+			}
+			fields = append(fields, KeyContainerID, id)
+			if mount.runtime != "" {
+				fields = append(fields, KeyContainerRuntime, mount.runtime)
+			}
+			return fields
+		}
+	}
+	return nil
+}
+
+// containerID returns the first run of 64 hex digits in line and its position.
+func containerID(line string) (string, int) {
+	n := 0
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') {
+			n++
+			continue
+		}
+		if n == 64 {
+			return line[i-64 : i], i - 64
+		}
+		n = 0
+	}
+	if n == 64 {
+		return line[len(line)-64:], len(line) - 64
+	}
+	return "", 0
+}
+
+// enrichAll sets the key value pairs of fields on every message that does not have the key already.
+func enrichAll(fields []interface{}) Processor {
+	return ProcessorFunc(func(ctx context.Context, msg *Message) *Message {
+		for i := 0; i+1 < len(fields); i += 2 {
+			key := fields[i].(string)
+			if _, ok := msg.Data[key]; !ok {
+				msg.Set(key, fields[i+1])
+			}
+		}
+		return msg
+	})
+}
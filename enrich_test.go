@@ -0,0 +1,163 @@
+package module
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"runtime/debug"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestGoroutine(t *testing.T) {
+	var msg *Message
+	m := NewWithOptions("module", messages, WithProcessors(Goroutine()), WithSinks(SinkFunc(func(m *Message) error {
+		msg = m
+		return nil
+	})))
+
+	pprof.Do(context.Background(), pprof.Labels("worker", "7"), func(ctx context.Context) {
+		m.Info("test", ctx, "A", 1)
+	})
+	if id, _ := msg.Data[KeyGoroutine].(uint64); id == 0 || msg.Data["pprof.worker"] != "7" {
+		t.Fatalf("unexpected data %v", msg.Data)
+	}
+
+	done := make(chan uint64)
+	go func() { done <- goroutineID() }()
+	if other := <-done; other == msg.Data[KeyGoroutine] {
+		t.Fatalf("goroutines share id %d", other)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	var msg *Message
+	now := time.Date(2022, 11, 17, 11, 49, 4, 0, time.UTC)
+	m := NewWithOptions("module", messages, WithClock(NewManualClock(now)), WithProcessors(Deadline()), WithSinks(SinkFunc(func(m *Message) error {
+		msg = m
+		return nil
+	})))
+
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(1500*time.Millisecond))
+	defer cancel()
+	m.Info("test", ctx, "A", 1)
+	if msg.Data[KeyDeadline] != int64(1500) {
+		t.Fatalf("unexpected data %v", msg.Data)
+	}
+	m.Info("test", "A", 1)
+	if _, ok := msg.Data[KeyDeadline]; ok {
+		t.Fatalf("deadline without deadline: %v", msg.Data)
+	}
+}
+
+func TestProcessInfo(t *testing.T) {
+	var msg *Message
+	m := NewWithOptions("module", messages, WithProcessors(ProcessInfo("1.2.3")), WithSinks(SinkFunc(func(m *Message) error {
+		msg = m
+		return nil
+	})))
+	m.Info("test", "A", 1, "version", "override")
+
+	host, _ := os.Hostname()
+	if msg.Data[KeyHost] != host || msg.Data[KeyPID] != os.Getpid() || msg.Data[KeyExecutable] == "" {
+		t.Fatalf("unexpected data %v", msg.Data)
+	}
+	if msg.Data[KeyVersion] != "override" {
+		t.Fatalf("version of the message was overwritten: %v", msg.Data)
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	info := &debug.BuildInfo{
+		Main:     debug.Module{Path: "example.com/app", Version: "v1.4.0"},
+		Settings: []debug.BuildSetting{{Key: "vcs", Value: "git"}, {Key: "vcs.revision", Value: "abc123"}},
+	}
+	var msgs []*Message
+	m := NewWithOptions("module", messages, WithProcessors(buildInfo(info)), WithSinks(SinkFunc(func(m *Message) error {
+		msgs = append(msgs, m)
+		return nil
+	})))
+	m.Err("test", "A", 1)
+	if msgs[0].Data[KeyBuildPath] != "example.com/app" || msgs[0].Data[KeyBuildVersion] != "v1.4.0" || msgs[0].Data[KeyBuildRevision] != "abc123" {
+		t.Fatalf("unexpected data %v", msgs[0].Data)
+	}
+
+	m.Info("test", "A", 1)
+	if _, ok := msgs[1].Data[KeyBuildVersion]; ok {
+		t.Fatalf("build info added to info message: %v", msgs[1].Data)
+	}
+}
+
+func TestEnvFields(t *testing.T) {
+	t.Setenv("REGION", "eu-west-1")
+	t.Setenv("POD_NAME", "")
+	var msg *Message
+	m := NewWithOptions("module", messages, WithEnvFields("REGION", "POD_NAME", "MODULE_UNSET_VARIABLE"), WithSinks(SinkFunc(func(m *Message) error {
+		msg = m
+		return nil
+	})))
+	os.Setenv("REGION", "changed")
+	m.Info("test", "A", 1)
+
+	if len(msg.Data) != 3 || msg.Data["REGION"] != "eu-west-1" || msg.Data["POD_NAME"] != "" {
+		t.Fatalf("unexpected data %v", msg.Data)
+	}
+}
+
+func TestKubernetes(t *testing.T) {
+	env := map[string]string{"NODE_NAME": "node-1", "KUBERNETES_SERVICE_HOST": "10.0.0.1", "HOSTNAME": "api-7f9c"}
+	root := fstest.MapFS{
+		"var/run/secrets/kubernetes.io/serviceaccount/namespace": {Data: []byte("prod\n")},
+	}
+	fields := kubernetesFields(func(key string) string { return env[key] }, root)
+	expected := []interface{}{KeyK8sNamespace, "prod", KeyK8sPod, "api-7f9c", KeyK8sNode, "node-1"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+
+	env = map[string]string{"POD_NAMESPACE": "staging", "HOSTNAME": "laptop"}
+	root["etc/podinfo/name"] = &fstest.MapFile{Data: []byte("web-0")}
+	fields = kubernetesFields(func(key string) string { return env[key] }, root)
+	expected = []interface{}{KeyK8sNamespace, "staging", KeyK8sPod, "web-0"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+
+	if fields := kubernetesFields(func(string) string { return "" }, fstest.MapFS{}); fields != nil {
+		t.Fatalf("unexpected fields outside of kubernetes %v", fields)
+	}
+}
+
+func TestContainer(t *testing.T) {
+	id := strings.Repeat("0123456789abcdef", 4)
+	for cgroup, expected := range map[string][]interface{}{
+		"12:cpu,cpuacct:/docker/" + id + "\n1:name=systemd:/docker/" + id + "\n":   {KeyContainerID, id, KeyContainerRuntime, "docker"},
+		"0::/kubepods.slice/kubepods-pod1.slice/cri-containerd-" + id + ".scope\n": {KeyContainerID, id, KeyContainerRuntime, "containerd"},
+		"0::/machine.slice/libpod-" + id + ".scope/container\n":                    {KeyContainerID, id, KeyContainerRuntime, "podman"},
+		"0::/user.slice/user-1000.slice/session-2.scope\n":                         nil,
+	} {
+		fields := containerFields(fstest.MapFS{"proc/self/cgroup": {Data: []byte(cgroup)}})
+		if !reflect.DeepEqual(fields, expected) {
+			t.Fatalf("containerFields(%q) = %v, expected %v", cgroup, fields, expected)
+		}
+	}
+
+	root := fstest.MapFS{
+		"proc/self/cgroup":    {Data: []byte("0::/\n")},
+		"proc/self/mountinfo": {Data: []byte("1 0 8:1 / / rw - ext4 /dev/sda1 rw\n2 1 8:1 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw - ext4 /dev/sda1 rw\n")},
+	}
+	if fields := containerFields(root); !reflect.DeepEqual(fields, []interface{}{KeyContainerID, id, KeyContainerRuntime, "docker"}) {
+		t.Fatalf("unexpected fields from mountinfo %v", fields)
+	}
+	// a host running containers
+	root["proc/self/mountinfo"] = &fstest.MapFile{Data: []byte("1 0 8:1 / / rw - ext4 /dev/sda1 rw\n" +
+		"2 1 0:50 / /var/lib/docker/overlay2/" + id + "/merged rw - overlay overlay rw,lowerdir=/var/lib/docker/overlay2/l/A\n" +
+		"3 1 0:51 / /var/lib/docker/containers/" + id + "/mounts/shm rw - tmpfs shm rw\n" +
+		"4 1 8:1 /srv/" + id + "/hosts /srv/hosts rw - ext4 /dev/sda1 rw\n")}
+	if fields := containerFields(root); fields != nil {
+		t.Fatalf("container detected on host: %v", fields)
+	}
+}
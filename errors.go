@@ -0,0 +1,103 @@
+package module
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/halliday/go-errors"
+)
+
+// EncodedError is a JSON-safe representation of an error chain, suitable for
+// sending across service boundaries. Use DecodeError to turn it back into an error.
+type EncodedError struct {
+	Name     string        `json:"name,omitempty"`
+	Code     int           `json:"code,omitempty"`
+	Desc     string        `json:"desc,omitempty"`
+	Link     string        `json:"link,omitempty"`
+	Data     interface{}   `json:"data,omitempty"`
+	CausedBy *EncodedError `json:"caused_by,omitempty"`
+	// Joined are the errors joined in a multi-error, like errors.Join.
+	Joined []*EncodedError `json:"joined,omitempty"`
+}
+
+// EncodeError converts err and its whole Unwrap chain, including joined errors, into an EncodedError.
+// Data values that can not be marshaled to JSON are replaced by their fmt.Sprint form.
+func EncodeError(err error) *EncodedError {
+	if err == nil {
+		return nil
+	}
+	e := &EncodedError{}
+	if r, ok := err.(errors.NameError); ok {
+		e.Name = r.ErrorName()
+	}
+	if r, ok := err.(errors.CodeError); ok {
+		e.Code = r.ErrorCode()
+	}
+	if r, ok := err.(errors.DescError); ok {
+		e.Desc = r.ErrorDescription()
+	} else {
+		e.Desc = err.Error()
+	}
+	if r, ok := err.(errors.LinkError); ok {
+		e.Link = r.ErrorLink()
+	}
+	if r, ok := err.(errors.DataError); ok {
+		e.Data = jsonSafe(r.ErrorData())
+	}
+	e.CausedBy = EncodeError(errors.Unwrap(err))
+	for _, joined := range unwrapMulti(err) {
+		e.Joined = append(e.Joined, EncodeError(joined))
+	}
+	return e
+}
+
+// NamedError returns the first error in the chain of err with a name, like the errors of NewError, or nil.
+func NamedError(err error) error {
+	var named error
+	walkErrors(err, func(err error) bool {
+		if n, ok := err.(errors.NameError); ok && n.ErrorName() != "" {
+			named = err
+		}
+		return named == nil
+	})
+	return named
+}
+
+// DecodeError reconstructs the error chain encoded by EncodeError.
+func DecodeError(e *EncodedError) error {
+	if e == nil {
+		return nil
+	}
+	var joined errors.Multi
+	for _, j := range e.Joined {
+		joined.Append(DecodeError(j))
+	}
+	if joined != nil && e.Name == "" && e.Code == 0 && e.CausedBy == nil {
+		// a plain joined error, like of errors.Join
+		return joined
+	}
+	var causedBy error
+	if e.CausedBy != nil {
+		causedBy = DecodeError(e.CausedBy)
+	} else if joined != nil {
+		causedBy = joined
+	}
+	return errors.NewRich(e.Name, e.Code, e.Desc, e.Link, e.Data, causedBy)
+}
+
+func jsonSafe(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		safe := make(map[string]interface{}, len(m))
+		for key, value := range m {
+			safe[key] = jsonSafe(value)
+		}
+		return safe
+	}
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return v
+}
@@ -0,0 +1,39 @@
+package module
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/halliday/go-errors"
+)
+
+func TestEncodeError(t *testing.T) {
+	_, e, _ := New("module", messages)
+
+	err := e("test", e("test2"), "ch", make(chan int), "A", 1)
+
+	data, jsonErr := json.Marshal(EncodeError(err))
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+
+	var encoded EncodedError
+	if jsonErr := json.Unmarshal(data, &encoded); jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	decoded := DecodeError(&encoded)
+	if decoded.Error() != err.Error() {
+		t.Fatalf("unexpected error: %s", decoded)
+	}
+	r := decoded.(*errors.RichError)
+	if r.Name != "test" || r.Code != 123 || r.CausedBy.(*errors.RichError).Name != "test2" {
+		t.Fatal("unexpected chain")
+	}
+	if data := r.Data.(map[string]interface{}); data["A"] != 1.0 || data["ch"] == nil {
+		t.Fatalf("unexpected data: %v", data)
+	}
+
+	if enc := EncodeError(e("test4")); enc.Link != "https://example.com/test4" {
+		t.Fatalf("unexpected link %q", enc.Link)
+	}
+}
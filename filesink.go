@@ -0,0 +1,297 @@
+package module
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/halliday/go-errors"
+	"github.com/klauspost/compress/zstd"
+)
+
+type Compression int
+
+const (
+	NoCompression Compression = iota
+	Gzip
+	Zstd
+)
+
+func (c Compression) ext() string {
+	switch c {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// Encoding returns the HTTP Content-Encoding of c.
+func (c Compression) Encoding() string {
+	switch c {
+	case Gzip:
+		return "gzip"
+	case Zstd:
+		return "zstd"
+	}
+	return ""
+}
+
+type FileSinkOptions struct {
+	// MaxSize rotates the file after MaxSize bytes were written, 0 means never.
+	MaxSize int64
+	// MaxAge rotates the file after it was open for MaxAge, 0 means never.
+	MaxAge time.Duration
+	// Compress compresses rotated files, in the background.
+	Compress Compression
+	// Level is the compression level (1-9 for gzip, 1-4 for zstd), 0 selects the default.
+	Level int
+	// Clock is used for MaxAge and the names of rotated files. Default SystemClock.
+	Clock Clock
+	// CompressLive compresses the live file with Compress as well. Every record is flushed,
+	// so the file can be tailed by a decompressor. Rotated files are not compressed again.
+	CompressLive bool
+}
+
+// FileSink appends messages as JSON lines to a file.
+// Rotated files are renamed to path.<UTC time>[.<n>][.gz|.zst], with a counter n from 2
+// for files rotated at the same time.
+type FileSink struct {
+	mu     sync.Mutex
+	path   string
+	opts   FileSinkOptions
+	f      *os.File
+	w      io.Writer
+	size   int64
+	opened time.Time
+
+	wg  sync.WaitGroup
+	err error
+}
+
+// OpenFileSink opens (or creates) the file for appending.
+func OpenFileSink(path string) (*FileSink, error) {
+	return OpenFileSinkWithOptions(path, FileSinkOptions{})
+}
+
+func OpenFileSinkWithOptions(path string, opts FileSinkOptions) (*FileSink, error) {
+	s := &FileSink{path: path, opts: opts}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f = f
+	s.w = f
+	s.size = info.Size()
+	s.opened = clockOrSystem(s.opts.Clock).Now()
+	if s.opts.CompressLive && s.opts.Compress != NoCompression {
+		s.w, err = compressor(f, s.opts.Compress, s.opts.Level)
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+func compressor(w io.Writer, c Compression, level int) (io.WriteCloser, error) {
+	switch c {
+	case Gzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	case Zstd:
+		if level == 0 {
+			return zstd.NewWriter(w)
+		}
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevel(level)))
+	}
+	return nil, fmt.Errorf("unknown compression %d", c)
+}
+
+// Compress returns data compressed with c at level, see FileSinkOptions.Level, or data itself for NoCompression.
+func Compress(data []byte, c Compression, level int) ([]byte, error) {
+	if c == NoCompression {
+		return data, nil
+	}
+	var b bytes.Buffer
+	w, err := compressor(&b, c, level)
+	if err != nil {
+		return nil, err
+	}
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (s *FileSink) Write(msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.write(append(data, '\n'))
+}
+
+func (s *FileSink) write(record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	if (s.opts.MaxSize > 0 && s.size > 0 && s.size+int64(len(record)) > s.opts.MaxSize) ||
+		(s.opts.MaxAge > 0 && clockOrSystem(s.opts.Clock).Now().Sub(s.opened) >= s.opts.MaxAge) {
+		// on failure the original file stays open, the error is returned by Close
+		if err := s.rotate(); err != nil {
+			s.err = errors.Join(s.err, err)
+			if s.f == nil {
+				return err
+			}
+		}
+	}
+	n, err := s.w.Write(record)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if f, ok := s.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Rotate closes the current file, renames it and opens a new one.
+// If that fails, writing continues to the current file.
+func (s *FileSink) Rotate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	return s.rotate()
+}
+
+func (s *FileSink) rotate() error {
+	err := s.close()
+	var rotated string
+	if err == nil {
+		rotated = s.rotatedName()
+		err = os.Rename(s.path, rotated)
+	}
+	if err != nil {
+		return errors.Join(err, s.open())
+	}
+	if !s.opts.CompressLive && s.opts.Compress != NoCompression {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := compressFile(rotated, s.opts.Compress, s.opts.Level); err != nil {
+				s.mu.Lock()
+				s.err = errors.Join(s.err, err)
+				s.mu.Unlock()
+			}
+		}()
+	}
+	return s.open()
+}
+
+// rotatedName returns path.<UTC time>[.gz|.zst] for the current file, with a counter
+// after the time if a file of that name exists already.
+func (s *FileSink) rotatedName() string {
+	base := s.path + "." + clockOrSystem(s.opts.Clock).Now().UTC().Format("20060102T150405.000000000")
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name += "." + strconv.Itoa(i)
+		}
+		if !exists(name) && !exists(name+s.opts.Compress.ext()) {
+			if s.opts.CompressLive {
+				name += s.opts.Compress.ext()
+			}
+			return name
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func compressFile(path string, c Compression, level int) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+c.ext(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	w, err := compressor(dst, c, level)
+	if err == nil {
+		_, err = io.Copy(w, src)
+		err = errors.Join(err, w.Close())
+	}
+	if err = errors.Join(err, dst.Close()); err != nil {
+		os.Remove(dst.Name())
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (s *FileSink) close() error {
+	var err error
+	if c, ok := s.w.(io.Closer); ok && s.w != io.Writer(s.f) {
+		err = c.Close()
+	}
+	err = errors.Join(err, s.f.Close())
+	s.f = nil
+	s.w = nil
+	return err
+}
+
+func (s *FileSink) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	return s.f.Sync()
+}
+
+// Close closes the file and waits for the compression of rotated files.
+// It returns the first error of the background compression, if any.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	var err error
+	if s.f != nil {
+		err = s.close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(err, s.err)
+}
@@ -0,0 +1,123 @@
+package module
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestFileSinkRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.json")
+	sink, err := OpenFileSinkWithOptions(path, FileSinkOptions{MaxSize: 100, Compress: Gzip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _, m := New("module", messages)
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+	m.Sinks = []Sink{sink}
+	l.Info("test")
+	l.Info("test2")
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, _ := filepath.Glob(path + ".*.gz")
+	if len(rotated) != 1 {
+		t.Fatalf("expected one rotated file, found %v", rotated)
+	}
+	f, err := os.Open(rotated[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil || !strings.Contains(string(data), `"name":"test"`) {
+		t.Fatalf("unexpected rotated file: %s %v", data, err)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), `"name":"test2"`) {
+		t.Fatalf("unexpected live file: %s", data)
+	}
+}
+
+func TestFileSinkRotateFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.json")
+	sink, err := OpenFileSinkWithOptions(path, FileSinkOptions{Clock: NewManualClock(time.Unix(0, 0))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &Message{Module: "module"}
+
+	// rotations at the same time
+	for i := 0; i < 3; i++ {
+		if err := sink.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rotated, _ := filepath.Glob(path + ".*"); len(rotated) != 3 {
+		t.Fatalf("expected three rotated files, found %v", rotated)
+	}
+
+	// the file was removed, so it can not be renamed
+	os.Remove(path)
+	if err := sink.Rotate(); err == nil {
+		t.Fatal("rotation of a removed file succeeded")
+	}
+	if err := sink.Write(msg); err != nil {
+		t.Fatalf("write after failed rotation: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), `"module":"module"`) {
+		t.Fatalf("unexpected file: %s", data)
+	}
+}
+
+func TestFileSinkCompressLive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.json.zst")
+	sink, err := OpenFileSinkWithOptions(path, FileSinkOptions{Compress: Zstd, CompressLive: true, Level: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _, m := New("module", messages)
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+	m.Sinks = []Sink{sink}
+	l.Info("test")
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := zstd.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	line := make([]byte, 1024)
+	n, _ := r.Read(line)
+	if !strings.Contains(string(line[:n]), `"name":"test"`) {
+		t.Fatalf("record was not flushed: %q", line[:n])
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
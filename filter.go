@@ -0,0 +1,497 @@
+package module
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A Filter is a compiled filter expression over messages, like
+//
+//	level >= warn && module == "auth" && data.tenant != "internal"
+//
+// Fields are level, module, name, code, desc, link, id, caller, seq and data.<key>.
+// They compare with ==, !=, <, <=, > and >= to strings ("..."), numbers and, for level,
+// bare level names; other bare words are unknown fields. =~ matches a regular expression string. Conditions combine with &&, || and !,
+// and group with parentheses. A field alone is true if it is set and not zero.
+// Missing data keys are nil: they are != to every value and compare false otherwise.
+type Filter struct {
+	src   string
+	match func(msg *Message) bool
+}
+
+// ParseFilter compiles the filter expression src.
+func ParseFilter(src string) (*Filter, error) {
+	p := &filterParser{src: src}
+	p.next()
+	match, err := p.or()
+	if err == nil && p.tok.kind != tokEOF {
+		err = p.errorf("unexpected %q", p.tok.text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{src, match}, nil
+}
+
+// MustParseFilter is like ParseFilter but panics if src is not valid.
+func MustParseFilter(src string) *Filter {
+	f, err := ParseFilter(src)
+	if err != nil {
+		panic(err.Error())
+	}
+	return f
+}
+
+func (f *Filter) Match(msg *Message) bool {
+	return f.match(msg)
+}
+
+func (f *Filter) String() string {
+	return f.src
+}
+
+// FilterSink returns a Sink that writes only the messages that match to sink.
+func FilterSink(sink Sink, match func(msg *Message) bool) Sink {
+	return SinkFunc(func(msg *Message) error {
+		if !match(msg) {
+			return nil
+		}
+		return sink.Write(msg)
+	})
+}
+
+// FilterHook returns a Hook that runs hook only for the messages that match.
+func FilterHook(hook Hook, match func(msg *Message) bool) Hook {
+	return func(msg *Message) *Message {
+		if !match(msg) {
+			return msg
+		}
+		return hook(msg)
+	}
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+type filterParser struct {
+	src string
+	pos int
+	tok token
+}
+
+func (p *filterParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("filter %q: position %d: %s", p.src, p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+var filterOps = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!", "(", ")"}
+
+// next reads the next token into p.tok.
+func (p *filterParser) next() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) != -1 {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos == len(p.src) {
+		p.tok = token{tokEOF, "", start}
+		return
+	}
+	c := p.src[p.pos]
+	switch {
+	case c == '"':
+		for p.pos++; p.pos < len(p.src) && p.src[p.pos] != '"'; p.pos++ {
+			if p.src[p.pos] == '\\' {
+				p.pos++
+			}
+		}
+		p.pos++
+		if p.pos > len(p.src) {
+			p.pos = len(p.src)
+		}
+		p.tok = token{tokString, p.src[start:p.pos], start}
+	case c == '-' || c >= '0' && c <= '9':
+		for p.pos++; p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) != -1; p.pos++ {
+		}
+		p.tok = token{tokNumber, p.src[start:p.pos], start}
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for p.pos++; p.pos < len(p.src) && isIdentByte(p.src[p.pos]); p.pos++ {
+		}
+		p.tok = token{tokIdent, p.src[start:p.pos], start}
+	default:
+		for _, op := range filterOps {
+			if strings.HasPrefix(p.src[p.pos:], op) {
+				p.pos += len(op)
+				p.tok = token{tokOp, op, start}
+				return
+			}
+		}
+		p.pos++
+		p.tok = token{tokOp, p.src[start:p.pos], start}
+	}
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *filterParser) or() (func(*Message) bool, error) {
+	left, err := p.and()
+	for err == nil && p.tok.text == "||" && p.tok.kind == tokOp {
+		p.next()
+		var right func(*Message) bool
+		if right, err = p.and(); err == nil {
+			l := left
+			left = func(msg *Message) bool { return l(msg) || right(msg) }
+		}
+	}
+	return left, err
+}
+
+func (p *filterParser) and() (func(*Message) bool, error) {
+	left, err := p.unary()
+	for err == nil && p.tok.text == "&&" && p.tok.kind == tokOp {
+		p.next()
+		var right func(*Message) bool
+		if right, err = p.unary(); err == nil {
+			l := left
+			left = func(msg *Message) bool { return l(msg) && right(msg) }
+		}
+	}
+	return left, err
+}
+
+func (p *filterParser) unary() (func(*Message) bool, error) {
+	if p.tok.kind == tokOp && p.tok.text == "!" {
+		p.next()
+		f, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(msg *Message) bool { return !f(msg) }, nil
+	}
+	if p.tok.kind == tokOp && p.tok.text == "(" {
+		p.next()
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.text != ")" {
+			return nil, p.errorf("missing )")
+		}
+		p.next()
+		return f, nil
+	}
+	return p.comparison()
+}
+
+// A filterValue evaluates an operand for a message.
+type filterValue struct {
+	get     func(msg *Message) interface{}
+	isLevel bool
+	literal *token
+	// word is set for an identifier that is not a field, which is only valid as a level name.
+	word bool
+}
+
+// unknown returns the error for a word that is not compared with the level.
+func (p *filterParser) unknown(v filterValue) error {
+	return fmt.Errorf("filter %q: position %d: unknown field %q", p.src, v.literal.pos+1, v.literal.text)
+}
+
+func (p *filterParser) comparison() (func(*Message) bool, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.tok
+	switch op.text {
+	case "==", "!=", "<", "<=", ">", ">=", "=~":
+		if op.kind != tokOp {
+			break
+		}
+		p.next()
+		rightTok := p.tok
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if op.text == "=~" {
+			if left.word {
+				return nil, p.unknown(left)
+			}
+			return p.regexp(left, right, rightTok)
+		}
+		if left.isLevel || right.isLevel {
+			if left, err = p.level(left); err == nil {
+				right, err = p.level(right)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, v := range []filterValue{left, right} {
+			if v.word {
+				return nil, p.unknown(v)
+			}
+		}
+		return compareValues(op.text, left.get, right.get), nil
+	}
+	if left.word {
+		return nil, p.unknown(left)
+	}
+	return func(msg *Message) bool { return truthy(left.get(msg)) }, nil
+}
+
+func (p *filterParser) regexp(left, right filterValue, tok token) (func(*Message) bool, error) {
+	if right.literal == nil || right.literal.kind != tokString {
+		return nil, fmt.Errorf("filter %q: position %d: =~ needs a string", p.src, tok.pos+1)
+	}
+	pattern, _ := right.get(nil).(string)
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("filter %q: position %d: %v", p.src, tok.pos+1, err)
+	}
+	return func(msg *Message) bool {
+		v := left.get(msg)
+		return v != nil && re.MatchString(fmt.Sprint(v))
+	}, nil
+}
+
+// level turns literals compared with the level field into levels.
+func (p *filterParser) level(v filterValue) (filterValue, error) {
+	if v.literal == nil {
+		return v, nil
+	}
+	s, _ := v.get(nil).(string)
+	l, err := ParseLevel(s)
+	if err != nil {
+		return v, fmt.Errorf("filter %q: position %d: %v", p.src, v.literal.pos+1, err)
+	}
+	return filterValue{get: func(*Message) interface{} { return l }, isLevel: true}, nil
+}
+
+func (p *filterParser) operand() (filterValue, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokString:
+		s, err := strconv.Unquote(tok.text)
+		if err != nil {
+			return filterValue{}, p.errorf("bad string %s", tok.text)
+		}
+		p.next()
+		return filterValue{get: func(*Message) interface{} { return s }, literal: &tok}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return filterValue{}, p.errorf("bad number %s", tok.text)
+		}
+		p.next()
+		return filterValue{get: func(*Message) interface{} { return f }, literal: &tok}, nil
+	case tokIdent:
+		p.next()
+		if get, ok := messageField(tok.text); ok {
+			return filterValue{get: get, isLevel: tok.text == "level"}, nil
+		}
+		// other identifiers are plain words, valid only as level names
+		word := tok.text
+		return filterValue{get: func(*Message) interface{} { return word }, literal: &tok, word: true}, nil
+	case tokEOF:
+		return filterValue{}, p.errorf("unexpected end")
+	}
+	return filterValue{}, p.errorf("unexpected %q", tok.text)
+}
+
+// messageField returns a getter for the field name of a message.
+func messageField(name string) (func(msg *Message) interface{}, bool) {
+	if strings.HasPrefix(name, "data.") {
+		key := name[len("data."):]
+		return func(msg *Message) interface{} {
+			v, ok := msg.Data[key]
+			if !ok {
+				return nil
+			}
+			return v
+		}, true
+	}
+	rich := func(f func(msg *Message) interface{}) func(msg *Message) interface{} {
+		return func(msg *Message) interface{} {
+			if msg.RichError == nil {
+				return ""
+			}
+			return f(msg)
+		}
+	}
+	switch name {
+	case "level":
+		return func(msg *Message) interface{} { return msg.Level }, true
+	case "module":
+		return func(msg *Message) interface{} { return msg.Module }, true
+	case "id":
+		return func(msg *Message) interface{} { return msg.ID }, true
+	case "caller":
+		return func(msg *Message) interface{} { return msg.Caller }, true
+	case "seq":
+		return func(msg *Message) interface{} { return msg.Seq }, true
+	case "name":
+		return rich(func(msg *Message) interface{} { return msg.Name }), true
+	case "desc":
+		return rich(func(msg *Message) interface{} { return msg.Desc }), true
+	case "link":
+		return rich(func(msg *Message) interface{} { return msg.Link }), true
+	case "code":
+		return func(msg *Message) interface{} {
+			if msg.RichError == nil {
+				return 0
+			}
+			return msg.Code
+		}, true
+	}
+	return nil, false
+}
+
+func compareValues(op string, left, right func(*Message) interface{}) func(*Message) bool {
+	return func(msg *Message) bool {
+		a, b := left(msg), right(msg)
+		if a == nil || b == nil {
+			return op == "!=" && (a != nil || b != nil)
+		}
+		var c int
+		if x, ok := toFloat(a); ok {
+			if y, ok := toFloat(b); ok {
+				switch {
+				case x < y:
+					c = -1
+				case x > y:
+					c = 1
+				}
+				return compareResult(op, c)
+			}
+		}
+		c = strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+		return compareResult(op, c)
+	}
+}
+
+func compareResult(op string, c int) bool {
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+// toFloat converts numbers and levels to float64.
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case isInt(rv.Kind()) && rv.Kind() >= reflect.Uint:
+		return float64(rv.Uint()), true
+	case isInt(rv.Kind()):
+		return float64(rv.Int()), true
+	case rv.Kind() == reflect.Float32 || rv.Kind() == reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func truthy(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	return true
+}
+
+// NameFilter matches messages by name. Patterns are globs like "db.*" in the syntax of path.Match,
+// where * also matches dots, or regular expressions between slashes like "/^http\.(4|5)\d\d$/".
+type NameFilter struct {
+	include []func(name string) bool
+	exclude []func(name string) bool
+}
+
+// NewNameFilter returns a filter matching messages with names that match one of include
+// (or all names if include is empty) and none of exclude.
+func NewNameFilter(include []string, exclude []string) (*NameFilter, error) {
+	f := &NameFilter{}
+	var err error
+	if f.include, err = namePatterns(include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = namePatterns(exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func namePatterns(patterns []string) (matchers []func(name string) bool, err error) {
+	for _, pattern := range patterns {
+		if len(pattern) >= 2 && pattern[0] == '/' && pattern[len(pattern)-1] == '/' {
+			re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+			if err != nil {
+				return nil, fmt.Errorf("name pattern %q: %v", pattern, err)
+			}
+			matchers = append(matchers, re.MatchString)
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("name pattern %q: %v", pattern, err)
+		}
+		pattern := pattern
+		matchers = append(matchers, func(name string) bool {
+			ok, _ := path.Match(pattern, name)
+			return ok
+		})
+	}
+	return matchers, nil
+}
+
+// Match reports whether the name of msg is included and not excluded. Messages without name
+// (of Print and Printf) have the name "".
+func (f *NameFilter) Match(msg *Message) bool {
+	var name string
+	if msg.RichError != nil {
+		name = msg.Name
+	}
+	return (len(f.include) == 0 || matchAny(f.include, name)) && !matchAny(f.exclude, name)
+}
+
+func matchAny(matchers []func(name string) bool, name string) bool {
+	for _, match := range matchers {
+		if match(name) {
+			return true
+		}
+	}
+	return false
+}
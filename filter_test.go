@@ -0,0 +1,109 @@
+package module
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/halliday/go-errors"
+)
+
+func TestFilter(t *testing.T) {
+	msg := &Message{
+		Module:    "auth",
+		Level:     Warn,
+		RichError: errors.NewRich("login_failed", 1001, "login failed", "", nil, nil),
+		Data:      map[string]interface{}{"tenant": "acme", "attempts": 3, "admin": false},
+	}
+	for src, expected := range map[string]bool{
+		`level >= warn && module == "auth" && data.tenant != "internal"`: true,
+		`level > warn`:                          false,
+		`level == warn`:                         true,
+		`warn <= level`:                         true,
+		`name =~ "^login_" && code >= 1000`:     true,
+		`!(code == 1001)`:                       false,
+		`data.attempts > 2.5 || level == error`: true,
+		`data.attempts < 3`:                     false,
+		`data.missing == "x"`:                   false,
+		`data.missing != "x"`:                   true,
+		`data.missing`:                          false,
+		`data.tenant && !data.admin`:            true,
+		`module == "auth"`:                      true,
+		`desc < "m"`:                            true,
+	} {
+		f, err := ParseFilter(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Match(msg) != expected {
+			t.Errorf("%s: expected %v", src, expected)
+		}
+	}
+
+	if !MustParseFilter(`name == ""`).Match(&Message{}) {
+		t.Error("name of message without error not empty")
+	}
+
+	for src, expected := range map[string]string{
+		`level >= loud`:     `position 10: unknown level "loud"`,
+		`(module == "auth"`: `position 18: missing )`,
+		`name =~ level`:     `position 9: =~ needs a string`,
+		`name == "a" name`:  `position 13: unexpected "name"`,
+		`name ==`:           `position 8: unexpected end`,
+		`name =~ "("`:       `position 9: error parsing regexp`,
+		`levle >= warn`:     `position 1: unknown field "levle"`,
+		`modul == "auth"`:   `position 1: unknown field "modul"`,
+		`module == auth`:    `position 11: unknown field "auth"`,
+		`modul =~ "a"`:      `position 1: unknown field "modul"`,
+		`!admin`:            `position 2: unknown field "admin"`,
+	} {
+		_, err := ParseFilter(src)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: unexpected error %v, expected %q", src, err, expected)
+		}
+	}
+}
+
+func TestFilterSink(t *testing.T) {
+	var names []string
+	sink := FilterSink(SinkFunc(func(msg *Message) error {
+		names = append(names, msg.Name)
+		return nil
+	}), MustParseFilter(`level == error`).Match)
+	m := NewWithOptions("module", messages, WithSinks(sink))
+	m.Info("test", "A", 1)
+	m.Err("test2")
+	if len(names) != 1 || names[0] != "test2" {
+		t.Fatalf("unexpected messages %v", names)
+	}
+}
+
+func TestNameFilter(t *testing.T) {
+	f, err := NewNameFilter([]string{"db.*", `/^http\.5\d\d$/`}, []string{"db.pool.*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]bool{
+		"db.conn.lost": true,
+		"db.pool.busy": false,
+		"http.503":     true,
+		"http.404":     false,
+		"auth.login":   false,
+		"":             false,
+	} {
+		msg := &Message{RichError: errors.NewRich(name, 0, "", "", nil, nil)}
+		if f.Match(msg) != expected {
+			t.Errorf("%q: expected %v", name, expected)
+		}
+	}
+
+	f, _ = NewNameFilter(nil, []string{"noisy"})
+	if !f.Match(&Message{}) || f.Match(&Message{RichError: errors.NewRich("noisy", 0, "", "", nil, nil)}) {
+		t.Error("exclude only filter does not match all other names")
+	}
+
+	for _, pattern := range []string{"[a-", "/(/"} {
+		if _, err := NewNameFilter([]string{pattern}, nil); err == nil {
+			t.Errorf("bad pattern %q accepted", pattern)
+		}
+	}
+}
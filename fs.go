@@ -0,0 +1,156 @@
+package module
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// NewFS creates a Module like NewWithOptions, with a catalog merged from all files
+// of fsys matching the glob pattern, e.g. "messages/*.csv" of an embed.FS.
+// Files are read in lexical order; a name defined in more than one file is an error.
+func NewFS(name string, fsys fs.FS, pattern string, opts ...Option) *Module {
+	c, err := parseCatalogFS(fsys, pattern)
+	if err != nil {
+		panic("module.NewFS(\"" + name + "\"): " + err.Error())
+	}
+	m := newModule(name, c)
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.registerOrWarn()
+	return m
+}
+
+// AddLocaleFS adds a translation for lang merged from all files of fsys matching the glob pattern,
+// see AddLocale and NewFS.
+func (m *Module) AddLocaleFS(lang string, fsys fs.FS, pattern string) {
+	c, err := parseCatalogFS(fsys, pattern)
+	if err != nil {
+		panic(fmt.Sprintf("module %q: locale %q: %v", m.Name, lang, err))
+	}
+	m.addLocale(lang, c)
+}
+
+func parseCatalogFS(fsys fs.FS, pattern string) (*catalog, error) {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%q matches no files", pattern)
+	}
+	return parseCatalogFiles(fsys, files)
+}
+
+func parseCatalogFiles(fsys fs.FS, files []string) (*catalog, error) {
+	c := &catalog{index: make(map[string]int)}
+	for _, file := range files {
+		src, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parseCatalog(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, e := range f.entries {
+			e.file = file
+			if i, ok := c.index[e.name]; ok && c.entries[i].file != file {
+				return nil, fmt.Errorf("%s: %q already defined at %s", e.pos(), e.name, c.entries[i].pos())
+			}
+			if _, ok := c.index[e.name]; !ok {
+				c.index[e.name] = len(c.entries)
+			}
+			c.entries = append(c.entries, e)
+		}
+	}
+	return c, nil
+}
+
+// BaseLocale is the locale NewFromDir uses as base catalog if the directory has no files without locale.
+const BaseLocale = "en"
+
+// NewFromDir creates a Module like NewWithOptions from the catalog files in dir of fsys.
+// Files are named "<stem>.<locale>.csv", like "messages.en.csv" and "messages.de.csv",
+// and files of the same locale are merged. The locale is a BCP 47 tag, like "pt-BR"; files with another
+// suffix, like "messages.v2.csv", are ignored. Files without locale, like "messages.csv", form the base
+// catalog; if there are none, the BaseLocale files are the base.
+// Every other locale is added with AddLocale and falls back to the base entries it does not translate.
+func NewFromDir(name string, fsys fs.FS, dir string, opts ...Option) *Module {
+	c, locales, err := parseCatalogDir(fsys, dir)
+	if err != nil {
+		panic("module.NewFromDir(\"" + name + "\"): " + err.Error())
+	}
+	m := newModule(name, c)
+	for lang, locale := range locales {
+		m.addLocale(lang, locale)
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.registerOrWarn()
+	return m
+}
+
+func parseCatalogDir(fsys fs.FS, dir string) (base *catalog, locales map[string]*catalog, err error) {
+	dir = path.Clean(dir)
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := make(map[string][]string)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || path.Ext(name) != ".csv" {
+			continue
+		}
+		stem := strings.TrimSuffix(name, ".csv")
+		lang := ""
+		if i := strings.LastIndexByte(stem, '.'); i != -1 {
+			tag, err := language.Parse(stem[i+1:])
+			if err != nil {
+				continue
+			}
+			lang = tag.String()
+		}
+		files[lang] = append(files[lang], path.Join(dir, name))
+	}
+	baseLang := ""
+	if _, ok := files[""]; !ok {
+		baseLang = BaseLocale
+	}
+	if _, ok := files[baseLang]; !ok {
+		return nil, nil, fmt.Errorf("%s: no base catalog", dir)
+	}
+	locales = make(map[string]*catalog)
+	for lang, names := range files {
+		sort.Strings(names)
+		c, err := parseCatalogFiles(fsys, names)
+		if err != nil {
+			return nil, nil, err
+		}
+		if lang == baseLang {
+			base = c
+		}
+		if lang != "" {
+			locales[lang] = c
+		}
+	}
+	for lang, c := range locales {
+		if lang == baseLang {
+			continue
+		}
+		for _, e := range base.entries {
+			if _, ok := c.index[e.name]; !ok {
+				c.index[e.name] = len(c.entries)
+				c.entries = append(c.entries, e)
+			}
+		}
+	}
+	return base, locales, nil
+}
@@ -0,0 +1,74 @@
+package module
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"messages/auth.csv":   {Data: []byte("login_failed;1;login failed for %s\n")},
+		"messages/db.csv":     {Data: []byte("# database\ndb.timeout;2;timeout after %dms\n")},
+		"messages/readme.txt": {Data: []byte("not a catalog")},
+		"de/auth.csv":         {Data: []byte("login_failed;1;Anmeldung für %s fehlgeschlagen\n")},
+	}
+	m := NewFS("module", fsys, "messages/*.csv")
+	m.AddLocaleFS("de", fsys, "de/*.csv")
+
+	if _, desc, _, _, _, _ := m.Lookup("db.timeout", 30); desc != "timeout after 30ms" {
+		t.Fatalf("unexpected desc %q", desc)
+	}
+	if e, _ := m.catalog.lookup("db.timeout"); e.pos() != "messages/db.csv:2" {
+		t.Fatalf("unexpected position %q", e.pos())
+	}
+	if desc, ok := m.Translate(m.NewError("login_failed", "bob"), "de"); !ok || desc != "Anmeldung für bob fehlgeschlagen" {
+		t.Fatalf("unexpected translation %q", desc)
+	}
+
+	for pattern, expected := range map[string]string{
+		"*/auth.csv":      `messages/auth.csv:1: "login_failed" already defined at de/auth.csv:1`,
+		"messages/*.json": `"messages/*.json" matches no files`,
+	} {
+		_, err := parseCatalogFS(fsys, pattern)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("parseCatalogFS(%q): unexpected error %v", pattern, err)
+		}
+	}
+}
+
+func TestNewFromDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"messages/messages.en.csv":    {Data: []byte("hello;1;hello %s\nbye;2;bye\n")},
+		"messages/auth.en.csv":        {Data: []byte("denied;3;access denied\n")},
+		"messages/messages.de.csv":    {Data: []byte("hello;1;hallo %s\n")},
+		"messages/messages.pt-br.csv": {Data: []byte("hello;1;olá %s\n")},
+		"messages/messages.v2.csv":    {Data: []byte("hello;9;not a locale\n")},
+		"messages/notes.txt":          {Data: []byte("not a catalog")},
+	}
+	m := NewFromDir("module", fsys, "messages/")
+	if _, desc, _, _, _, _ := m.Lookup("denied"); desc != "access denied" {
+		t.Fatalf("unexpected desc %q", desc)
+	}
+	for name, expected := range map[string]string{
+		"hello": "hallo bob",
+		"bye":   "bye",
+	} {
+		if desc, ok := m.Translate(m.NewError(name, "bob"), "de"); !ok || desc != expected {
+			t.Fatalf("Translate(%q) = %q, %v, expected %q", name, desc, ok, expected)
+		}
+	}
+	if desc, ok := m.Translate(m.NewError("hello", "bob"), "pt-BR"); !ok || desc != "olá bob" {
+		t.Fatalf("Translate(pt-BR) = %q, %v", desc, ok)
+	}
+	if _, ok := m.Translate(m.NewError("hello", "bob"), "v2"); ok {
+		t.Fatal("file without locale was added as locale")
+	}
+	if desc, ok := m.Translate(m.NewError("hello", "bob"), "en"); !ok || desc != "hello bob" {
+		t.Fatalf("Translate(en) = %q, %v", desc, ok)
+	}
+
+	if _, _, err := parseCatalogDir(fstest.MapFS{"m/messages.de.csv": {Data: []byte("a;1;a\n")}}, "m"); err == nil || err.Error() != "m: no base catalog" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+// Package gcpsink writes module messages to Google Cloud Logging with the entries.write API.
+//
+// The sink needs an authorized client, for example from golang.org/x/oauth2/google:
+//
+//	client, err := google.DefaultClient(ctx, gcpsink.Scope)
+//	res, err := gcpsink.DetectResource(ctx)
+//	sink := gcpsink.New(gcpsink.Options{Client: client, Resource: res})
+//	m.Sinks = append(m.Sinks, module.NewBatchSink(sink, module.BatchOptions{}))
+//
+// Levels become severities, the trace_id and span_id of messages (see module.CtxWithTraceParent)
+// link the entries to Cloud Trace, and the first frame becomes the source location.
+package gcpsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	module "github.com/halliday/go-module"
+)
+
+// Scope is the OAuth2 scope needed to write log entries.
+const Scope = "https://www.googleapis.com/auth/logging.write"
+
+// DefaultEndpoint is the Cloud Logging API.
+const DefaultEndpoint = "https://logging.googleapis.com"
+
+// A Resource is the monitored resource that produced the messages, see DetectResource.
+type Resource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type Options struct {
+	// Client sends authorized requests. Required.
+	Client *http.Client
+	// Resource is the monitored resource of the entries. Default global with the project_id of ProjectID.
+	Resource *Resource
+	// ProjectID is the project of the log. Default the project_id label of Resource.
+	ProjectID string
+	// LogID is the name of the log. Default "module".
+	LogID string
+	// Labels are added to every entry.
+	Labels map[string]string
+	// Endpoint is the URL of the API. Default DefaultEndpoint.
+	Endpoint string
+}
+
+// Sink is a module.Sink and module.BatchWriter writing messages as log entries with a JSON payload.
+type Sink struct {
+	url  string
+	opts Options
+}
+
+// New returns a Sink for opts. It panics if opts.Client is nil or there is no project ID.
+func New(opts Options) *Sink {
+	if opts.Client == nil {
+		panic("gcpsink.New: nil client")
+	}
+	if opts.ProjectID == "" && opts.Resource != nil {
+		opts.ProjectID = opts.Resource.Labels["project_id"]
+	}
+	if opts.ProjectID == "" {
+		panic("gcpsink.New: no project ID")
+	}
+	if opts.Resource == nil {
+		opts.Resource = &Resource{Type: "global", Labels: map[string]string{"project_id": opts.ProjectID}}
+	}
+	if opts.LogID == "" {
+		opts.LogID = "module"
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = DefaultEndpoint
+	}
+	return &Sink{url: opts.Endpoint + "/v2/entries:write", opts: opts}
+}
+
+// Severity returns the Cloud Logging severity of level.
+func Severity(level module.Level) string {
+	switch level {
+	case module.Trace, module.Debug:
+		return "DEBUG"
+	case module.Info:
+		return "INFO"
+	case module.Warn:
+		return "WARNING"
+	case module.Error:
+		return "ERROR"
+	}
+	return "DEFAULT"
+}
+
+type writeRequest struct {
+	LogName        string            `json:"logName"`
+	Resource       *Resource         `json:"resource"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Entries        []*entry          `json:"entries"`
+	PartialSuccess bool              `json:"partialSuccess"`
+}
+
+type entry struct {
+	Timestamp      string                 `json:"timestamp"`
+	Severity       string                 `json:"severity"`
+	InsertID       string                 `json:"insertId,omitempty"`
+	JSONPayload    map[string]interface{} `json:"jsonPayload"`
+	Labels         map[string]string      `json:"labels,omitempty"`
+	Trace          string                 `json:"trace,omitempty"`
+	SpanID         string                 `json:"spanId,omitempty"`
+	SourceLocation *sourceLocation        `json:"sourceLocation,omitempty"`
+}
+
+type sourceLocation struct {
+	File     string `json:"file"`
+	Line     string `json:"line"`
+	Function string `json:"function"`
+}
+
+func (s *Sink) entry(msg *module.Message) *entry {
+	payload := map[string]interface{}{"module": msg.Module}
+	e := &entry{
+		Timestamp:   msg.Time.UTC().Format(time.RFC3339Nano),
+		Severity:    Severity(msg.Level),
+		InsertID:    msg.ID,
+		JSONPayload: payload,
+		Labels:      map[string]string{"module": msg.Module},
+	}
+	if msg.RichError != nil {
+		// "message" is shown as the summary of the entry
+		payload["message"] = msg.Desc
+		if msg.Name != "" {
+			payload["name"] = msg.Name
+			e.Labels["name"] = msg.Name
+		}
+		if msg.Code != 0 {
+			payload["code"] = msg.Code
+		}
+		if msg.Link != "" {
+			payload["link"] = msg.Link
+		}
+		if msg.CausedBy != nil {
+			payload["caused_by"] = msg.CausedBy.Error()
+		}
+	}
+	if len(msg.Data) > 0 {
+		payload["data"] = msg.Data
+	}
+	if traceID, _ := msg.Data[module.KeyTraceID].(string); traceID != "" {
+		e.Trace = "projects/" + s.opts.ProjectID + "/traces/" + traceID
+		e.SpanID, _ = msg.Data[module.KeySpanID].(string)
+	}
+	if msg.ID != "" {
+		payload["id"] = msg.ID
+	}
+	if len(msg.Frames) > 0 {
+		f := msg.Frames[0]
+		e.SourceLocation = &sourceLocation{File: f.File, Line: strconv.Itoa(f.Line), Function: f.Function}
+	}
+	return e
+}
+
+func (s *Sink) Write(msg *module.Message) error {
+	return s.WriteBatch([]*module.Message{msg})
+}
+
+func (s *Sink) WriteBatch(msgs []*module.Message) error {
+	r := writeRequest{
+		LogName:        "projects/" + s.opts.ProjectID + "/logs/" + s.opts.LogID,
+		Resource:       s.opts.Resource,
+		Labels:         s.opts.Labels,
+		PartialSuccess: true,
+	}
+	for _, msg := range msgs {
+		r.Entries = append(r.Entries, s.entry(msg))
+	}
+	body, err := json.Marshal(&r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return module.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	resp.Body = io.NopCloser(io.LimitReader(resp.Body, 512))
+	return module.ResponseError(resp)
+}
+
+// metadataURL is the base URL of the metadata server of GCE, GKE and Cloud Run.
+var metadataURL = "http://metadata.google.internal/computeMetadata/v1/"
+
+// DetectResource returns the monitored resource of the process from the environment and the metadata server:
+// cloud_run_revision on Cloud Run, k8s_container on GKE, gce_instance on Compute Engine, or else global
+// with the project of GOOGLE_CLOUD_PROJECT. It returns an error if the project is unknown.
+func DetectResource(ctx context.Context) (*Resource, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	md := func(path string) string {
+		v, _ := metadata(ctx, path)
+		return v
+	}
+
+	project, err := metadata(ctx, "project/project-id")
+	if err != nil {
+		if project = os.Getenv("GOOGLE_CLOUD_PROJECT"); project == "" {
+			return nil, err
+		}
+		return &Resource{Type: "global", Labels: map[string]string{"project_id": project}}, nil
+	}
+	labels := map[string]string{"project_id": project}
+	switch {
+	case os.Getenv("K_SERVICE") != "":
+		labels["service_name"] = os.Getenv("K_SERVICE")
+		labels["revision_name"] = os.Getenv("K_REVISION")
+		labels["configuration_name"] = os.Getenv("K_CONFIGURATION")
+		labels["location"] = last(md("instance/region"))
+		return &Resource{Type: "cloud_run_revision", Labels: labels}, nil
+	case os.Getenv("KUBERNETES_SERVICE_HOST") != "":
+		labels["location"] = md("instance/attributes/cluster-location")
+		labels["cluster_name"] = md("instance/attributes/cluster-name")
+		labels["namespace_name"] = namespace()
+		labels["pod_name"] = os.Getenv("HOSTNAME")
+		labels["container_name"] = os.Getenv("CONTAINER_NAME")
+		return &Resource{Type: "k8s_container", Labels: labels}, nil
+	}
+	labels["instance_id"] = md("instance/id")
+	labels["zone"] = last(md("instance/zone"))
+	return &Resource{Type: "gce_instance", Labels: labels}, nil
+}
+
+func metadata(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := module.ResponseError(resp); err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return strings.TrimSpace(string(b)), err
+}
+
+// last returns the last part of a path like "projects/123/zones/us-central1-a".
+func last(path string) string {
+	return path[strings.LastIndexByte(path, '/')+1:]
+}
+
+// namespace returns the Kubernetes namespace of the pod.
+func namespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	if b, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		return strings.TrimSpace(string(b))
+	}
+	return "default"
+}
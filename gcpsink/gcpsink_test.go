@@ -0,0 +1,109 @@
+package gcpsink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+func TestWriteBatch(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/entries:write" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	s := New(Options{Client: srv.Client(), ProjectID: "shop-prod", Endpoint: srv.URL})
+	msg := &module.Message{
+		Module:    "auth",
+		Level:     module.Warn,
+		Time:      time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC),
+		ID:        "01GJ3Q2V4R8N6YH3T2KXW5Z9CD",
+		RichError: errors.NewRich("login_failed", 1001, "Login failed", "", nil, nil),
+		Data:      map[string]interface{}{module.KeyTraceID: "4bf92f3577b34da6a3ce929d0e0e4736", module.KeySpanID: "00f067aa0ba902b7"},
+		Frames:    []module.Frame{{Function: "main.login", File: "auth/login.go", Line: 42}},
+	}
+	if err := s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := json.Marshal(got)
+	want := `{"entries":[{"insertId":"01GJ3Q2V4R8N6YH3T2KXW5Z9CD",` +
+		`"jsonPayload":{"code":1001,"data":{"span_id":"00f067aa0ba902b7","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"},"id":"01GJ3Q2V4R8N6YH3T2KXW5Z9CD","message":"Login failed","module":"auth","name":"login_failed"},` +
+		`"labels":{"module":"auth","name":"login_failed"},"severity":"WARNING",` +
+		`"sourceLocation":{"file":"auth/login.go","function":"main.login","line":"42"},"spanId":"00f067aa0ba902b7",` +
+		`"timestamp":"2022-11-17T12:00:00Z","trace":"projects/shop-prod/traces/4bf92f3577b34da6a3ce929d0e0e4736"}],` +
+		`"logName":"projects/shop-prod/logs/module","partialSuccess":true,"resource":{"labels":{"project_id":"shop-prod"},"type":"global"}}`
+	if string(b) != want {
+		t.Fatalf("unexpected request\n%s\nwant\n%s", b, want)
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	for level, want := range map[module.Level]string{module.Trace: "DEBUG", module.Debug: "DEBUG", module.None: "DEFAULT", module.Info: "INFO", module.Warn: "WARNING", module.Error: "ERROR"} {
+		if got := Severity(level); got != want {
+			t.Fatalf("Severity(%v) = %s, want %s", level, got, want)
+		}
+	}
+}
+
+func TestDetectResource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/project/project-id":
+			w.Write([]byte("shop-prod"))
+		case "/instance/region":
+			w.Write([]byte("projects/123/regions/europe-west1"))
+		case "/instance/id":
+			w.Write([]byte("4711"))
+		case "/instance/zone":
+			w.Write([]byte("projects/123/zones/europe-west1-b"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer func(u string) { metadataURL = u }(metadataURL)
+	metadataURL = srv.URL + "/"
+
+	t.Setenv("K_SERVICE", "")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	res, err := DetectResource(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Type != "gce_instance" || res.Labels["instance_id"] != "4711" || res.Labels["zone"] != "europe-west1-b" {
+		t.Fatalf("unexpected resource %+v", res)
+	}
+
+	t.Setenv("K_SERVICE", "shop")
+	t.Setenv("K_REVISION", "shop-00001")
+	res, err = DetectResource(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Type != "cloud_run_revision" || res.Labels["service_name"] != "shop" || res.Labels["location"] != "europe-west1" || res.Labels["project_id"] != "shop-prod" {
+		t.Fatalf("unexpected resource %+v", res)
+	}
+
+	metadataURL = "http://127.0.0.1:1/"
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "local")
+	res, err = DetectResource(context.Background())
+	if err != nil || res.Type != "global" || res.Labels["project_id"] != "local" {
+		t.Fatalf("unexpected resource %+v, %v", res, err)
+	}
+}
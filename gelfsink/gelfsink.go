@@ -0,0 +1,266 @@
+// Package gelfsink sends module messages to Graylog in the Graylog Extended Log Format (GELF 1.1),
+// over UDP (chunked and optionally compressed) or TCP (null-byte delimited):
+//
+//	m.Sinks = append(m.Sinks, gelfsink.New("udp", "graylog:12201", gelfsink.Options{Compress: module.Gzip}))
+//
+// The description becomes short_message, the causes and frames full_message, and the module,
+// name, code, ID, caller and data become additional fields like _module and _user.
+package gelfsink
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	module "github.com/halliday/go-module"
+)
+
+// maxChunks is the largest number of chunks of a GELF message.
+const maxChunks = 128
+
+type Options struct {
+	// Host is the host field of the messages. Default os.Hostname.
+	Host string
+	// Compress compresses UDP messages, NoCompression or Gzip. Graylog does not accept compressed TCP messages.
+	Compress module.Compression
+	// ChunkSize is the largest UDP datagram, larger messages are chunked. Default 1420, which fits
+	// into the MTU of most networks; use 8154 within a LAN.
+	ChunkSize int
+	// Timeout limits connecting and writing a message. Default 10s.
+	Timeout time.Duration
+}
+
+// Sink is a module.Sink sending GELF messages.
+type Sink struct {
+	network string
+	addr    string
+	opts    Options
+	udp     bool
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// New returns a Sink sending to addr over network, "udp" or "tcp" (or their 4 and 6 variants).
+// It connects on the first Write. It panics for other networks, for compression other than Gzip,
+// for compression over TCP and for chunks smaller than 512 bytes.
+func New(network, addr string, opts Options) *Sink {
+	udp := strings.HasPrefix(network, "udp")
+	if !udp && !strings.HasPrefix(network, "tcp") {
+		panic("gelfsink.New: unsupported network " + network)
+	}
+	if opts.Compress != module.NoCompression && (!udp || opts.Compress != module.Gzip) {
+		panic("gelfsink.New: unsupported compression " + opts.Compress.Encoding() + " over " + network)
+	}
+	if opts.Host == "" {
+		opts.Host, _ = os.Hostname()
+	}
+	if opts.ChunkSize == 0 {
+		opts.ChunkSize = 1420
+	}
+	if opts.ChunkSize < 512 {
+		panic("gelfsink.New: chunk size " + strconv.Itoa(opts.ChunkSize) + " too small")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &Sink{network: network, addr: addr, opts: opts, udp: udp}
+}
+
+// Level returns the syslog severity of level that GELF uses.
+func Level(level module.Level) int {
+	switch level {
+	case module.Trace, module.Debug:
+		return 7
+	case module.Info:
+		return 6
+	case module.Warn:
+		return 4
+	case module.Error:
+		return 3
+	}
+	return 5 // notice
+}
+
+// Field returns the additional field of a data key: "_" followed by key with characters
+// other than letters, digits, '_', '.' and '-' replaced by '_'. The reserved "_id" becomes "__id".
+func Field(key string) string {
+	f := "_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, key)
+	if f == "_id" {
+		return "__id"
+	}
+	return f
+}
+
+// Marshal returns the GELF message of msg sent from host.
+func Marshal(msg *module.Message, host string) ([]byte, error) {
+	g := map[string]interface{}{
+		"version":   "1.1",
+		"host":      host,
+		"timestamp": json.Number(strconv.FormatFloat(float64(msg.Time.UnixMicro())/1e6, 'f', -1, 64)),
+		"level":     Level(msg.Level),
+	}
+	// data first, so it cannot replace the fields of the message
+	for key, value := range msg.Data {
+		switch v := value.(type) {
+		case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			g[Field(key)] = v
+		case time.Time:
+			g[Field(key)] = v.Format(time.RFC3339Nano)
+		default:
+			// GELF fields are strings or numbers
+			if data, err := json.Marshal(v); err == nil {
+				g[Field(key)] = string(data)
+			} else {
+				g[Field(key)] = fmt.Sprint(v)
+			}
+		}
+	}
+	g["_module"] = msg.Module
+	short := msg.Module
+	if msg.RichError != nil {
+		if msg.Desc != "" {
+			short = msg.Desc
+		}
+		if msg.Name != "" {
+			g["_name"] = msg.Name
+		}
+		if msg.Code != 0 {
+			g["_code"] = msg.Code
+		}
+		if msg.Link != "" {
+			g["_link"] = msg.Link
+		}
+	}
+	g["short_message"] = short
+	if full := fullMessage(msg); full != "" {
+		g["full_message"] = full
+	}
+	if msg.ID != "" {
+		g["_message_id"] = msg.ID
+	}
+	if msg.Caller != "" {
+		g["_caller"] = msg.Caller
+	}
+	return json.Marshal(g)
+}
+
+// fullMessage returns the causes and the frames of msg, one per line.
+func fullMessage(msg *module.Message) string {
+	var b strings.Builder
+	if msg.RichError != nil && msg.CausedBy != nil {
+		b.WriteString("caused by: ")
+		b.WriteString(msg.CausedBy.Error())
+		b.WriteByte('\n')
+	}
+	for _, f := range msg.Frames {
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Write sends msg. After a failed TCP write, the message is sent once more on a new connection.
+func (s *Sink) Write(msg *module.Message) error {
+	data, err := Marshal(msg, s.opts.Host)
+	if err != nil {
+		return err
+	}
+	if s.udp {
+		return s.writeUDP(data)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data = append(data, 0)
+	// the peer may have closed an idle connection
+	retry := s.conn != nil
+	err = s.write(data)
+	if err != nil && retry {
+		err = s.write(data)
+	}
+	return err
+}
+
+func (s *Sink) writeUDP(data []byte) error {
+	data, err := module.Compress(data, s.opts.Compress, 0)
+	if err != nil {
+		return err
+	}
+	chunks := Chunk(data, s.opts.ChunkSize)
+	if chunks == nil {
+		return module.Permanent(fmt.Errorf("gelf message of %d bytes exceeds %d chunks", len(data), maxChunks))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, chunk := range chunks {
+		if err := s.write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes p, connecting if needed. After an error, the connection is closed.
+func (s *Sink) write(p []byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.addr, s.opts.Timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.opts.Timeout))
+	if _, err := s.conn.Write(p); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// Chunk splits data into GELF chunks of at most size bytes, or returns data itself if it fits.
+// It returns nil if more than 128 chunks would be needed.
+func Chunk(data []byte, size int) [][]byte {
+	if len(data) <= size {
+		return [][]byte{data}
+	}
+	const header = 12
+	n := (len(data) + size - header - 1) / (size - header)
+	if n > maxChunks {
+		return nil
+	}
+	var id [8]byte
+	rand.Read(id[:])
+	chunks := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		part := data[i*(size-header) : min(len(data), (i+1)*(size-header))]
+		chunk := make([]byte, 0, header+len(part))
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id[:]...)
+		chunk = append(chunk, byte(i), byte(n))
+		chunks = append(chunks, append(chunk, part...))
+	}
+	return chunks
+}
+
+// Close closes the connection.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,157 @@
+package gelfsink
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+func TestMarshal(t *testing.T) {
+	msg := &module.Message{
+		Module:    "auth",
+		Level:     module.Warn,
+		Time:      time.Date(2022, 11, 17, 12, 0, 0, 250000000, time.UTC),
+		ID:        "01GJ3Q2V4R8N6YH3T2KXW5Z9CD",
+		RichError: errors.NewRich("login_failed", 1001, "Login failed", "", nil, errors.New("db down")),
+		Data:      map[string]interface{}{"user": "bob", "n": 3, "id": 7, "tags": []string{"a"}, "module": "spoofed"},
+		Frames:    []module.Frame{{Function: "main.login", File: "auth/login.go", Line: 42}},
+	}
+	data, err := Marshal(msg, "host-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g map[string]interface{}
+	if err := json.Unmarshal(data, &g); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]interface{}{
+		"version":       "1.1",
+		"host":          "host-1",
+		"timestamp":     1668686400.25,
+		"level":         4.0,
+		"short_message": "Login failed",
+		"full_message":  "caused by: db down\nmain.login\n\tauth/login.go:42",
+		"_module":       "auth",
+		"_name":         "login_failed",
+		"_code":         1001.0,
+		"_message_id":   "01GJ3Q2V4R8N6YH3T2KXW5Z9CD",
+		"_user":         "bob",
+		"_n":            3.0,
+		"__id":          7.0,
+		"_tags":         `["a"]`,
+	} {
+		if g[key] != want {
+			t.Fatalf("%s = %#v, want %#v", key, g[key], want)
+		}
+	}
+}
+
+func TestUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	s := New("udp", pc.LocalAddr().String(), Options{Host: "host-1", Compress: module.Gzip, ChunkSize: 512})
+	defer s.Close()
+	// random hex compresses poorly, so the message needs a few chunks
+	b := make([]byte, 2000)
+	rand.Read(b)
+	random := hex.EncodeToString(b)
+	if err := s.Write(&module.Message{Module: "auth", Level: module.Info, Time: time.Now(), Data: map[string]interface{}{"random": random}}); err != nil {
+		t.Fatal(err)
+	}
+
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	var chunks [][]byte
+	for n := 1; len(chunks) < n; {
+		size, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunk := append([]byte(nil), buf[:size]...)
+		if size > 512 || chunk[0] != 0x1e || chunk[1] != 0x0f || int(chunk[10]) != len(chunks) {
+			t.Fatalf("unexpected chunk header % x", chunk[:12])
+		}
+		n = int(chunk[11])
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	var data []byte
+	for _, chunk := range chunks {
+		data = append(data, chunk[12:]...)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = io.ReadAll(r)
+	var g map[string]interface{}
+	if err := json.Unmarshal(data, &g); err != nil || g["_random"] != random {
+		t.Fatalf("unexpected message %.100s, %v", data, err)
+	}
+}
+
+func TestTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	msgs := make(chan string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			msg, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			msgs <- msg
+		}
+	}()
+	s := New("tcp", ln.Addr().String(), Options{Host: "host-1"})
+	defer s.Close()
+	for _, name := range []string{"a", "b"} {
+		if err := s.Write(&module.Message{Module: name, Time: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{"a", "b"} {
+		msg := <-msgs
+		var g map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSuffix(msg, "\x00")), &g); err != nil || g["_module"] != want || g["short_message"] != want {
+			t.Fatalf("unexpected message %q, %v", msg, err)
+		}
+	}
+}
+
+func TestChunk(t *testing.T) {
+	if chunks := Chunk(make([]byte, 100), 512); len(chunks) != 1 || len(chunks[0]) != 100 {
+		t.Fatal("expected data itself")
+	}
+	if chunks := Chunk(make([]byte, 128*500), 512); len(chunks) != 128 {
+		t.Fatalf("expected 128 chunks, got %d", len(chunks))
+	}
+	if Chunk(make([]byte, 128*500+1), 512) != nil {
+		t.Fatal("expected too many chunks")
+	}
+}
@@ -1,5 +1,9 @@
 module github.com/halliday/go-module
 
-go 1.18
+go 1.21
 
-require github.com/halliday/go-errors v0.0.0-20221117114904-701c88d594be
+require (
+	github.com/halliday/go-errors v0.0.0-20221117114904-701c88d594be
+	github.com/klauspost/compress v1.17.9
+	golang.org/x/text v0.13.0
+)
@@ -0,0 +1,47 @@
+package module
+
+// GQLError has the JSON layout of a GraphQL error (and of gqlerror.Error of gqlgen).
+// It implements error and the Extensions method gqlgen presents, so resolvers can return it directly.
+type GQLError struct {
+	Message string                 `json:"message"`
+	Path    []interface{}          `json:"path,omitempty"`
+	Ext     map[string]interface{} `json:"extensions,omitempty"`
+	err     error
+}
+
+// GraphQLError converts err to a GQLError. The message is the description of the first named error
+// in the chain of err, and the extensions "code", "name", "link" and "data" carry its catalog entry,
+// so clients can branch on them. Empty extensions are omitted. A nil err returns nil.
+func GraphQLError(err error) *GQLError {
+	if err == nil {
+		return nil
+	}
+	named := NamedError(err)
+	if named == nil {
+		return &GQLError{Message: err.Error(), err: err}
+	}
+	e := EncodeError(named)
+	ext := map[string]interface{}{"name": e.Name}
+	if e.Code != 0 {
+		ext["code"] = e.Code
+	}
+	if e.Link != "" {
+		ext["link"] = e.Link
+	}
+	if e.Data != nil {
+		ext["data"] = e.Data
+	}
+	return &GQLError{Message: e.Desc, Ext: ext, err: err}
+}
+
+func (e *GQLError) Error() string {
+	return e.Message
+}
+
+func (e *GQLError) Unwrap() error {
+	return e.err
+}
+
+func (e *GQLError) Extensions() map[string]interface{} {
+	return e.Ext
+}
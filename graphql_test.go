@@ -0,0 +1,30 @@
+package module
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestGraphQLError(t *testing.T) {
+	m := NewWithOptions("module", "not_found;404;%s not found;https://example.com/404\n")
+	err := fmt.Errorf("resolve: %w", m.NewError("not_found", "user", "id", 7))
+	e := GraphQLError(err)
+	e.Path = []interface{}{"user", 0}
+	data, _ := json.Marshal(e)
+	expected := `{"message":"user not found","path":["user",0],"extensions":{"code":404,"data":{"format_args":["user"],"id":7},"link":"https://example.com/404","name":"not_found"}}`
+	if string(data) != expected {
+		t.Fatalf("unexpected JSON %s", data)
+	}
+	if !errors.Is(e, err) || e.Extensions()["name"] != "not_found" {
+		t.Fatalf("unexpected error %v", e)
+	}
+
+	if data, _ := json.Marshal(GraphQLError(errors.New("boom"))); string(data) != `{"message":"boom"}` {
+		t.Fatalf("unexpected JSON %s", data)
+	}
+	if GraphQLError(nil) != nil {
+		t.Fatal("nil error was converted")
+	}
+}
@@ -0,0 +1,21 @@
+module github.com/halliday/go-module/grpcerr
+
+go 1.21
+
+require (
+	github.com/halliday/go-errors v0.0.0-20221117114904-701c88d594be
+	github.com/halliday/go-module v0.0.0-00010101000000-000000000000
+	google.golang.org/genproto v0.0.0-20230306155012-7f2fa6fef1f4
+	google.golang.org/grpc v1.55.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
+
+replace github.com/halliday/go-module => ../
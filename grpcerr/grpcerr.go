@@ -0,0 +1,183 @@
+// Package grpcerr carries module errors across gRPC as status details.
+//
+// The name, code, link and data of a RichError travel as google.rpc.ErrorInfo,
+// the description as google.rpc.LocalizedMessage:
+//
+//	return nil, grpcerr.Status("auth", err, "en").Err()
+//
+// and on the client side:
+//
+//	err = grpcerr.FromError(err)
+//
+// The status code is derived from the error, see Code.
+package grpcerr
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys of the ErrorInfo detail. Data is JSON encoded.
+const (
+	KeyCode = "code"
+	KeyLink = "link"
+	KeyData = "data"
+)
+
+// CodeOf, if set, maps a module error to a status code before Code does, e.g. for catalog codes
+// that are not HTTP statuses. It returns false for the default mapping. Set it during initialization.
+var CodeOf func(e *module.EncodedError) (codes.Code, bool)
+
+// httpCodes maps HTTP statuses to status codes.
+var httpCodes = map[int]codes.Code{
+	http.StatusBadRequest:                   codes.InvalidArgument,
+	http.StatusUnauthorized:                 codes.Unauthenticated,
+	http.StatusForbidden:                    codes.PermissionDenied,
+	http.StatusNotFound:                     codes.NotFound,
+	http.StatusRequestTimeout:               codes.DeadlineExceeded,
+	http.StatusConflict:                     codes.AlreadyExists,
+	http.StatusPreconditionFailed:           codes.FailedPrecondition,
+	http.StatusRequestedRangeNotSatisfiable: codes.OutOfRange,
+	http.StatusTooManyRequests:              codes.ResourceExhausted,
+	499:                                     codes.Canceled,
+	http.StatusInternalServerError:          codes.Internal,
+	http.StatusNotImplemented:               codes.Unimplemented,
+	http.StatusServiceUnavailable:           codes.Unavailable,
+	http.StatusGatewayTimeout:               codes.DeadlineExceeded,
+}
+
+// codeNames are the status codes by their names in snake case, in the order they are matched.
+var codeNames = []struct {
+	name string
+	code codes.Code
+}{
+	{"canceled", codes.Canceled},
+	{"invalid_argument", codes.InvalidArgument},
+	{"deadline_exceeded", codes.DeadlineExceeded},
+	{"not_found", codes.NotFound},
+	{"already_exists", codes.AlreadyExists},
+	{"permission_denied", codes.PermissionDenied},
+	{"resource_exhausted", codes.ResourceExhausted},
+	{"failed_precondition", codes.FailedPrecondition},
+	{"aborted", codes.Aborted},
+	{"out_of_range", codes.OutOfRange},
+	{"unimplemented", codes.Unimplemented},
+	{"internal", codes.Internal},
+	{"unavailable", codes.Unavailable},
+	{"data_loss", codes.DataLoss},
+	{"unauthenticated", codes.Unauthenticated},
+}
+
+// Code returns the status code of the first named error in the chain of err: the code of CodeOf,
+// the code for its catalog code as HTTP status (404 is NotFound), or the code its name ends with
+// ("user_not_found" is NotFound). Otherwise it is Unknown, and OK for nil.
+func Code(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	named := module.NamedError(err)
+	if named == nil {
+		return codes.Unknown
+	}
+	e := module.EncodeError(named)
+	if CodeOf != nil {
+		if code, ok := CodeOf(e); ok {
+			return code
+		}
+	}
+	if code, ok := httpCodes[e.Code]; ok {
+		return code
+	}
+	for _, c := range codeNames {
+		if e.Name == c.name || strings.HasSuffix(e.Name, "_"+c.name) || strings.HasSuffix(e.Name, "."+c.name) {
+			return c.code
+		}
+	}
+	return codes.Unknown
+}
+
+// Status returns a status with the Code and the message of err, or nil for a nil err. The first named
+// error in the chain of err is attached as ErrorInfo of domain, e.g. the service or module name,
+// and its description as LocalizedMessage in locale.
+func Status(domain string, err error, locale string) *status.Status {
+	if err == nil {
+		return nil
+	}
+	st := status.New(Code(err), err.Error())
+	named := module.NamedError(err)
+	if named == nil {
+		return st
+	}
+	e := module.EncodeError(named)
+	info := &errdetails.ErrorInfo{
+		Reason:   e.Name,
+		Domain:   domain,
+		Metadata: make(map[string]string),
+	}
+	if e.Code != 0 {
+		info.Metadata[KeyCode] = strconv.Itoa(e.Code)
+	}
+	if e.Link != "" {
+		info.Metadata[KeyLink] = e.Link
+	}
+	if e.Data != nil {
+		if data, err := json.Marshal(e.Data); err == nil {
+			info.Metadata[KeyData] = string(data)
+		}
+	}
+	msg := &errdetails.LocalizedMessage{Locale: locale, Message: e.Desc}
+	if withDetails, err := st.WithDetails(info, msg); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// FromStatus reconstructs the RichError attached by Status, or returns nil if st has no ErrorInfo.
+// Without LocalizedMessage, the status message is used as description.
+func FromStatus(st *status.Status) *errors.RichError {
+	var info *errdetails.ErrorInfo
+	desc := st.Message()
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			if info == nil {
+				info = d
+			}
+		case *errdetails.LocalizedMessage:
+			desc = d.Message
+		}
+	}
+	if info == nil {
+		return nil
+	}
+	e := &errors.RichError{
+		Name: info.Reason,
+		Desc: desc,
+		Link: info.Metadata[KeyLink],
+	}
+	e.Code, _ = strconv.Atoi(info.Metadata[KeyCode])
+	if data, ok := info.Metadata[KeyData]; ok {
+		json.Unmarshal([]byte(data), &e.Data)
+	}
+	return e
+}
+
+// FromError returns the RichError carried by the gRPC status of err, or err itself.
+func FromError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	if e := FromStatus(st); e != nil {
+		return e
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package grpcerr
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRoundTrip(t *testing.T) {
+	m := module.NewWithOptions("auth", "user_not_found;404;user %s not found;https://example.com/404\n")
+	err := fmt.Errorf("lookup: %w", m.NewError("user_not_found", "bob", "tenant", "acme"))
+
+	st := Status("auth", err, "en")
+	if st.Code() != codes.NotFound || st.Message() != err.Error() {
+		t.Fatalf("unexpected status %v", st)
+	}
+	received := FromError(st.Err())
+	expected := &errors.RichError{
+		Name: "user_not_found",
+		Code: 404,
+		Desc: "user bob not found",
+		Link: "https://example.com/404",
+		Data: map[string]interface{}{"tenant": "acme", module.KeyFormatArgs: []interface{}{"bob"}},
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Fatalf("unexpected error %#v", received)
+	}
+
+	plain := status.Error(codes.Internal, "boom")
+	if FromError(plain) != plain {
+		t.Fatal("status without details was replaced")
+	}
+	if st := Status("auth", fmt.Errorf("boom"), "en"); st.Code() != codes.Unknown || len(st.Details()) != 0 {
+		t.Fatalf("unexpected details %v", st.Details())
+	}
+}
+
+func TestCode(t *testing.T) {
+	m := module.NewWithOptions("auth", "user_not_found;404;user not found\ndb.unavailable;0;database down\nlocked;7;account locked\n")
+	CodeOf = func(e *module.EncodedError) (codes.Code, bool) {
+		return codes.PermissionDenied, e.Code == 7
+	}
+	defer func() { CodeOf = nil }()
+	for err, expected := range map[error]codes.Code{
+		nil:                          codes.OK,
+		fmt.Errorf("boom"):           codes.Unknown,
+		m.NewError("user_not_found"): codes.NotFound,
+		m.NewError("db.unavailable"): codes.Unavailable,
+		m.NewError("locked"):         codes.PermissionDenied,
+		fmt.Errorf("x: %w", m.NewError("locked")): codes.PermissionDenied,
+	} {
+		if code := Code(err); code != expected {
+			t.Errorf("Code(%v) = %v, expected %v", err, code, expected)
+		}
+	}
+	if Status("auth", nil, "en") != nil {
+		t.Fatal("status for nil error")
+	}
+}
@@ -0,0 +1,111 @@
+package module
+
+import "sync"
+
+type HealthStatus int
+
+const (
+	Healthy HealthStatus = iota
+	Degraded
+	CircuitOpen
+)
+
+func (s HealthStatus) String() string {
+	switch s {
+	case Healthy:
+		return "ok"
+	case Degraded:
+		return "degraded"
+	case CircuitOpen:
+		return "open-circuit"
+	}
+	return "unknown"
+}
+
+type SinkHealth struct {
+	Sink       Sink
+	Status     HealthStatus
+	LastError  error
+	QueueDepth int
+}
+
+// HealthReporter is implemented by sinks that know their own health, like DeadLetterSink and BreakerSink.
+// Other sinks are Degraded if their last write failed.
+type HealthReporter interface {
+	Health() SinkHealth
+}
+
+func (s *DeadLetterSink) Health() SinkHealth {
+	h := SinkHealth{Sink: s}
+	if r, ok := s.sink.(HealthReporter); ok {
+		h = r.Health()
+		h.Sink = s
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h.QueueDepth += len(s.buf)
+	if len(s.buf) > 0 && h.Status == Healthy {
+		h.Status = Degraded
+	}
+	return h
+}
+
+func (s *BreakerSink) Health() SinkHealth {
+	state, err := s.State()
+	h := SinkHealth{Sink: s, LastError: err}
+	switch state {
+	case Open:
+		h.Status = CircuitOpen
+	case HalfOpen:
+		h.Status = Degraded
+	}
+	return h
+}
+
+type sinkHealth struct {
+	mu     sync.Mutex
+	errs   []error
+	status []HealthStatus
+}
+
+// SinkHealth returns the health of every sink of the Module, for readiness probes.
+func (m *Module) SinkHealth() []SinkHealth {
+	health := make([]SinkHealth, len(m.Sinks))
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+	for i, sink := range m.Sinks {
+		health[i] = m.sinkHealth(i, sink)
+	}
+	return health
+}
+
+func (m *Module) sinkHealth(i int, sink Sink) SinkHealth {
+	if r, ok := sink.(HealthReporter); ok {
+		h := r.Health()
+		h.Sink = sink
+		return h
+	}
+	h := SinkHealth{Sink: sink}
+	if i < len(m.health.errs) && m.health.errs[i] != nil {
+		h.Status = Degraded
+		h.LastError = m.health.errs[i]
+	}
+	return h
+}
+
+// trackHealth records the result of a write to the i-th sink and calls OnSinkHealth if its status changed.
+func (m *Module) trackHealth(i int, sink Sink, err error) {
+	m.health.mu.Lock()
+	if len(m.health.errs) != len(m.Sinks) {
+		m.health.errs = make([]error, len(m.Sinks))
+		m.health.status = make([]HealthStatus, len(m.Sinks))
+	}
+	m.health.errs[i] = err
+	h := m.sinkHealth(i, sink)
+	changed := h.Status != m.health.status[i]
+	m.health.status[i] = h.Status
+	m.health.mu.Unlock()
+	if changed && m.OnSinkHealth != nil {
+		m.OnSinkHealth(h)
+	}
+}
@@ -0,0 +1,53 @@
+package module
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+)
+
+func TestSinkHealth(t *testing.T) {
+	down := false
+	failing := SinkFunc(func(msg *Message) error {
+		if down {
+			return errors.New("down")
+		}
+		return nil
+	})
+	l, _, m := New("module", messages)
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+	deadLetter := NewDeadLetterSink(failing, 10)
+	m.Sinks = []Sink{failing, deadLetter}
+	var changes []HealthStatus
+	m.OnSinkHealth = func(h SinkHealth) {
+		changes = append(changes, h.Status)
+	}
+
+	l.Info("test")
+	down = true
+	l.Info("test")
+	l.Info("test")
+
+	health := m.SinkHealth()
+	if health[0].Status != Degraded || health[0].LastError == nil {
+		t.Fatalf("unexpected health of sink 0: %+v", health[0])
+	}
+	if health[1].Status != Degraded || health[1].QueueDepth != 2 {
+		t.Fatalf("unexpected health of sink 1: %+v", health[1])
+	}
+
+	down = false
+	if err := deadLetter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("test")
+	for i, h := range m.SinkHealth() {
+		if h.Status != Healthy {
+			t.Fatalf("sink %d did not recover: %+v", i, h)
+		}
+	}
+	if len(changes) != 4 {
+		t.Fatalf("unexpected health changes: %v", changes)
+	}
+}
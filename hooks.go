@@ -0,0 +1,106 @@
+package module
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+type hookEntry struct {
+	priority int
+	id       uint64
+	hook     Hook
+}
+
+// hookList is a list of hooks ordered by priority and registration. Registration is
+// serialized, iteration is lock free on a copy-on-write slice.
+type hookList struct {
+	mu     sync.Mutex
+	nextID uint64
+	hooks  atomic.Value // []hookEntry
+}
+
+func (l *hookList) add(priority int, hook Hook) (remove func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.nextID++
+	id := l.nextID
+	old := l.load()
+	hooks := make([]hookEntry, len(old), len(old)+1)
+	copy(hooks, old)
+	hooks = append(hooks, hookEntry{priority, id, hook})
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].priority < hooks[j].priority
+	})
+	l.hooks.Store(hooks)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.remove(id)
+		})
+	}
+}
+
+func (l *hookList) remove(id uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	old := l.load()
+	hooks := make([]hookEntry, 0, len(old))
+	for _, e := range old {
+		if e.id != id {
+			hooks = append(hooks, e)
+		}
+	}
+	l.hooks.Store(hooks)
+}
+
+func (l *hookList) load() []hookEntry {
+	hooks, _ := l.hooks.Load().([]hookEntry)
+	return hooks
+}
+
+// run passes msg through all hooks in order with call and stops if a hook returns nil.
+func (l *hookList) run(msg *Message, call func(hook Hook, msg *Message) *Message) *Message {
+	for _, e := range l.load() {
+		if msg = call(e.hook, msg); msg == nil {
+			return nil
+		}
+	}
+	return msg
+}
+
+// clone returns a copy of l with the same hooks. Removing a hook from one list does not affect the other.
+func (l *hookList) clone(c *hookList) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c.nextID = l.nextID
+	c.hooks.Store(l.load())
+}
+
+// Conventional hook priorities: enrichment runs before redaction, which runs before hooks that
+// ship messages elsewhere.
+const (
+	PriorityEnrich = -100
+	PriorityRedact = 0
+	PriorityShip   = 100
+)
+
+var globalHooks hookList
+
+// AddGlobalHook registers a hook that runs for the messages of all modules.
+// Hooks run in ascending priority, hooks with the same priority in registration order.
+// Each hook receives the message returned by the previous one; returning nil stops the chain.
+// AddGlobalHook is safe for concurrent use. Call remove to unregister the hook.
+func AddGlobalHook(priority int, hook Hook) (remove func()) {
+	return globalHooks.add(priority, hook)
+}
+
+// AddHook registers a hook for the messages of m. Like with AddGlobalHook, hooks run in ascending
+// priority and in registration order for the same priority, and returning nil stops the chain.
+//
+// The hooks of a message run in this order: the hook of CtxCatch, Module.Hook, the hooks of AddHook,
+// the hooks of AddGlobalHook and GlobalHook. Then the message is signed and written to the sinks.
+func (m *Module) AddHook(priority int, hook Hook) (remove func()) {
+	return m.hooks.add(priority, hook)
+}
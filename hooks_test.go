@@ -0,0 +1,103 @@
+package module
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAddGlobalHook(t *testing.T) {
+	var order []string
+	hook := func(name string) Hook {
+		return func(msg *Message) *Message {
+			if msg.Module == "global" {
+				order = append(order, name)
+			}
+			return msg
+		}
+	}
+	removeC := AddGlobalHook(10, hook("c"))
+	removeA := AddGlobalHook(-10, hook("a"))
+	removeB1 := AddGlobalHook(0, hook("b1"))
+	removeB2 := AddGlobalHook(0, hook("b2"))
+
+	l, _, m := New("global", messages)
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+	l.Info("test")
+	removeB1()
+	removeB1()
+	l.Info("test")
+	removeA()
+	removeB2()
+	removeC()
+	l.Info("test")
+
+	if strings.Join(order, ",") != "a,b1,b2,c,a,b2,c" {
+		t.Fatalf("unexpected order %v", order)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			remove := AddGlobalHook(0, func(msg *Message) *Message { return msg })
+			l.Info("test")
+			remove()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNoGlobalHooks(t *testing.T) {
+	var modules []string
+	remove := AddGlobalHook(0, func(msg *Message) *Message {
+		if msg.Module == "app" || msg.Module == "lib" {
+			modules = append(modules, msg.Module)
+		}
+		return msg
+	})
+	defer remove()
+
+	app := NewWithOptions("app", messages, WithLogger(log.New(&bytes.Buffer{}, "", 0)))
+	lib := app.Clone(func(m *Module) { m.Name = "lib" }, WithoutGlobalHooks())
+	app.Info("test")
+	lib.Info("test")
+
+	if strings.Join(modules, ",") != "app" {
+		t.Fatalf("unexpected modules %v", modules)
+	}
+}
+
+func TestAddHook(t *testing.T) {
+	var order []string
+	hook := func(name string) Hook {
+		return func(msg *Message) *Message {
+			order = append(order, name)
+			return msg
+		}
+	}
+	m := NewWithOptions("module", messages,
+		WithLogger(log.New(&bytes.Buffer{}, "", 0)),
+		WithHook(hook("hook")),
+		WithHookPriority(PriorityShip, hook("ship")),
+		WithHookPriority(PriorityEnrich, hook("enrich")),
+		WithHookPriority(PriorityRedact, hook("redact")),
+		WithHookPriority(PriorityEnrich, hook("enrich2")),
+	)
+	remove := m.AddHook(PriorityRedact, hook("redact2"))
+	c := m.Clone()
+	remove()
+
+	m.Info("test")
+	if strings.Join(order, ",") != "hook,enrich,enrich2,redact,ship" {
+		t.Fatalf("unexpected order %v", order)
+	}
+	order = nil
+	c.Info("test")
+	if strings.Join(order, ",") != "hook,enrich,enrich2,redact,redact2,ship" {
+		t.Fatalf("unexpected order of clone %v", order)
+	}
+}
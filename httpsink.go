@@ -0,0 +1,136 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type HTTPSinkOptions struct {
+	// Client sends the requests. Default http.DefaultClient.
+	Client *http.Client
+	// Header is added to every request, e.g. for authorization.
+	Header http.Header
+	// Compress compresses the request bodies and sets Content-Encoding.
+	// If the server answers 415 Unsupported Media Type, the sink switches to an encoding listed
+	// in the Accept-Encoding header of the response (RFC 7694), or to no compression.
+	Compress Compression
+	// Level is the compression level, see FileSinkOptions.
+	Level int
+}
+
+// HTTPSink posts messages as JSON lines (application/x-ndjson) to a URL, like a webhook.
+// It is a BatchWriter, wrap it with NewBatchSink to send many messages per request.
+// Responses with status 4xx, except 408 and 429, are Permanent errors.
+type HTTPSink struct {
+	url  string
+	opts HTTPSinkOptions
+
+	mu       sync.Mutex
+	compress Compression
+}
+
+func NewHTTPSink(url string, opts HTTPSinkOptions) *HTTPSink {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return &HTTPSink{url: url, opts: opts, compress: opts.Compress}
+}
+
+func (s *HTTPSink) Write(msg *Message) error {
+	return s.WriteBatch([]*Message{msg})
+}
+
+func (s *HTTPSink) WriteBatch(msgs []*Message) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, msg := range msgs {
+		if err := enc.Encode(msg); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	compress := s.compress
+	s.mu.Unlock()
+
+	resp, err := s.post(body.Bytes(), compress)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnsupportedMediaType && compress != NoCompression {
+		compress = negotiateEncoding(resp.Header.Get("Accept-Encoding"), s.opts.Compress)
+		s.mu.Lock()
+		s.compress = compress
+		s.mu.Unlock()
+		if resp, err = s.post(body.Bytes(), compress); err != nil {
+			return err
+		}
+	}
+	return ResponseError(resp)
+}
+
+// post sends body compressed with c and closes the response body.
+func (s *HTTPSink) post(body []byte, c Compression) (*http.Response, error) {
+	body, err := Compress(body, c, s.opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	for key, values := range s.opts.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if c != NoCompression {
+		req.Header.Set("Content-Encoding", c.Encoding())
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// keep the start of the body for error messages
+	var head bytes.Buffer
+	io.CopyN(&head, resp.Body, 512)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(&head)
+	return resp, nil
+}
+
+// negotiateEncoding picks preferred if it is listed in acceptEncoding, else another supported
+// compression, else NoCompression.
+func negotiateEncoding(acceptEncoding string, preferred Compression) Compression {
+	accepted := make(map[string]bool)
+	for _, e := range strings.Split(acceptEncoding, ",") {
+		if i := strings.IndexByte(e, ';'); i != -1 {
+			e = e[:i]
+		}
+		accepted[strings.ToLower(strings.TrimSpace(e))] = true
+	}
+	for _, c := range []Compression{preferred, Zstd, Gzip} {
+		if accepted[c.Encoding()] {
+			return c
+		}
+	}
+	return NoCompression
+}
+
+// ResponseError returns an error for responses that are not 2xx, with the start of the body,
+// for sinks built on other HTTP APIs. Status 4xx, except 408 and 429, is a Permanent error.
+func ResponseError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(resp.Body)
+	err := fmt.Errorf("%s %s: %s: %s", resp.Request.Method, resp.Request.URL.Redacted(), resp.Status, bytes.TrimSpace(body))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package module
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPSink(t *testing.T) {
+	var encodings []string
+	var names []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := r.Header.Get("Content-Encoding")
+		encodings = append(encodings, enc)
+		var body io.Reader = r.Body
+		switch enc {
+		case "zstd":
+			w.Header().Set("Accept-Encoding", "gzip")
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		case "gzip":
+			body, _ = gzip.NewReader(r.Body)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			http.Error(w, "bad headers", http.StatusBadRequest)
+			return
+		}
+		s := bufio.NewScanner(body)
+		for s.Scan() {
+			var msg map[string]interface{}
+			json.Unmarshal(s.Bytes(), &msg)
+			names = append(names, msg["name"].(string))
+		}
+	}))
+	defer srv.Close()
+
+	m := NewWithOptions("module", messages)
+	var msgs []*Message
+	m.Sinks = []Sink{SinkFunc(func(msg *Message) error {
+		msgs = append(msgs, msg)
+		return nil
+	})}
+	m.Info("test", "A", 1)
+	m.Warn("test2")
+
+	s := NewHTTPSink(srv.URL, HTTPSinkOptions{Header: http.Header{"Authorization": {"Bearer token"}}, Compress: Zstd})
+	if err := s.WriteBatch(msgs); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(msgs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(encodings) != 3 || encodings[0] != "zstd" || encodings[1] != "gzip" || encodings[2] != "gzip" {
+		t.Fatalf("unexpected encodings %v", encodings)
+	}
+	if len(names) != 3 || names[0] != "test" || names[1] != "test2" {
+		t.Fatalf("unexpected names %v", names)
+	}
+
+	err := NewHTTPSink(srv.URL, HTTPSinkOptions{}).Write(msgs[0])
+	if !IsPermanent(err) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, expected := range map[string]Compression{
+		"":               NoCompression,
+		"identity":       NoCompression,
+		"gzip;q=0.5, br": Gzip,
+		"GZIP, zstd":     Zstd,
+	} {
+		if c := negotiateEncoding(header, Zstd); c != expected {
+			t.Errorf("negotiateEncoding(%q) = %v, expected %v", header, c, expected)
+		}
+	}
+	if c := negotiateEncoding("gzip, zstd", Gzip); c != Gzip {
+		t.Errorf("preferred compression not chosen: %v", c)
+	}
+}
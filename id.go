@@ -0,0 +1,52 @@
+package module
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+)
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newID returns a ULID: 26 characters, lexically sortable by time (millisecond precision).
+func newID(t time.Time) string {
+	var b [16]byte
+	ms := uint64(t.UnixMilli())
+	binary.BigEndian.PutUint16(b[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:], uint32(ms))
+	rand.Read(b[6:])
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	var s [26]byte
+	for i := len(s) - 1; i >= 0; i-- {
+		s[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:])
+}
+
+type idContextKey struct{}
+
+// ctxWithID presets the ID of the next message logged with ctx.
+func ctxWithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idContextKey{}, id)
+}
+
+func ctxID(ctx context.Context) string {
+	id, _ := ctx.Value(idContextKey{}).(string)
+	return id
+}
+
+// EmitWithID logs like Log and returns the ID of the message, to be shown to a user as reference.
+// It returns "" if the message is suppressed by Throttle.
+func (m *Module) EmitWithID(level Level, name string, args ...interface{}) (id string) {
+	if !m.allow(name) {
+		return ""
+	}
+	code, desc, link, tail, ctx, causedBy := m.lookup(nil, level, name, args)
+	id = newID(m.now())
+	m.log(ctxWithID(ctx, id), level, name, code, desc, link, tail, causedBy)
+	return id
+}
@@ -0,0 +1,53 @@
+package module
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewID(t *testing.T) {
+	t0 := time.Date(2022, 11, 17, 0, 0, 0, 0, time.UTC)
+	a, b := newID(t0), newID(t0.Add(time.Millisecond))
+	if len(a) != 26 || a == b || a >= b || a[:10] != "01GJ1E5700" {
+		t.Fatalf("bad ids %s, %s", a, b)
+	}
+}
+
+func TestEmitWithID(t *testing.T) {
+	var b bytes.Buffer
+	var last *Message
+	m := NewWithOptions("module", messages, WithLogger(log.New(&b, "", 0)), WithShowID(), WithHook(func(msg *Message) *Message {
+		last = msg
+		return msg
+	}))
+
+	m.Info("test")
+	if last.ID == "" || b.String() != "[INFO ] This is a test message id="+last.ID+"\n" {
+		t.Fatalf("unexpected id %q in %q", last.ID, b.String())
+	}
+
+	b.Reset()
+	id := m.EmitWithID(Error, "test3")
+	if last.ID != id || b.String() != "[ERR  ] Some more tests over here. id="+id+"\n" {
+		t.Fatalf("unexpected log output %q for id %s", b.String(), id)
+	}
+
+	// internal messages get their own ID
+	b.Reset()
+	m.AddHook(0, func(msg *Message) *Message {
+		panic("boom")
+	})
+	id = m.EmitWithID(Error, "test3")
+	if last.ID != id || strings.Count(b.String(), "id="+id) != 1 || strings.Count(b.String(), " id=") != 2 {
+		t.Fatalf("unexpected log output %q for id %s", b.String(), id)
+	}
+
+	m.Throttle(ThrottleOptions{Rate: 1})
+	m.EmitWithID(Error, "test3")
+	if id := m.EmitWithID(Error, "test3"); id != "" {
+		t.Fatalf("throttled message has id %s", id)
+	}
+}
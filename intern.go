@@ -0,0 +1,42 @@
+package module
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// maxInterned limits the number of strings kept by intern, so keys and names made up
+// from unbounded input do not grow the table forever.
+const maxInterned = 4096
+
+var (
+	interned  sync.Map // string -> string
+	ninterned int32
+)
+
+// intern returns a shared copy of s, so messages built from the same names and keys
+// reference the same memory instead of keeping their own copies alive.
+func intern(s string) string {
+	if s == "" {
+		return s
+	}
+	if v, ok := interned.Load(s); ok {
+		return v.(string)
+	}
+	if atomic.LoadInt32(&ninterned) >= maxInterned {
+		return s
+	}
+	v, loaded := interned.LoadOrStore(s, s)
+	if !loaded {
+		atomic.AddInt32(&ninterned, 1)
+	}
+	return v.(string)
+}
+
+// internName returns the name of the catalog entry for name, or the interned name if there is none.
+func (m *Module) internName(name string) string {
+	if e, ok := m.catalog.lookup(name); ok {
+		return e.name
+	}
+	return intern(name)
+}
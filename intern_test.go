@@ -0,0 +1,44 @@
+package module
+
+import (
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func sameString(a, b string) bool {
+	return (*reflect.StringHeader)(unsafe.Pointer(&a)).Data == (*reflect.StringHeader)(unsafe.Pointer(&b)).Data
+}
+
+func TestIntern(t *testing.T) {
+	a := intern(strings.Repeat("k", 3))
+	b := intern(strings.Repeat("k", 3))
+	if a != "kkk" || !sameString(a, b) {
+		t.Fatal("interned strings are not shared")
+	}
+}
+
+func TestInternMessage(t *testing.T) {
+	var names, keys []string
+	_, _, m := New("module", messages)
+	m.Logger = log.New(io.Discard, "", 0)
+	m.Hook = func(msg *Message) *Message {
+		names = append(names, msg.Name)
+		for key := range msg.Data {
+			keys = append(keys, key)
+		}
+		return msg
+	}
+	for i := 0; i < 2; i++ {
+		m.Info(string([]byte("test")), string([]byte("ip")), "10.0.0.1")
+	}
+	if len(names) != 2 || !sameString(names[0], names[1]) {
+		t.Fatalf("names are not shared: %q", names)
+	}
+	if len(keys) != 2 || !sameString(keys[0], keys[1]) {
+		t.Fatalf("keys are not shared: %q", keys)
+	}
+}
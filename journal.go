@@ -0,0 +1,148 @@
+package module
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// A Journal is a FileSink that keeps messages locally to replay them later,
+// e.g. into a log backend that was added after an incident started:
+//
+//	j, err := module.OpenJournal("/var/lib/app/journal", module.FileSinkOptions{MaxSize: 64 << 20, Compress: module.Zstd})
+//	m.Sinks = append(m.Sinks, j)
+//	...
+//	n, err := j.Replay(incidentStart, newBackendSink)
+type Journal struct {
+	*FileSink
+}
+
+func OpenJournal(path string, opts FileSinkOptions) (*Journal, error) {
+	s, err := OpenFileSinkWithOptions(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{s}, nil
+}
+
+// Replay writes the messages of the journal since the given time (all for the zero time) to sink,
+// see ReplayJournal. Messages written during the replay may or may not be included.
+func (j *Journal) Replay(since time.Time, sink Sink) (n int, err error) {
+	return ReplayJournal(j.path, since, sink)
+}
+
+// ReplayJournal writes the messages of the journal at path since the given time to sink, oldest first:
+// the rotated files, compressed or not, followed by the current file. To feed a Hook, wrap it in a SinkFunc.
+// It stops at the first error of sink and returns the number of messages written.
+func ReplayJournal(path string, since time.Time, sink Sink) (n int, err error) {
+	files, err := rotatedFiles(path)
+	if err != nil {
+		return 0, err
+	}
+	for _, file := range append(files, path) {
+		k, err := replayFile(file, since, sink)
+		n += k
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// rotatedFiles returns the rotated files of the FileSink at path, oldest first.
+func rotatedFiles(path string) ([]string, error) {
+	dir, base := filepath.Split(path)
+	entries, err := os.ReadDir(filepath.Clean(dir + "."))
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		// path.<UTC time>[.gz|.zst], which sorts by age
+		if e.IsDir() || !strings.HasPrefix(name, base+".") || len(name) == len(base)+1 || !isDigit(name[len(base)+1]) {
+			continue
+		}
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	rotated := files[:0]
+	for i, name := range files {
+		// a file that is being compressed exists twice for a moment
+		if i+1 < len(files) && (files[i+1] == name+Gzip.ext() || files[i+1] == name+Zstd.ext()) {
+			continue
+		}
+		rotated = append(rotated, filepath.Join(dir, name))
+	}
+	return rotated, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func replayFile(path string, since time.Time, sink Sink) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+	return ReplayReader(f, since, sink)
+}
+
+// ReplayReader writes the messages of a journal file, optionally compressed with gzip or zstd,
+// since the given time to sink. A truncated last line, as of a crash or a file that is still written, is skipped.
+func ReplayReader(r io.Reader, since time.Time, sink Sink) (n int, err error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	var rd io.Reader = br
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return 0, err
+		}
+		defer gz.Close()
+		rd = gz
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return 0, err
+		}
+		defer zr.Close()
+		rd = zr
+	}
+	lines := bufio.NewReader(rd)
+	for {
+		line, err := lines.ReadBytes('\n')
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		var msg Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return n, err
+		}
+		if msg.Time.Before(since) {
+			continue
+		}
+		if err := sink.Write(&msg); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
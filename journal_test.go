@@ -0,0 +1,66 @@
+package module
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "journal")
+	clock := NewManualClock(time.Date(2022, 11, 17, 11, 49, 4, 0, time.UTC))
+	j, err := OpenJournal(path, FileSinkOptions{Compress: Gzip, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, e, m := New("module", messages)
+	m.Logger = log.New(io.Discard, "", 0)
+	m.Clock = clock
+	m.Sinks = []Sink{j}
+
+	m.Info("test", "A", 1)
+	clock.Advance(time.Minute)
+	if err := j.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	m.Warn("test", e("test2"), "A", 2)
+	clock.Advance(time.Minute)
+	m.Err("test", "A", 3)
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// a line cut off by a crash
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"schema_version":1,"time":"2022-11`)
+	f.Close()
+	os.WriteFile(path+".lock", nil, 0o644)
+
+	var msgs []*Message
+	n, err := ReplayJournal(path, time.Time{}, SinkFunc(func(msg *Message) error {
+		msgs = append(msgs, msg)
+		return nil
+	}))
+	if err != nil || n != 3 || len(msgs) != 3 {
+		t.Fatalf("replayed %d messages: %v", n, err)
+	}
+	for i, msg := range msgs {
+		if msg.Name != "test" || msg.Data["A"] != float64(i+1) || msg.Seq != uint64(i+1) {
+			t.Fatalf("unexpected message %d: %+v", i, msg)
+		}
+	}
+	if msgs[1].Level != Warn || msgs[1].CausedBy == nil || msgs[1].CausedBy.Error() != "234 test2 This is a another test message" {
+		t.Fatalf("unexpected message %+v", msgs[1])
+	}
+
+	n, err = ReplayJournal(path, clock.Now().Add(-30*time.Second), SinkFunc(func(msg *Message) error { return nil }))
+	if err != nil || n != 1 {
+		t.Fatalf("replayed %d messages since: %v", n, err)
+	}
+}
@@ -0,0 +1,223 @@
+package module
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/halliday/go-errors"
+)
+
+// SchemaVersion is the version of the JSON encoding produced by Message.MarshalJSON.
+//
+// Version 1 looks like:
+//
+//	{
+//	  "schema_version": 1,
+//	  "time": "2022-11-17T11:49:04.123456789Z",  // RFC 3339 (ISO 8601), UTC
+//	  "module": "auth",                          // Module.Name
+//	  "seq": 42,                                 // sequence number of the module, omitted if 0
+//	  "epoch": "lp4kzq-1a2b3c4d",                // see Epoch, omitted if empty
+//	  "id": "01GJ3Q2V4R8N6YH3T2KXW5Z9CD",        // unique ULID of the message
+//	  "level": "warn",                           // trace, debug, none, info, warn or error
+//	  "name": "login_failed",                    // catalog name, omitted for Print/Printf
+//	  "code": 1001,                              // omitted if 0
+//	  "desc": "Login failed for user bob",
+//	  "link": "https://...",                     // omitted if empty
+//	  "data": {"ip": "10.0.0.1"},                // omitted if empty
+//	  "caller": "auth/login.go:42",              // with Module.Caller, omitted if empty
+//	  "frames": [                                // caller or stack, see Message.Frames, omitted if empty
+//	    {"function": "main.login", "file": "auth/login.go", "line": 42}
+//	  ],
+//	  "caused_by": [                             // omitted if empty, outermost cause first
+//	    {"name": "db_timeout", "code": 1500, "desc": "...", "link": "...", "error": "...",
+//	     "joined": [[...], [...]]}                 // causes of each joined error (errors.Join), omitted if none
+//	  ],
+//	  "sig": "9f86d0..."                         // HMAC, see Signer, always last, omitted if empty
+//	}
+//
+// New fields may be added within a version; fields are never removed or
+// changed in meaning without incrementing SchemaVersion. Consumers should ignore
+// unknown fields; ConvertJSON converts messages between versions.
+const SchemaVersion = 1
+
+type jsonMessage struct {
+	SchemaVersion int                    `json:"schema_version"`
+	Time          string                 `json:"time"`
+	Module        string                 `json:"module"`
+	Seq           uint64                 `json:"seq,omitempty"`
+	Epoch         string                 `json:"epoch,omitempty"`
+	ID            string                 `json:"id,omitempty"`
+	Level         string                 `json:"level"`
+	Name          string                 `json:"name,omitempty"`
+	Code          int                    `json:"code,omitempty"`
+	Desc          string                 `json:"desc"`
+	Link          string                 `json:"link,omitempty"`
+	Data          map[string]interface{} `json:"data,omitempty"`
+	Caller        string                 `json:"caller,omitempty"`
+	Frames        []Frame                `json:"frames,omitempty"`
+	CausedBy      []jsonCause            `json:"caused_by,omitempty"`
+	Sig           string                 `json:"sig,omitempty"`
+}
+
+type jsonCause struct {
+	Name   string        `json:"name,omitempty"`
+	Code   int           `json:"code,omitempty"`
+	Desc   string        `json:"desc,omitempty"`
+	Link   string        `json:"link,omitempty"`
+	Error  string        `json:"error"`
+	Joined [][]jsonCause `json:"joined,omitempty"`
+}
+
+func (msg *Message) MarshalJSON() ([]byte, error) {
+	j := jsonMessage{
+		SchemaVersion: SchemaVersion,
+		Time:          msg.Time.UTC().Format(time.RFC3339Nano),
+		Module:        msg.Module,
+		Seq:           msg.Seq,
+		Epoch:         msg.Epoch,
+		ID:            msg.ID,
+		Level:         msg.Level.String(),
+		Data:          msg.Data,
+		Caller:        msg.Caller,
+		Frames:        msg.Frames,
+		Sig:           msg.Sig,
+	}
+	if msg.RichError != nil {
+		j.Name = msg.Name
+		j.Code = msg.Code
+		j.Desc = msg.Desc
+		j.Link = msg.Link
+		j.CausedBy = causeChain(msg.CausedBy)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes the output of MarshalJSON, e.g. to replay messages of a Journal.
+// Messages of older schema versions are converted with ConvertJSON.
+// The causes become a chain of RichErrors; causes that had no name, code or description keep their error text.
+func (msg *Message) UnmarshalJSON(data []byte) error {
+	var j jsonMessage
+	err := json.Unmarshal(data, &j)
+	if j.SchemaVersion < SchemaVersion {
+		// older versions, whose fields may not even decode, are converted first
+		if data, err = ConvertJSON(data, SchemaVersion); err != nil {
+			return err
+		}
+		j = jsonMessage{}
+		err = json.Unmarshal(data, &j)
+	}
+	if err != nil {
+		return err
+	}
+	if j.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("unsupported schema_version %d", j.SchemaVersion)
+	}
+	t, err := time.Parse(time.RFC3339Nano, j.Time)
+	if err != nil {
+		return err
+	}
+	level, err := ParseLevel(j.Level)
+	if err != nil {
+		return err
+	}
+	*msg = Message{
+		Module: j.Module,
+		Level:  level,
+		Time:   t,
+		Seq:    j.Seq,
+		Epoch:  j.Epoch,
+		ID:     j.ID,
+		RichError: &errors.RichError{
+			Name:     j.Name,
+			Code:     j.Code,
+			Desc:     j.Desc,
+			Link:     j.Link,
+			CausedBy: decodeCauses(j.CausedBy),
+		},
+		Data:   j.Data,
+		Caller: j.Caller,
+		Frames: j.Frames,
+		Sig:    j.Sig,
+	}
+	if j.Data != nil {
+		msg.RichError.Data = j.Data
+	}
+	return nil
+}
+
+// decodedCause is a cause decoded from JSON that was not a RichError.
+type decodedCause struct {
+	text  string
+	cause error
+}
+
+func (e *decodedCause) Error() string { return e.text }
+func (e *decodedCause) Unwrap() error { return e.cause }
+
+// decodedJoin is a joined error decoded from JSON.
+type decodedJoin struct {
+	text string
+	errs []error
+}
+
+func (e *decodedJoin) Error() string   { return e.text }
+func (e *decodedJoin) Unwrap() []error { return e.errs }
+
+func decodeCauses(chain []jsonCause) error {
+	if len(chain) == 0 {
+		return nil
+	}
+	c := chain[0]
+	var next error
+	if c.Joined != nil {
+		join := &decodedJoin{text: c.Error}
+		for _, branch := range c.Joined {
+			join.errs = append(join.errs, decodeCauses(branch))
+		}
+		if c.Name == "" && c.Code == 0 && c.Desc == "" {
+			return join
+		}
+		next = join
+	} else {
+		next = decodeCauses(chain[1:])
+	}
+	if c.Name == "" && c.Code == 0 && c.Desc == "" {
+		return &decodedCause{text: c.Error, cause: next}
+	}
+	return errors.NewRich(c.Name, c.Code, c.Desc, c.Link, nil, next)
+}
+
+func causeChain(err error) (chain []jsonCause) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		c := jsonCause{Error: err.Error()}
+		if e, ok := err.(errors.NameError); ok {
+			c.Name = e.ErrorName()
+		}
+		if e, ok := err.(errors.CodeError); ok {
+			c.Code = e.ErrorCode()
+		}
+		if e, ok := err.(errors.DescError); ok {
+			c.Desc = e.ErrorDescription()
+		}
+		if e, ok := err.(errors.LinkError); ok {
+			c.Link = e.ErrorLink()
+		}
+		if errs := unwrapMulti(err); errs != nil {
+			for _, e := range errs {
+				c.Joined = append(c.Joined, causeChain(e))
+			}
+		}
+		chain = append(chain, c)
+	}
+	return chain
+}
+
+func (m *Module) writeJSON(msg *Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		m.logger().Println("[ERR  ] json: " + err.Error())
+		return
+	}
+	m.logger().Writer().Write(append(data, '\n'))
+}
@@ -0,0 +1,64 @@
+package module
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	msg := &Message{
+		Module: "module",
+		Level:  Warn,
+		Time:   time.Date(2022, 11, 17, 11, 49, 4, 0, time.FixedZone("CET", 3600)),
+		RichError: &errors.RichError{
+			Name:     "test",
+			Code:     123,
+			Desc:     "This is a test message",
+			CausedBy: errors.NewRich("test2", 234, "This is a another test message", "", nil, nil),
+		},
+		Data: map[string]interface{}{"A": 1},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"schema_version":1,"time":"2022-11-17T10:49:04Z","module":"module","level":"warn","name":"test","code":123,"desc":"This is a test message","data":{"A":1},"caused_by":[{"name":"test2","code":234,"desc":"This is a another test message","error":"234 test2 This is a another test message"}]}`
+	if string(data) != expected {
+		t.Fatalf("unexpected json:\n%s", data)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	_, e, _ := New("module", messages)
+	msg := &Message{
+		Module:    "module",
+		Level:     Error,
+		Time:      time.Date(2022, 11, 17, 11, 49, 4, 5, time.UTC),
+		Seq:       7,
+		ID:        "01GJ3Q2V4R8N6YH3T2KXW5Z9CD",
+		RichError: &errors.RichError{Name: "test", Code: 123, Desc: "This is a test message", CausedBy: e("test4", joinError{e("test2"), errors.New("eof")})},
+		Data:      map[string]interface{}{"A": "x"},
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := json.Marshal(&decoded)
+	if string(again) != string(data) {
+		t.Fatalf("round trip changed the message:\n%s\n%s", data, again)
+	}
+	if !decoded.Time.Equal(msg.Time) || decoded.Level != Error || decoded.Data["A"] != "x" || NamedError(decoded.CausedBy) == nil {
+		t.Fatalf("unexpected message %+v", decoded)
+	}
+	if err := json.Unmarshal([]byte(`{"schema_version":2}`), &decoded); err == nil {
+		t.Fatal("unknown schema version was accepted")
+	}
+}
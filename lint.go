@@ -0,0 +1,121 @@
+package module
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is a problem found in a catalog by Lint.
+type Diagnostic struct {
+	// File is the name of the catalog file, set by LintFiles.
+	File string
+	Line int
+	Name string
+	Msg  string
+}
+
+func (d Diagnostic) String() string {
+	pos := strconv.Itoa(d.Line)
+	if d.File != "" {
+		pos = d.File + ":" + pos
+	}
+	if d.Name == "" {
+		return fmt.Sprintf("%s: %s", pos, d.Msg)
+	}
+	return fmt.Sprintf("%s: %q: %s", pos, d.Name, d.Msg)
+}
+
+type LintOptions struct {
+	// RequireLinks reports entries without a link column.
+	RequireLinks bool
+}
+
+// A LintFile is a catalog file for LintFiles.
+type LintFile struct {
+	Name string
+	Src  string
+}
+
+// Lint checks a catalog for bad lines and codes, duplicate names and codes,
+// bad fmt verbs and arguments skipped by explicit indexes in descriptions,
+// and unused columns.
+// Unlike New, Lint does not stop at the first problem.
+func Lint(src string, opts LintOptions) []Diagnostic {
+	return LintFiles([]LintFile{{Src: src}}, opts)
+}
+
+// LintFiles checks the files of one catalog like Lint, and reports names and codes
+// defined in more than one of them, as NewFS would. Lint the files of each locale separately.
+func LintFiles(files []LintFile, opts LintOptions) (diags []Diagnostic) {
+	type def struct {
+		file string
+		line int
+		name string
+	}
+	names := make(map[string]def)
+	codes := make(map[int]def)
+	for _, file := range files {
+		report := func(line int, name string, format string, args ...interface{}) {
+			diags = append(diags, Diagnostic{file.Name, line, name, fmt.Sprintf(format, args...)})
+		}
+		where := func(d def) string {
+			if d.file == file.Name {
+				return "line " + strconv.Itoa(d.line)
+			}
+			return d.file + ":" + strconv.Itoa(d.line)
+		}
+
+		for n, line := range strings.Split(file.Src, "\n") {
+			n++
+			if len(line) > 0 && line[0] == '#' {
+				continue
+			}
+			fields := strings.Split(line, ";")
+			if len(fields) == 1 {
+				continue
+			}
+			name := strings.TrimSpace(fields[0])
+			if name == "" {
+				report(n, name, "empty name")
+			}
+			if len(fields) == 2 {
+				report(n, name, "bad line: missing description")
+				continue
+			}
+			if first, ok := names[name]; ok {
+				report(n, name, "duplicate name, first defined on %s", where(first))
+			} else {
+				names[name] = def{file.Name, n, name}
+			}
+			code, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+			if err != nil {
+				report(n, name, "bad code %q", strings.TrimSpace(fields[1]))
+			} else if code != 0 {
+				if other, ok := codes[code]; ok && other.name != name {
+					if other.file == file.Name {
+						report(n, name, "duplicate code %d, already used by %q", code, other.name)
+					} else {
+						report(n, name, "duplicate code %d, already used by %q on %s", code, other.name, where(other))
+					}
+				} else if !ok {
+					codes[code] = def{file.Name, n, name}
+				}
+			}
+			if verbs, nargs, bad := scanPattern(fields[2]); bad != "" {
+				report(n, name, "bad fmt verb %q", bad)
+			} else {
+				for _, i := range unusedArgs(verbs, nargs) {
+					report(n, name, "argument %d is not used", i)
+				}
+			}
+			if len(fields) > 4 {
+				report(n, name, "%d unused column(s)", len(fields)-4)
+			}
+			if opts.RequireLinks && (len(fields) < 4 || strings.TrimSpace(fields[3]) == "") {
+				report(n, name, "missing link")
+			}
+		}
+	}
+	return diags
+}
@@ -0,0 +1,52 @@
+package module
+
+import "testing"
+
+func TestLint(t *testing.T) {
+	if diags := Lint(messages, LintOptions{}); len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	src := "# comment\na;1;A %d\nb;x;B\na;2;A\nc;1;C %y\nd;3;D;link;extra\ne\nf;4\ng;5;%[3]s %[1]s\n"
+	expected := []string{
+		`3: "b": bad code "x"`,
+		`4: "a": duplicate name, first defined on line 2`,
+		`5: "c": duplicate code 1, already used by "a"`,
+		`5: "c": bad fmt verb "%y"`,
+		`6: "d": 1 unused column(s)`,
+		`8: "f": bad line: missing description`,
+		`9: "g": argument 2 is not used`,
+	}
+	diags := Lint(src, LintOptions{})
+	if len(diags) != len(expected) {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	for i, d := range diags {
+		if d.String() != expected[i] {
+			t.Fatalf("unexpected diagnostic %q, expected %q", d, expected[i])
+		}
+	}
+
+	if diags := Lint("a;1;A\nb;2;B;http://b\n", LintOptions{RequireLinks: true}); len(diags) != 1 || diags[0].Line != 1 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+}
+
+func TestLintFiles(t *testing.T) {
+	diags := LintFiles([]LintFile{
+		{"a.csv", "a;1;A\nb;2;B\n"},
+		{"b.csv", "c;3;C\na;4;A\nd;2;D\n"},
+	}, LintOptions{})
+	expected := []string{
+		`b.csv:2: "a": duplicate name, first defined on a.csv:1`,
+		`b.csv:3: "d": duplicate code 2, already used by "b" on a.csv:2`,
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	for i, d := range diags {
+		if d.String() != expected[i] {
+			t.Fatalf("unexpected diagnostic %q, expected %q", d, expected[i])
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package module
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/halliday/go-errors"
+)
+
+// AddLocale adds a translation of the catalog for lang, e.g. "de" or "pt-BR".
+// messages has the format of the base catalog; entries are matched by name and
+// may reorder arguments with explicit indexes like %[2]s.
+// Errors created by NewError keep their format arguments in Data as "format_args",
+// so that Translate can render them again.
+func (m *Module) AddLocale(lang string, messages string) {
+	c, err := parseCatalog(messages)
+	if err != nil {
+		panic(fmt.Sprintf("module %q: locale %q: %v", m.Name, lang, err))
+	}
+	m.addLocale(lang, c)
+}
+
+func (m *Module) addLocale(lang string, c *catalog) {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+	if m.locales == nil {
+		m.locales = make(map[string]*catalog)
+	}
+	m.locales[lang] = c
+}
+
+// locale returns the catalog for lang, falling back from "pt-BR" to "pt".
+func (m *Module) locale(lang string) (*catalog, bool) {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	if c, ok := m.locales[lang]; ok {
+		return c, true
+	}
+	if i := strings.IndexByte(lang, '-'); i != -1 {
+		c, ok := m.locales[lang[:i]]
+		return c, ok
+	}
+	return nil, false
+}
+
+// KeyFormatArgs holds the format arguments of the errors of NewError, for Translate.
+// Report leaves it out of the logged Data.
+const KeyFormatArgs = "format_args"
+
+// Translate renders the description of the first named error in the chain of err
+// with the catalog of lang, using its name and the "format_args" in its Data. This works for errors
+// decoded with DecodeError too; numbers decoded from JSON are converted back for integer verbs.
+// It returns false if there is no such error, locale or entry, or if the stored arguments do not fit.
+func (m *Module) Translate(err error, lang string) (string, bool) {
+	c, ok := m.locale(lang)
+	if !ok {
+		return "", false
+	}
+	if err = NamedError(err); err == nil {
+		return "", false
+	}
+	return translate(c, err.(errors.NameError).ErrorName(), err)
+}
+
+func translate(c *catalog, name string, err error) (string, bool) {
+	e, ok := c.match(name)
+	if !ok {
+		return "", false
+	}
+	if e.nargs == 0 {
+		return e.desc, true
+	}
+	var args []interface{}
+	if d, ok := err.(errors.DataError); ok {
+		if data, ok := d.ErrorData().(map[string]interface{}); ok {
+			args, _ = data[KeyFormatArgs].([]interface{})
+		}
+	}
+	if len(args) < e.nargs {
+		return "", false
+	}
+	args = append([]interface{}(nil), args[:e.nargs]...)
+	for _, v := range e.verbs {
+		if f, ok := args[v.arg].(float64); ok && strings.IndexByte("bcdoxXU", v.c) != -1 && f == math.Trunc(f) {
+			args[v.arg] = int64(f)
+		}
+	}
+	if checkArgs(e.verbs, args) != "" {
+		return "", false
+	}
+	return fmt.Sprintf(e.desc, args...), true
+}
+
+type exportedEntry struct {
+	Code        int    `json:"code,omitempty"`
+	Description string `json:"description"`
+	Link        string `json:"link,omitempty"`
+}
+
+// ExportCatalog returns the catalog of locale as JSON object of name to code, description and link,
+// for frontends that render messages themselves. The empty locale exports the base catalog.
+// Descriptions keep their fmt verbs; links missing in the locale are taken from the base catalog.
+// Names are sorted, so the output is stable.
+func (m *Module) ExportCatalog(locale string) ([]byte, error) {
+	return m.exportCatalog(locale, nil)
+}
+
+// exportCatalog exports the entries below one of prefixes, or all entries if there are none.
+func (m *Module) exportCatalog(locale string, prefixes []string) ([]byte, error) {
+	c := m.catalog
+	if locale != "" {
+		var ok bool
+		if c, ok = m.locale(locale); !ok {
+			return nil, fmt.Errorf("module %q: unknown locale %q", m.Name, locale)
+		}
+	}
+	if c == nil {
+		c = new(catalog)
+	}
+	export := make(map[string]exportedEntry, len(c.index))
+	for name, i := range c.index {
+		if !hasAnyPrefix(name, prefixes) {
+			continue
+		}
+		e := &c.entries[i]
+		x := exportedEntry{Code: e.code, Description: e.desc, Link: e.link}
+		if x.Link == "" {
+			if base, ok := m.catalog.lookup(name); ok {
+				x.Link = base.link
+			}
+		}
+		export[name] = x
+	}
+	return json.Marshal(export)
+}
+
+func hasAnyPrefix(name string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/halliday/go-errors"
+)
+
+func TestTranslate(t *testing.T) {
+	var b bytes.Buffer
+	m := NewWithOptions("module", "login_failed;1;user %s failed to log in after %d attempts\nlocked;2;account locked\n",
+		WithLogger(log.New(&b, "", 0)),
+		WithUnknownPolicy(UnknownFallback),
+	)
+	// created before the locale is added
+	err := m.NewError("login_failed", "bob", 3, "ip", "10.0.0.1")
+	m.AddLocale("de", "login_failed;1;nach %[2]d Versuchen konnte sich %[1]s nicht anmelden\nlocked;2;Konto gesperrt\n")
+
+	data, _ := json.Marshal(EncodeError(err))
+	var e EncodedError
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{err, DecodeError(&e)} {
+		for lang, expected := range map[string]string{
+			"de":    "nach 3 Versuchen konnte sich bob nicht anmelden",
+			"de-AT": "nach 3 Versuchen konnte sich bob nicht anmelden",
+		} {
+			if desc, ok := m.Translate(err, lang); !ok || desc != expected {
+				t.Fatalf("Translate(%q) = %q, %v, expected %q", lang, desc, ok, expected)
+			}
+		}
+	}
+	if _, ok := err.(*errors.RichError); !ok {
+		t.Fatalf("unexpected error type %T", err)
+	}
+	m.Report(err)
+	if b.String() != "[ERR  ] user bob failed to log in after 3 attempts ip=10.0.0.1\n" {
+		t.Fatalf("unexpected report %q", b.String())
+	}
+	if _, ok := m.Translate(err, "fr"); ok {
+		t.Fatal("translated to missing locale")
+	}
+
+	wrapped := fmt.Errorf("request failed: %w", m.NewError("locked"))
+	if desc, ok := m.Translate(wrapped, "de"); !ok || desc != "Konto gesperrt" {
+		t.Fatalf("Translate(wrapped) = %q, %v", desc, ok)
+	}
+	if _, ok := m.Translate(m.NewError("other"), "de"); ok {
+		t.Fatal("translated entry missing from locale")
+	}
+	if _, ok := m.Translate(m.NewError("other", "a", 1), "de"); ok {
+		t.Fatal("translated unknown message with args")
+	}
+}
+
+func TestExportCatalog(t *testing.T) {
+	m := NewWithOptions("module", "b;2;B %s;https://example.com/b\na;1;A\n",
+		WithLocale("de", "b;2;B auf Deutsch %s\n"),
+	)
+	for locale, expected := range map[string]string{
+		"":   `{"a":{"code":1,"description":"A"},"b":{"code":2,"description":"B %s","link":"https://example.com/b"}}`,
+		"de": `{"b":{"code":2,"description":"B auf Deutsch %s","link":"https://example.com/b"}}`,
+	} {
+		data, err := m.ExportCatalog(locale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Fatalf("ExportCatalog(%q) = %s, expected %s", locale, data, expected)
+		}
+	}
+	if _, err := m.ExportCatalog("fr"); err == nil {
+		t.Fatal("exported unknown locale")
+	}
+}
+
+func TestAddLocaleConcurrent(t *testing.T) {
+	m := NewWithOptions("module", "locked;2;account locked\n")
+	err := m.NewError("locked")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			m.AddLocale(fmt.Sprint("l", i), "locked;2;gesperrt\n")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		m.Translate(err, "de")
+	}
+	<-done
+	if desc, ok := m.Translate(err, "l99"); !ok || desc != "gesperrt" {
+		t.Fatalf("Translate = %q, %v", desc, ok)
+	}
+}
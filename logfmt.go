@@ -0,0 +1,77 @@
+package module
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MarshalLogfmt returns msg as one logfmt line (without newline), like:
+//
+//	time=2022-11-17T11:49:04.123Z level=warn module=auth name=login_failed code=1001 msg="Login failed for user bob" user=bob id=01GJ3Q2V4R8N6YH3T2KXW5Z9CD
+//
+// Data follows in the order of Message.Keys, then caller and the error of the causes.
+func (msg *Message) MarshalLogfmt() []byte {
+	b := make([]byte, 0, 256)
+	b = append(b, "time="...)
+	b = msg.Time.UTC().AppendFormat(b, time.RFC3339Nano)
+	b = append(b, " level="...)
+	b = append(b, msg.Level.String()...)
+	b = appendLogfmt(b, "module", msg.Module)
+	if msg.RichError != nil {
+		if msg.Name != "" {
+			b = appendLogfmt(b, "name", msg.Name)
+		}
+		if msg.Code != 0 {
+			b = append(b, " code="...)
+			b = strconv.AppendInt(b, int64(msg.Code), 10)
+		}
+		b = appendLogfmt(b, "msg", msg.Desc)
+	}
+	for _, key := range msg.Keys() {
+		var value string
+		switch v := msg.Data[key].(type) {
+		case string:
+			value = v
+		case time.Time:
+			value = v.Format(time.RFC3339Nano)
+		default:
+			value = fmt.Sprint(v)
+		}
+		b = appendLogfmt(b, key, value)
+	}
+	if msg.ID != "" {
+		b = appendLogfmt(b, "id", msg.ID)
+	}
+	if msg.Caller != "" {
+		b = appendLogfmt(b, "caller", msg.Caller)
+	}
+	if msg.RichError != nil && msg.CausedBy != nil {
+		b = appendLogfmt(b, "error", msg.CausedBy.Error())
+	}
+	return b
+}
+
+// appendLogfmt appends " key=value". Characters of the key that logfmt does not allow become '_',
+// the value is quoted if it is empty or contains spaces, '=', quotes or control characters.
+func appendLogfmt(b []byte, key, value string) []byte {
+	b = append(b, ' ')
+	if key == "" {
+		key = "_"
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			r = '_'
+		}
+		b = utf8.AppendRune(b, r)
+	}
+	b = append(b, '=')
+	if value == "" || strings.IndexFunc(value, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == '\\' || r == 0x7f || r == utf8.RuneError
+	}) != -1 {
+		return strconv.AppendQuote(b, value)
+	}
+	return append(b, value...)
+}
@@ -0,0 +1,29 @@
+package module
+
+import (
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+)
+
+func TestMarshalLogfmt(t *testing.T) {
+	msg := &Message{
+		Module:    "auth",
+		Level:     Warn,
+		Time:      time.Date(2022, 11, 17, 11, 49, 4, 123000000, time.FixedZone("CET", 3600)),
+		ID:        "01GJ3Q2V4R8N6YH3T2KXW5Z9CD",
+		RichError: errors.NewRich("login_failed", 1001, "Login failed for user bob", "", nil, errors.New("db down")),
+		Data:      map[string]interface{}{"user": "bob", "query": `a="b"`, "n": 3, "empty": "", "bad key": true},
+		keys:      []string{"user", "query", "n", "empty", "bad key"},
+	}
+	want := `time=2022-11-17T10:49:04.123Z level=warn module=auth name=login_failed code=1001 msg="Login failed for user bob" user=bob query="a=\"b\"" n=3 empty="" bad_key=true id=01GJ3Q2V4R8N6YH3T2KXW5Z9CD error="db down"`
+	if got := string(msg.MarshalLogfmt()); got != want {
+		t.Fatalf("MarshalLogfmt() =\n%s\nwant\n%s", got, want)
+	}
+
+	msg = &Message{Module: "auth", Level: Info, Time: time.Date(2022, 11, 17, 0, 0, 0, 0, time.UTC)}
+	if got := string(msg.MarshalLogfmt()); got != "time=2022-11-17T00:00:00Z level=info module=auth" {
+		t.Fatalf("unexpected %s", got)
+	}
+}
@@ -0,0 +1,167 @@
+// Package lokisink pushes module messages to Grafana Loki.
+//
+//	sink := lokisink.New("http://loki:3100", lokisink.Options{
+//		Labels:     map[string]string{"app": "shop"},
+//		DataLabels: []string{"tenant"},
+//	})
+//	m.Sinks = append(m.Sinks, module.NewBatchSink(sink, module.BatchOptions{}))
+//
+// Every message gets the labels "module" and "level". Its JSON encoding is the log line,
+// so LogQL can select data keys that are not labels with "| json".
+package lokisink
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	module "github.com/halliday/go-module"
+)
+
+type Options struct {
+	// Client sends the requests. Default http.DefaultClient.
+	Client *http.Client
+	// Header is added to every request, e.g. "X-Scope-OrgID" for multi-tenant Loki or authorization.
+	Header http.Header
+	// Labels are added to every stream.
+	Labels map[string]string
+	// DataLabels are data keys that become labels of the messages that have them.
+	// Only promote keys with few distinct values; every combination of labels is a stream.
+	DataLabels []string
+	// Compress compresses the requests with module.Gzip. Other compressions are not supported by Loki.
+	Compress module.Compression
+}
+
+// Sink is a module.Sink and module.BatchWriter pushing messages to the Loki push API,
+// one stream per set of labels.
+type Sink struct {
+	url  string
+	opts Options
+}
+
+func New(url string, opts Options) *Sink {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return &Sink{url: url + "/loki/api/v1/push", opts: opts}
+}
+
+type push struct {
+	Streams []*stream `json:"streams"`
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (s *Sink) Write(msg *module.Message) error {
+	return s.WriteBatch([]*module.Message{msg})
+}
+
+func (s *Sink) WriteBatch(msgs []*module.Message) error {
+	body, err := s.encode(msgs)
+	if err != nil {
+		return err
+	}
+	if body, err = module.Compress(body, s.opts.Compress, 0); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return module.Permanent(err)
+	}
+	for key, values := range s.opts.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.opts.Compress != module.NoCompression {
+		req.Header.Set("Content-Encoding", s.opts.Compress.Encoding())
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	resp.Body = io.NopCloser(io.LimitReader(resp.Body, 512))
+	return module.ResponseError(resp)
+}
+
+// encode groups msgs into streams by their labels and returns the push request.
+func (s *Sink) encode(msgs []*module.Message) ([]byte, error) {
+	// Loki rejects entries older than the newest of their stream unless out of order writes are enabled
+	msgs = append([]*module.Message(nil), msgs...)
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].Time.Before(msgs[j].Time) })
+
+	streams := make(map[string]*stream)
+	var p push
+	for _, msg := range msgs {
+		labels := s.labels(msg)
+		key := labelKey(labels)
+		st := streams[key]
+		if st == nil {
+			st = &stream{Stream: labels}
+			streams[key] = st
+			p.Streams = append(p.Streams, st)
+		}
+		line, err := json.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+		st.Values = append(st.Values, [2]string{strconv.FormatInt(msg.Time.UnixNano(), 10), string(line)})
+	}
+	return json.Marshal(&p)
+}
+
+// labels returns the labels of the stream of msg.
+func (s *Sink) labels(msg *module.Message) map[string]string {
+	labels := make(map[string]string, len(s.opts.Labels)+2+len(s.opts.DataLabels))
+	for name, value := range s.opts.Labels {
+		labels[name] = value
+	}
+	labels["module"] = msg.Module
+	labels["level"] = msg.Level.String()
+	for _, key := range s.opts.DataLabels {
+		if value, ok := msg.Data[key]; ok {
+			v, isString := value.(string)
+			if !isString {
+				b, _ := json.Marshal(value)
+				v = string(b)
+			}
+			labels[LabelName(key)] = v
+		}
+	}
+	return labels
+}
+
+// labelKey returns a string that is equal for equal label sets.
+func labelKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// LabelName returns key as a valid Prometheus label name, with invalid characters replaced by '_'.
+func LabelName(key string) string {
+	b := []byte(key)
+	for i, c := range b {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,94 @@
+package lokisink
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+func TestWriteBatch(t *testing.T) {
+	var got push
+	var tenant string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/push" || r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("unexpected request %s %q", r.URL.Path, r.Header.Get("Content-Encoding"))
+		}
+		tenant = r.Header.Get("X-Scope-OrgID")
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := json.NewDecoder(zr).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := New(srv.URL, Options{
+		Header:     http.Header{"X-Scope-Orgid": {"team-a"}},
+		Labels:     map[string]string{"app": "shop"},
+		DataLabels: []string{"tenant.id"},
+		Compress:   module.Gzip,
+	})
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC)
+	msg := func(level module.Level, name string, dt time.Duration, tenant interface{}) *module.Message {
+		m := &module.Message{Module: "auth", Level: level, Time: t0.Add(dt), RichError: errors.NewRich(name, 0, name, "", nil, nil)}
+		if tenant != nil {
+			m.Data = map[string]interface{}{"tenant.id": tenant}
+		}
+		return m
+	}
+	err := s.WriteBatch([]*module.Message{
+		msg(module.Info, "b", time.Second, "acme"),
+		msg(module.Warn, "c", 0, nil),
+		msg(module.Info, "a", 0, "acme"),
+		msg(module.Info, "d", 0, 7),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant != "team-a" {
+		t.Fatalf("unexpected tenant %q", tenant)
+	}
+
+	var streams []string
+	for _, st := range got.Streams {
+		var names []string
+		for _, v := range st.Values {
+			var m module.Message
+			if err := json.Unmarshal([]byte(v[1]), &m); err != nil {
+				t.Fatal(err)
+			}
+			if ts := fmt.Sprint(m.Time.UnixNano()); ts != v[0] {
+				t.Fatalf("timestamp %s, want %s", v[0], ts)
+			}
+			names = append(names, m.Name)
+		}
+		streams = append(streams, fmt.Sprint(st.Stream, names))
+	}
+	want := []string{
+		"map[app:shop level:warn module:auth] [c]",
+		"map[app:shop level:info module:auth tenant_id:acme] [a b]",
+		"map[app:shop level:info module:auth tenant_id:7] [d]",
+	}
+	if fmt.Sprint(streams) != fmt.Sprint(want) {
+		t.Fatalf("unexpected streams\n%v\nwant\n%v", streams, want)
+	}
+}
+
+func TestLabelName(t *testing.T) {
+	for key, want := range map[string]string{"user": "user", "tenant.id": "tenant_id", "9lives": "_lives", "a-b9": "a_b9"} {
+		if got := LabelName(key); got != want {
+			t.Fatalf("LabelName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package module
+
+import (
+	"expvar"
+	"time"
+)
+
+// Metrics receives the counters of a Module. *expvar.Map implements Metrics.
+//
+// Keys are "messages" for all messages, "level.<level>" per level and "name.<name>" per message name.
+// Every hook and sink adds "hook.<name>.calls" or "sink.<name>.calls", the total duration of the calls
+// in nanoseconds as ".ns", and the failures as ".panics" or ".errors" (which include panics).
+// Hooks are named by their function, like "main.notify.func1", sinks by their type, like "*module.FileSink".
+type Metrics interface {
+	Add(key string, delta int64)
+}
+
+// PublishExpvar publishes the counters of the Module as the expvar map "module.<Name>"
+// and sets it as Metrics. Modules with the same name share the map.
+func (m *Module) PublishExpvar() *expvar.Map {
+	name := "module." + m.Name
+	v, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		v = expvar.NewMap(name)
+	}
+	m.Metrics = v
+	return v
+}
+
+func (m *Module) count(msg *Message) {
+	if m.Metrics == nil {
+		return
+	}
+	m.Metrics.Add("messages", 1)
+	m.Metrics.Add("level."+msg.Level.String(), 1)
+	if msg.RichError != nil && msg.Name != "" {
+		m.Metrics.Add("name."+msg.Name, 1)
+	}
+}
+
+// measure counts a call of a hook or sink (kind) started at start.
+func (m *Module) measure(kind string, name string, start time.Time, failure string, failed bool) {
+	prefix := kind + "." + name + "."
+	m.Metrics.Add(prefix+"calls", 1)
+	m.Metrics.Add(prefix+"ns", int64(m.now().Sub(start)))
+	if failed {
+		m.Metrics.Add(prefix+failure, 1)
+	}
+}
@@ -0,0 +1,85 @@
+package module
+
+import (
+	"bytes"
+	"expvar"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	l, _, m := New("metrics", messages)
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+	m.PublishExpvar()
+
+	l.Info("test")
+	l.Warn("test")
+	l.Print("hello")
+
+	v := expvar.Get("module.metrics").(*expvar.Map)
+	for key, expected := range map[string]string{"messages": "3", "level.warn": "1", "level.none": "1", "name.test": "2"} {
+		if value := v.Get(key); value == nil || value.String() != expected {
+			t.Fatalf("unexpected %s: %v", key, value)
+		}
+	}
+
+	_, _, m2 := New("metrics", messages)
+	if m2.PublishExpvar() != v {
+		t.Fatal("expvar map was not shared")
+	}
+}
+
+type countMetrics map[string]int64
+
+func (c countMetrics) Add(key string, delta int64) {
+	c[key] += delta
+}
+
+func TestHookSinkMetrics(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	metrics := countMetrics{}
+	m := NewWithOptions("module", messages, WithLogger(log.New(&bytes.Buffer{}, "", 0)), WithClock(clock), WithMetrics(metrics),
+		WithHook(func(msg *Message) *Message {
+			clock.Advance(time.Millisecond)
+			return msg
+		}),
+		WithSinks(panickingSink{}),
+	)
+	m.Info("test")
+	m.Info("test")
+
+	hook := "hook." + pkgPrefix + "TestHookSinkMetrics.func1."
+	for key, expected := range map[string]int64{
+		hook + "calls":                     2,
+		hook + "ns":                        2 * int64(time.Millisecond),
+		"sink.module.panickingSink.calls":  4,
+		"sink.module.panickingSink.errors": 4,
+	} {
+		if metrics[key] != expected {
+			t.Fatalf("unexpected %s: %d, expected %d in %v", key, metrics[key], expected, metrics)
+		}
+	}
+}
+
+type namedSink string
+
+func (s namedSink) Write(msg *Message) error { return nil }
+
+func (s namedSink) Name() string { return string(s) }
+
+func TestSinkMetricNames(t *testing.T) {
+	metrics := countMetrics{}
+	w := BatchWriterFunc(func(msgs []*Message) error { return nil })
+	m := NewWithOptions("module", messages, WithLogger(log.New(&bytes.Buffer{}, "", 0)), WithMetrics(metrics),
+		WithSinks(NewBatchSink(w, BatchOptions{}), NewBatchSink(w, BatchOptions{}), NewBatchSink(w, BatchOptions{Name: "archive"}), namedSink("audit")),
+	)
+	m.Info("test")
+
+	batch := "sink.batch(" + pkgPrefix + "TestSinkMetricNames.func1)"
+	for _, key := range []string{batch + ".calls", batch + "#1.calls", "sink.archive.calls", "sink.audit.calls"} {
+		if metrics[key] != 1 {
+			t.Fatalf("unexpected %s: %d in %v", key, metrics[key], metrics)
+		}
+	}
+}
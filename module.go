@@ -4,10 +4,15 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"reflect"
-	"regexp"
+	"runtime/pprof"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/halliday/go-errors"
 )
@@ -21,26 +26,109 @@ const (
 	Error
 )
 
+// Debug and Trace messages are not part of AllLevels, so they are hidden unless enabled
+// in the Mask or for a single context with CtxVerbose. Trace is for finer detail than Debug.
+const (
+	Debug Level = 1
+	Trace Level = Error << 1
+)
+
 const AllLevels = None | Info | Warn | Error
 
+func (l Level) String() string {
+	switch l {
+	case Trace:
+		return "trace"
+	case Debug:
+		return "debug"
+	case None:
+		return "none"
+	case Info:
+		return "info"
+	case Warn:
+		return "warn"
+	case Error:
+		return "error"
+	}
+	return "level(" + strconv.Itoa(int(l)) + ")"
+}
+
+// ParseLevel returns the Level named s, the inverse of Level.String.
+func ParseLevel(s string) (Level, error) {
+	for _, l := range []Level{Trace, Debug, None, Info, Warn, Error} {
+		if l.String() == s {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown level %q", s)
+}
+
 type Hook func(m *Message) *Message
 
+// GlobalHook runs after all hooks registered with AddGlobalHook.
+//
+// Deprecated: GlobalHook is not safe for concurrent modification, use AddGlobalHook.
 var GlobalHook Hook
 
 type Message struct {
-	Module string `json:"module"`
-	Level  Level  `json:"level"`
+	Module string    `json:"module"`
+	Level  Level     `json:"level"`
+	Time   time.Time `json:"time"`
+	Seq    uint64    `json:"seq,omitempty"`
+	Epoch  string    `json:"epoch,omitempty"`
+	ID     string    `json:"id,omitempty"`
 	*errors.RichError
-	Data map[string]interface{} `json:"data"`
+	Data   map[string]interface{} `json:"data"`
+	Caller string                 `json:"caller,omitempty"`
+	// Frames is the caller with Module.Caller, or the call stack of Error messages with Module.Stack.
+	Frames []Frame `json:"frames,omitempty"`
+	Sig    string  `json:"sig,omitempty"`
+
+	keys []string
+	// origin is the module that logged the message, it reports the panics of pooled hooks and batch writers.
+	origin   *Module
+	internal bool
+	// routes are the sinks added by Route, written after the sinks of the module.
+	routes []Sink
 }
 
 func New(name string, messages string) (L Logger, E ErrorFactory, m *Module) {
-	m = new(Module)
+	m = NewWithOptions(name, messages)
+	return m, m.NewError, m
+}
+
+// NewReserved creates a Module like New that reserves the code range r, see Reserve.
+// It fails if r conflicts with the range or codes of another module.
+func NewReserved(name string, messages string, r CodeRange, opts ...Option) (*Module, error) {
+	m := newModule(name, mustParseCatalog(name, messages))
+	for _, opt := range opts {
+		opt(m)
+	}
+	if err := m.Reserve(r); err != nil {
+		return nil, err
+	}
+	if err := m.register(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func mustParseCatalog(name string, messages string) *catalog {
+	c, err := parseCatalog(messages)
+	if err != nil {
+		panic("module.New(\"" + name + "\"): " + err.Error())
+	}
+	return c
+}
+
+func newModule(name string, c *catalog) *Module {
+	m := new(Module)
 	m.Name = name
-	m.messages = messages
+	m.catalog = c
 	m.Mask = AllLevels
 	m.Logger = log.Default()
-	return m, m.NewError, m
+	m.maskSet = true
+	return m
 }
 
 type ErrorFactory func(name string, args ...interface{}) error
@@ -57,37 +145,149 @@ type Logger interface {
 	Report(err error)
 }
 
-type Module struct {
-	Name     string
-	messages string
+// DebugLogger is a Logger with the Debug and Trace levels, like Module.
+type DebugLogger interface {
+	Logger
+	Debug(name string, args ...interface{})
+	Trace(name string, args ...interface{})
+}
 
-	Mask   Level
-	Hook   Hook
-	Logger *log.Logger
+// A Module is usually created with New. The zero Module is usable too, e.g. embedded in a struct:
+// it has an empty catalog, logs all levels until a Mask is set, and writes to log.Default if the Logger is nil.
+type Module struct {
+	seq uint64 // first field for 64 bit alignment of atomic operations
+
+	Name    string
+	catalog *catalog
+	locales map[string]*catalog
+
+	// Mask, Hook and Logger may only be assigned while the Module is not in use;
+	// use SetMask, SetHook and SetLogger to change them at runtime.
+	Mask Level
+	Hook Hook
+	// NoGlobalHooks keeps the messages of the module from the hooks of AddGlobalHook and GlobalHook,
+	// e.g. for internal diagnostics of a library that the application must not see.
+	NoGlobalHooks bool
+	// Processors run in order on every message, before it is logged and passed to the hooks and sinks.
+	Processors []Processor
+	Sinks      []Sink
+	// OnSinkHealth is called when the health status of a sink changes.
+	OnSinkHealth func(h SinkHealth)
+	Signer       *Signer
+	Metrics      Metrics
+	Logger       *log.Logger
+	// Clock is used for timestamps and throttling. Default SystemClock.
+	Clock Clock
+	// Unknown is the policy for message names that are not in the catalog.
+	Unknown UnknownPolicy
+	// StrictFormat panics if an argument does not match its fmt verb, at every level. Without it, the arguments
+	// of enabled levels are checked, and a "format_mismatch" warning is logged once per message name.
+	StrictFormat bool
+	// Caller adds the file and line of the log call to every message.
+	Caller bool
+	// Stack adds the call stack to Error messages, see Message.Frames.
+	Stack bool
+	// JSON writes messages as JSON lines (see Message.MarshalJSON) to the Writer of the Logger, instead of text.
+	JSON bool
+	// ShowID adds the ID of every message to the text output. JSON and slog output always have it.
+	ShowID bool
+	// ProfileLabels sets the pprof labels "module" and "message" while hooks and sinks run,
+	// so CPU profiles attribute their cost to the messages.
+	ProfileLabels bool
 	// Stdout io.Writer
 	// Stderr io.Writer
+
+	audit     bool
+	verbosity int32
+	maskSet   bool // by New, WithMask or SetMask, so a Mask of 0 hides all messages
+
+	configMu sync.RWMutex // guards Hook, Logger, slog and locales
+	slog     *slog.Logger
+
+	maskMu      sync.RWMutex // guards Mask and the tenant and prefix masks
+	tenantMasks map[string]Level
+	prefixMasks map[string]Level
+
+	hooks  hookList
+	health sinkHealth
+	sizes  sizeStats
+
+	mismatched sync.Map // names with a "format_mismatch" warning
+
+	throttleMu sync.Mutex
+	throttle   ThrottleOptions
+	buckets    map[string]*bucket
 }
 
 func (m *Module) NewError(name string, args ...interface{}) error {
-	code, desc, link, tail, _, causedBy := m.Lookup(name, args...)
+	code, desc, link, tail, ctx, causedBy := m.Lookup(name, args...)
 	dataMap := denseArgs(nil, tail)
+	if e, ok := m.catalog.match(name); ok && e.nargs > 0 {
+		if dataMap == nil {
+			dataMap = make(map[string]interface{})
+		}
+		dataMap[KeyFormatArgs] = append([]interface{}(nil), args[:e.nargs]...)
+	}
+	if _, ok := dataMap[KeyCorrelationID]; !ok {
+		if id, ok := correlate(ctx, causedBy); ok {
+			if dataMap == nil {
+				dataMap = make(map[string]interface{})
+			}
+			dataMap[KeyCorrelationID] = id
+		}
+	}
 	var data interface{}
 	if len(dataMap) > 0 {
-		data = m
+		data = dataMap
 	}
-	return errors.NewRich(name, code, desc, link, data, causedBy)
+	return errors.NewRich(m.internName(name), code, desc, link, data, causedBy)
 }
 
 func (m *Module) Lookup(name string, args ...interface{}) (code int, desc string, link string, tail []interface{}, ctx context.Context, causedBy error) {
-	code, pattern, link := m.lookup(name)
-	desc, tail, ctx, causedBy = m.format(pattern, args)
-	return code, desc, link, tail, ctx, causedBy
+	return m.lookup(nil, Error, name, args)
+}
+
+// lookup is Lookup with an explicit ctx. If ctx is nil, it is taken from args.
+// The argument types are only checked if level is enabled for name.
+func (m *Module) lookup(ctx context.Context, level Level, name string, args []interface{}) (code int, desc string, link string, tail []interface{}, _ context.Context, causedBy error) {
+	e, ok := m.catalog.match(name)
+	if !ok {
+		return m.unknown(ctx, name, args)
+	}
+	if len(args) < e.nargs {
+		panic(fmt.Sprintf("message %q (%s): %d argument(s) for %d placeholder(s) %s", name, e.pos(), len(args), e.nargs, placeholders(e.verbs)))
+	}
+	desc, tail, ctx, causedBy = m.format(ctx, e.desc, e.nargs, args)
+	if e.nargs != 0 && m.checkFormat(ctx, level, name) {
+		if bad := checkArgs(e.verbs, args); bad != "" {
+			if m.StrictFormat {
+				panic(fmt.Sprintf("message %q (%s): %s", name, e.pos(), bad))
+			}
+			// once per name, so a mismatch in a loop does not flood the sinks
+			if _, warned := m.mismatched.LoadOrStore(name, true); !warned {
+				m.log(ctx, Warn, "format_mismatch", 0, fmt.Sprintf("message %q: %s", name, bad), "", []interface{}{"message", name}, nil)
+			}
+		}
+	}
+	return e.code, desc, e.link, tail, ctx, causedBy
 }
 
-func (m *Module) format(pattern string, args []interface{}) (desc string, tail []interface{}, ctx context.Context, causedBy error) {
-	n := numArgs(pattern)
+// checkFormat reports whether the arguments of name need to be checked: unless StrictFormat is set,
+// only for enabled levels and until a mismatch was reported for name.
+func (m *Module) checkFormat(ctx context.Context, level Level, name string) bool {
+	if m.StrictFormat {
+		return true
+	}
+	if _, warned := m.mismatched.Load(name); warned {
+		return false
+	}
+	return m.audit || level&m.mask(ctx, name) != 0
+}
+
+// format formats the first n args with pattern and splits off the context (unless given) and cause from the tail.
+func (m *Module) format(ctx context.Context, pattern string, n int, args []interface{}) (desc string, tail []interface{}, _ context.Context, causedBy error) {
 	if len(args) < n {
-		panic(fmt.Sprintf("pattern has %d args for %d placeholders", len(args), n))
+		panic(fmt.Sprintf("pattern %q: %d argument(s) for %d placeholder(s)", pattern, len(args), n))
 	}
 	if n != 0 {
 		desc = fmt.Sprintf(pattern, args[:n]...)
@@ -95,7 +295,7 @@ func (m *Module) format(pattern string, args []interface{}) (desc string, tail [
 	} else {
 		desc = pattern
 	}
-	if len(args) > 0 {
+	if ctx == nil && len(args) > 0 {
 		var ok bool
 		ctx, ok = args[0].(context.Context)
 		if ok {
@@ -118,63 +318,6 @@ func (m *Module) format(pattern string, args []interface{}) (desc string, tail [
 	return desc, args, ctx, causedBy
 }
 
-func numArgs(s string) int {
-	n := 0
-	for {
-		i := strings.IndexByte(s, '%')
-		if i == -1 {
-			return n
-		}
-		s = s[i+1:]
-		if len(s) > 0 && s[0] == '%' {
-			s = s[1:]
-		} else {
-			n++
-		}
-	}
-}
-
-func (m *Module) lookup(name string) (code int, desc string, link string) {
-	var line string
-	for entries := m.messages; entries != ""; {
-		i := strings.IndexByte(entries, '\n')
-		if i == -1 {
-			line = entries
-			entries = ""
-		} else {
-			line = entries[:i]
-			entries = entries[i+1:]
-		}
-		if len(line) > 0 && line[0] == '#' {
-			continue
-		}
-		i = strings.IndexByte(line, ';')
-		if i == -1 {
-			continue
-		}
-		if line[:i] == name {
-			line = line[i+1:]
-			i = strings.IndexByte(line, ';')
-			if i == -1 {
-				panic("Module.lookup(\"" + name + "\"): bad line")
-			}
-			code, err := strconv.Atoi(strings.TrimSpace(line[:i]))
-			if err != nil {
-				panic("Module.lookup(\"" + name + "\"): bad code")
-			}
-			line = line[i+1:]
-			i = strings.IndexByte(line, ';')
-			if i == -1 {
-				desc = strings.TrimSpace(line)
-			} else {
-				desc = strings.TrimSpace(line[:i])
-			}
-			return code, desc, ""
-		}
-	}
-	panic("Module.lookup(\"" + name + "\"): not found")
-}
-
 func (m *Module) Warn(name string, args ...interface{}) {
 	m.Log(Warn, name, args...)
 }
@@ -187,8 +330,17 @@ func (m *Module) Info(name string, args ...interface{}) {
 	m.Log(Info, name, args...)
 }
 
+func (m *Module) Debug(name string, args ...interface{}) {
+	m.Log(Debug, name, args...)
+}
+
+func (m *Module) Trace(name string, args ...interface{}) {
+	m.Log(Trace, name, args...)
+}
+
 func (m *Module) Printf(pattern string, args ...interface{}) {
-	desc, tail, ctx, causedBy := m.format(pattern, args)
+	_, n, _ := scanPattern(pattern)
+	desc, tail, ctx, causedBy := m.format(nil, pattern, n, args)
 	m.log(ctx, None, "", 0, desc, "", tail, causedBy)
 }
 
@@ -219,50 +371,30 @@ func (m *Module) Report(err error) {
 	if err == nil {
 		return
 	}
-	r := errors.Rich(err).(*errors.RichError)
-	ctx := context.Background()
-	m.log(ctx, Error, r.Name, r.Code, r.Desc, r.Link, []interface{}{r.Data}, r.CausedBy)
+	m.report(err)
 }
 
 func (m *Module) Log(level Level, name string, args ...interface{}) {
-	code, desc, link, data, ctx, causedBy := m.Lookup(name, args...)
+	if !m.allow(name) {
+		return
+	}
+	code, desc, link, data, ctx, causedBy := m.lookup(nil, level, name, args)
 	m.log(ctx, level, name, code, desc, link, data, causedBy)
 }
 
-func (m *Module) log(ctx context.Context, level Level, name string, code int, desc string, link string, tail []interface{}, causedBy error) {
-
-	var data map[string]interface{}
-
-	if level&m.Mask != 0 {
-		var b strings.Builder
-		b.Grow(8 + len(desc))
-		switch level {
-		case Error:
-			b.WriteString("[ERR  ] ")
-		case Warn:
-			b.WriteString("[WARN ] ")
-		case Info:
-			b.WriteString("[INFO ] ")
-		default:
-			b.WriteString("[     ] ")
-		}
-		b.WriteString(desc)
-		data = denseArgs(&b, tail)
-
-		for i := causedBy; i != nil; i = errors.Unwrap(i) {
-			b.WriteString(" (caused by ")
-			b.WriteString(i.Error())
-			b.WriteString(")")
-		}
+func (m *Module) log(ctx context.Context, level Level, name string, code int, desc string, link string, tail []interface{}, causedBy error) error {
 
-		m.Logger.Println(b.String())
-	} else {
-		data = denseArgs(nil, tail)
-	}
+	name = m.internName(name)
+	var keys []string
+	data := denseArgs(&keys, tail)
+	data = ctxTags(&keys, ctx, data)
+	data = ctxTrace(&keys, ctx, data)
 
+	now := m.now()
 	msg := &Message{
 		Module: m.Name,
 		Level:  level,
+		Time:   now,
 		RichError: &errors.RichError{
 			Name:     name,
 			Code:     code,
@@ -271,69 +403,337 @@ func (m *Module) log(ctx context.Context, level Level, name string, code int, de
 			CausedBy: causedBy,
 			Data:     data,
 		},
-		Data: data,
+		Data:     data,
+		keys:     keys,
+		origin:   m,
+		internal: isInternal(ctx),
+	}
+	if _, ok := data[KeyCorrelationID]; !ok {
+		if id, ok := CorrelationID(causedBy); ok {
+			msg.Set(KeyCorrelationID, id)
+		}
+	}
+	if msg.ID = ctxID(ctx); msg.ID == "" {
+		msg.ID = newID(now)
+	} else {
+		// the messages logged meanwhile with ctx, like panics of hooks, get their own ID
+		ctx = ctxWithID(ctx, "")
+	}
+	if m.Stack && msg.Level == Error || wantsStack(ctx) {
+		msg.Frames = stackOf(maxStack)
+	} else if m.Caller {
+		msg.Frames = stackOf(1)
+	}
+	if m.Caller && len(msg.Frames) > 0 {
+		msg.Caller = msg.Frames[0].String()
+	}
+	for _, p := range m.Processors {
+		out := p.Process(ctx, msg)
+		if out == nil && !m.audit {
+			return nil
+		}
+		// processors can not drop audit messages
+		if out != nil {
+			msg = out
+		}
+	}
+	msg.Seq = atomic.AddUint64(&m.seq, 1)
+	msg.Epoch = Epoch
+
+	if m.audit || msg.Level&m.mask(ctx, msg.Name) != 0 {
+		if logger := m.slogger(); logger != nil {
+			m.writeSlog(ctx, logger, msg)
+		} else if m.JSON {
+			m.writeJSON(msg)
+		} else {
+			m.writeText(msg)
+		}
+	}
+	m.count(msg)
+	if m.ProfileLabels {
+		var err error
+		pprof.Do(ctx, pprof.Labels("module", m.Name, "message", name), func(ctx context.Context) {
+			err = m.dispatch(ctx, msg)
+		})
+		return err
+	}
+	return m.dispatch(ctx, msg)
+}
+
+func (m *Module) writeText(msg *Message) {
+	var b strings.Builder
+	b.Grow(m.sizes.hint(msg.Name, 8+len(msg.Desc)))
+	switch msg.Level {
+	case Error:
+		b.WriteString("[ERR  ] ")
+	case Warn:
+		b.WriteString("[WARN ] ")
+	case Info:
+		b.WriteString("[INFO ] ")
+	case Debug:
+		b.WriteString("[DEBUG] ")
+	case Trace:
+		b.WriteString("[TRACE] ")
+	default:
+		b.WriteString("[     ] ")
+	}
+	b.WriteString(msg.Desc)
+
+	for _, key := range msg.Keys() {
+		writeKeyValue(&b, key, msg.Data[key])
+	}
+
+	if m.ShowID && msg.ID != "" {
+		b.WriteString(" id=")
+		b.WriteString(msg.ID)
+	}
+
+	if msg.Caller != "" {
+		b.WriteString(" caller=")
+		b.WriteString(msg.Caller)
+	}
+
+	if msg.CausedBy != nil {
+		b.WriteString(" (caused by ")
+		writeCause(&b, msg.CausedBy)
+		b.WriteByte(')')
+	}
+
+	m.sizes.observe(msg.Name, b.Len())
+	m.logger().Println(b.String())
+}
+
+func writeKeyValue(b *strings.Builder, key string, value interface{}) {
+	b.WriteByte(' ')
+	b.WriteString(key)
+	b.WriteByte('=')
+	writeLogValue(b, value)
+}
+
+// writeLogValue writes value like EncodeLogValue(fmt.Sprint(value)), without allocations for
+// strings, numbers, bools, durations and times. Times are written without monotonic clock reading.
+func writeLogValue(b *strings.Builder, value interface{}) {
+	var buf [64]byte
+	switch v := value.(type) {
+	case string:
+		b.WriteString(EncodeLogValue(v))
+	case int:
+		b.Write(strconv.AppendInt(buf[:0], int64(v), 10))
+	case int8:
+		b.Write(strconv.AppendInt(buf[:0], int64(v), 10))
+	case int16:
+		b.Write(strconv.AppendInt(buf[:0], int64(v), 10))
+	case int32:
+		b.Write(strconv.AppendInt(buf[:0], int64(v), 10))
+	case int64:
+		b.Write(strconv.AppendInt(buf[:0], v, 10))
+	case uint:
+		b.Write(strconv.AppendUint(buf[:0], uint64(v), 10))
+	case uint8:
+		b.Write(strconv.AppendUint(buf[:0], uint64(v), 10))
+	case uint16:
+		b.Write(strconv.AppendUint(buf[:0], uint64(v), 10))
+	case uint32:
+		b.Write(strconv.AppendUint(buf[:0], uint64(v), 10))
+	case uint64:
+		b.Write(strconv.AppendUint(buf[:0], v, 10))
+	case bool:
+		b.Write(strconv.AppendBool(buf[:0], v))
+	case float64:
+		b.Write(strconv.AppendFloat(buf[:0], v, 'g', -1, 64))
+	case float32:
+		b.Write(strconv.AppendFloat(buf[:0], float64(v), 'g', -1, 32))
+	case time.Duration:
+		b.WriteString(v.String())
+	case time.Time:
+		b.WriteString(EncodeLogValue(string(v.AppendFormat(buf[:0], "2006-01-02 15:04:05.999999999 -0700 MST"))))
+	default:
+		b.WriteString(EncodeLogValue(fmt.Sprint(value)))
+	}
+}
+
+// Keys returns the keys of Data in the order of the log arguments,
+// followed by keys added later (by processors or hooks) in sorted order.
+func (msg *Message) Keys() []string {
+	if len(msg.Data) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(msg.Data))
+	for _, key := range msg.keys {
+		if _, ok := msg.Data[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == len(msg.Data) {
+		return keys
+	}
+	n := len(keys)
+	for key := range msg.Data {
+		if !containsString(keys[:n], key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys[n:])
+	return keys
+}
+
+func containsString(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch runs the hooks and sinks for msg. Panics of hooks and sinks are recovered, see callHook and writeSink.
+func (m *Module) dispatch(ctx context.Context, msg *Message) error {
+	if !isInternal(ctx) {
+		if msg = m.runHooks(ctx, msg); msg == nil {
+			return nil
+		}
+	}
+	if m.Signer != nil {
+		return m.signWrite(ctx, msg)
+	}
+	return m.writeSinks(ctx, msg)
+}
+
+type deferredKey struct{}
+
+// signWrite signs msg and writes it to the sinks. Internal messages logged by the sinks meanwhile
+// are written after msg, as the Signer is locked.
+func (m *Module) signWrite(ctx context.Context, msg *Message) error {
+	if deferred, ok := ctx.Value(deferredKey{}).(*[]*Message); ok {
+		*deferred = append(*deferred, msg)
+		return nil
+	}
+	var deferred []*Message
+	err := m.Signer.signWrite(msg, func(msg *Message) error {
+		return m.writeSinks(context.WithValue(ctx, deferredKey{}, &deferred), msg)
+	})
+	for _, msg := range deferred {
+		m.signWrite(ctxInternal(ctx), msg)
+	}
+	return err
+}
+
+func (m *Module) runHooks(ctx context.Context, msg *Message) *Message {
+	call := func(hook Hook, msg *Message) *Message {
+		out := m.callHook(ctx, hook, msg)
+		// hooks can not drop audit messages
+		if out == nil && m.audit {
+			return msg
+		}
+		return out
 	}
 	if hook := CtxCatch(ctx); hook != nil {
-		msg = hook(msg)
+		msg = call(hook, msg)
 	}
-	if m.Hook != nil {
-		msg = m.Hook(msg)
+	if hook := m.hook(); msg != nil && hook != nil {
+		msg = call(hook, msg)
 	}
-	if GlobalHook != nil {
-		GlobalHook(msg)
+	if msg == nil {
+		return nil
+	}
+	if msg = m.hooks.run(msg, call); msg == nil {
+		return nil
 	}
+	if !m.NoGlobalHooks {
+		if msg = globalHooks.run(msg, call); msg == nil {
+			return nil
+		}
+		if GlobalHook != nil {
+			call(GlobalHook, msg)
+		}
+	}
+	return msg
 }
 
-func denseArg(b *strings.Builder, arg interface{}) (data map[string]interface{}) {
+// denseArg copies the data of a single map (or slice) argument. The keys of a map are sorted.
+func denseArg(keys *[]string, arg interface{}) (data map[string]interface{}) {
 	if tail, ok := arg.([]interface{}); ok {
-		return denseArgs(b, tail)
-	}
-	if data, ok := arg.(map[string]interface{}); ok {
-		if b != nil {
-			for key, value := range data {
-				b.WriteByte(' ')
-				b.WriteString(key)
-				b.WriteByte('=')
-				b.WriteString(EncodeLogValue(fmt.Sprint(value)))
+		return denseArgs(keys, tail)
+	}
+	if m, ok := arg.(map[string]interface{}); ok {
+		data = make(map[string]interface{}, len(m))
+		for key, value := range m {
+			data[intern(key)] = value
+		}
+		if keys != nil {
+			n := len(*keys)
+			for key := range data {
+				*keys = append(*keys, key)
 			}
+			sort.Strings((*keys)[n:])
 		}
 		return data
 	}
 	return nil
 }
 
-func denseArgs(b *strings.Builder, args []interface{}) (data map[string]interface{}) {
+// denseArgs returns the key/value pairs of args as map, appending the keys in order to keys (if not nil).
+// A slog.Attr takes the place of a pair; the attributes of groups are flattened to keys like "group.key".
+func denseArgs(keys *[]string, args []interface{}) (data map[string]interface{}) {
 	if len(args) == 0 {
 		return nil
 	}
 	if len(args) == 1 {
-		return denseArg(b, args[0])
-	}
-	if len(args)%2 != 0 {
-		panic("bad argument count, must be multiple of two")
+		if _, ok := args[0].(slog.Attr); !ok {
+			return denseArg(keys, args[0])
+		}
 	}
 	data = make(map[string]interface{}, len(args)/2)
-	for i := 0; i < len(args); i += 2 {
+	for i := 0; i < len(args); {
+		if a, ok := args[i].(slog.Attr); ok {
+			setAttr(keys, data, "", a)
+			i++
+			continue
+		}
 		key, ok := args[i].(string)
 		if !ok {
 			panic("bad argument " + strconv.Itoa(i) + ": expected string, found " + reflect.TypeOf(args[i]).Name())
 		}
-		value := args[i+1]
-		data[key] = value
-		if b != nil {
-			b.WriteByte(' ')
-			b.WriteString(key)
-			b.WriteByte('=')
-			b.WriteString(EncodeLogValue(fmt.Sprint(value)))
+		if i+1 == len(args) {
+			panic("bad argument count, must be multiple of two")
 		}
+		setArg(keys, data, intern(key), args[i+1])
+		i += 2
 	}
 	return data
 }
 
-var unsafeLogRegexp = regexp.MustCompile(`[\s"]`)
+func setArg(keys *[]string, data map[string]interface{}, key string, value interface{}) {
+	if _, dup := data[key]; !dup && keys != nil {
+		*keys = append(*keys, key)
+	}
+	data[key] = value
+}
+
+// setAttr sets the value of a, or the values of the attributes of group a, like slog.Handler:
+// attributes with empty keys are ignored, and groups with empty keys are inlined.
+func setAttr(keys *[]string, data map[string]interface{}, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			setAttr(keys, data, prefix, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	setArg(keys, data, intern(prefix+a.Key), v.Any())
+}
+
+// unsafeLogChars are the characters that make EncodeLogValue escape a value.
+const unsafeLogChars = " \t\n\f\r\""
 
 func EncodeLogValue(str string) string {
-	if !unsafeLogRegexp.MatchString(str) {
+	if !strings.ContainsAny(str, unsafeLogChars) {
 		return str
 	}
 	var b strings.Builder
@@ -3,8 +3,14 @@ package module
 import (
 	"bytes"
 	_ "embed"
+	"fmt"
 	"log"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
 )
 
 //go:embed test_messages.csv
@@ -39,7 +45,116 @@ func TestNew(t *testing.T) {
 
 	log.Print(b.String())
 
-	if b.String() != "[WARN ] This is a test message A=1 B=foo\n[ERR  ] Some more tests over here.\n" {
+	if b.String() != "[WARN ] This is a test message A=1 B=foo (caused by 234 test2 This is a another test message)\n[ERR  ] Some more tests over here.\n" {
 		t.Fatal("unexpected log output")
 	}
 }
+
+func TestWriteLogValue(t *testing.T) {
+	ts := time.Date(2022, 11, 17, 11, 49, 4, 123000000, time.UTC)
+	for _, v := range []interface{}{
+		"plain", "with space", `with "quote"`,
+		-42, int8(-8), int16(16), int32(32), int64(-64),
+		uint(42), uint8(8), uint16(16), uint32(32), uint64(64),
+		true, false,
+		1.5, 1e6, 1e-7, float32(0.1),
+		1500 * time.Millisecond,
+		ts,
+		[]int{1, 2},
+	} {
+		var b strings.Builder
+		writeLogValue(&b, v)
+		if want := EncodeLogValue(fmt.Sprint(v)); b.String() != want {
+			t.Fatalf("%T %v: got %q, want %q", v, v, b.String(), want)
+		}
+	}
+}
+
+func BenchmarkWriteKeyValue(b *testing.B) {
+	values := []interface{}{"bob", 42, int64(7), true, 3.14, 250 * time.Millisecond}
+	var s strings.Builder
+	s.Grow(256)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s.Reset()
+		s.Grow(256)
+		for _, v := range values {
+			writeKeyValue(&s, "key", v)
+		}
+	}
+}
+
+func TestEncodeLogValue(t *testing.T) {
+	for _, s := range []string{"", "plain", "a b", "tab\there", "line\nbreak", `say "hi"`, "cr\rlf\f", "vert\vtab"} {
+		got := EncodeLogValue(s)
+		want := s
+		if regexp.MustCompile(`[\s"]`).MatchString(s) {
+			want = strings.ReplaceAll(s, `"`, `\"`)
+		}
+		if got != want {
+			t.Fatalf("EncodeLogValue(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func BenchmarkEncodeLogValue(b *testing.B) {
+	b.Run("safe", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			EncodeLogValue("0123456789abcdef0123456789abcdef")
+		}
+	})
+	b.Run("unsafe", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			EncodeLogValue(`0123456789abcdef "0123456789" abcdef`)
+		}
+	})
+}
+
+func BenchmarkEncodeLogValueRegexp(b *testing.B) {
+	re := regexp.MustCompile(`[\s"]`)
+	for i := 0; i < b.N; i++ {
+		re.MatchString("0123456789abcdef0123456789abcdef")
+	}
+}
+
+func TestZeroModule(t *testing.T) {
+	var b bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	defer log.SetFlags(log.Flags())
+	log.SetOutput(&b)
+	log.SetFlags(0)
+
+	var s struct {
+		Module
+	}
+	s.Printf("hello %s", "world")
+	s.Report(errors.New("failed"))
+	s.Unknown = UnknownFallback
+	s.Info("not_in_catalog")
+	if want := "[     ] hello world\n[ERR  ] failed\n[INFO ] not_in_catalog unknown_name=not_in_catalog\n"; b.String() != want {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+	if !s.Enabled(Info) || len(s.Names("")) != 0 {
+		t.Fatal("zero module")
+	}
+	if data, err := s.ExportCatalog(""); err != nil || string(data) != "{}" {
+		t.Fatalf("export: %s %v", data, err)
+	}
+
+	b.Reset()
+	s.SetMask(0)
+	s.Printf("hidden")
+	s.Err("not_in_catalog")
+	if b.Len() != 0 || s.Enabled(Error) {
+		t.Fatalf("output with a mask of 0: %q", b.String())
+	}
+
+	_, _, m := New("module", messages)
+	m.Mask = 0
+	if m.Enabled(Error) {
+		t.Fatal("Mask 0 of New module logs")
+	}
+	if m.Clone().Enabled(Error) {
+		t.Fatal("Mask 0 of cloned module logs")
+	}
+}
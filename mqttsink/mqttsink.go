@@ -0,0 +1,143 @@
+// Package mqttsink publishes module messages to an MQTT broker, for embedded and IoT devices:
+//
+//	client := mqtt.NewClient(mqtt.NewClientOptions().AddBroker("tcp://broker:1883").SetClientID("sensor-17"))
+//	client.Connect().Wait()
+//	m.Sinks = append(m.Sinks, mqttsink.New(client, mqttsink.Options{Topic: "devices/sensor-17/{module}/{level}", QoS: 1}))
+//
+// The payload is the JSON encoding of the message, see module.SchemaVersion.
+package mqttsink
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	module "github.com/halliday/go-module"
+)
+
+// DefaultTopic is the topic template used if Options.Topic is empty.
+const DefaultTopic = "logs/{module}/{level}"
+
+// A Publisher publishes MQTT messages, like mqtt.Client.
+type Publisher interface {
+	Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token
+}
+
+type Options struct {
+	// Topic is the template of the topics. The placeholders {module}, {level}, {name}, {code} and {id}
+	// are replaced by the fields of a message and {data.key} by the data value of key.
+	// Empty values become "_". Default DefaultTopic.
+	Topic string
+	// QoS is the quality of service: 0 at most once, 1 at least once, 2 exactly once.
+	QoS byte
+	// Retained makes the broker keep the last message of every topic for new subscribers.
+	Retained bool
+	// Timeout is the longest time to wait for the broker to acknowledge a publish. Default 10s.
+	Timeout time.Duration
+}
+
+// Sink is a module.Sink and module.BatchWriter publishing messages to MQTT topics.
+type Sink struct {
+	pub  Publisher
+	opts Options
+}
+
+// New returns a Sink publishing with pub. It panics if opts.QoS is not 0, 1 or 2.
+func New(pub Publisher, opts Options) *Sink {
+	if opts.Topic == "" {
+		opts.Topic = DefaultTopic
+	}
+	if opts.QoS > 2 {
+		panic("mqttsink.New: invalid QoS " + strconv.Itoa(int(opts.QoS)))
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &Sink{pub: pub, opts: opts}
+}
+
+// Topic returns the topic of msg.
+func (s *Sink) Topic(msg *module.Message) string {
+	t := s.opts.Topic
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(t, '{')
+		j := strings.IndexByte(t[i+1:], '}')
+		if i == -1 || j == -1 {
+			b.WriteString(t)
+			return b.String()
+		}
+		b.WriteString(t[:i])
+		b.WriteString(level(placeholder(msg, t[i+1:i+1+j])))
+		t = t[i+j+2:]
+	}
+}
+
+func placeholder(msg *module.Message, name string) string {
+	switch name {
+	case "module":
+		return msg.Module
+	case "level":
+		return msg.Level.String()
+	case "id":
+		return msg.ID
+	case "name":
+		if msg.RichError != nil {
+			return msg.Name
+		}
+	case "code":
+		if msg.RichError != nil && msg.Code != 0 {
+			return strconv.Itoa(msg.Code)
+		}
+	default:
+		if key := strings.TrimPrefix(name, "data."); key != name {
+			if value, ok := msg.Data[key]; ok {
+				return fmt.Sprint(value)
+			}
+		}
+	}
+	return ""
+}
+
+// level returns s as one topic level: '/', '+' and '#' are replaced by '_', and empty becomes "_".
+func level(s string) string {
+	if s == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '+', '#', 0:
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func (s *Sink) Write(msg *module.Message) error {
+	return s.WriteBatch([]*module.Message{msg})
+}
+
+// WriteBatch publishes msgs and then waits for the acknowledgments of the broker.
+func (s *Sink) WriteBatch(msgs []*module.Message) error {
+	tokens := make([]mqtt.Token, 0, len(msgs))
+	for _, msg := range msgs {
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		tokens = append(tokens, s.pub.Publish(s.Topic(msg), s.opts.QoS, s.opts.Retained, payload))
+	}
+	deadline := time.Now().Add(s.opts.Timeout)
+	for _, token := range tokens {
+		if !token.WaitTimeout(time.Until(deadline)) {
+			return fmt.Errorf("mqtt publish not acknowledged within %s", s.opts.Timeout)
+		}
+		if err := token.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package mqttsink
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+var _ Publisher = mqtt.NewClient(mqtt.NewClientOptions())
+
+type token struct {
+	done chan struct{}
+	err  error
+}
+
+func (t *token) Wait() bool { <-t.done; return true }
+func (t *token) WaitTimeout(d time.Duration) bool {
+	select {
+	case <-t.done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+func (t *token) Done() <-chan struct{} { return t.done }
+func (t *token) Error() error          { return t.err }
+
+type publish struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  []byte
+}
+
+type publisher struct {
+	published []publish
+	err       error
+	hang      bool
+}
+
+func (p *publisher) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	p.published = append(p.published, publish{topic, qos, retained, payload.([]byte)})
+	t := &token{done: make(chan struct{}), err: p.err}
+	if !p.hang {
+		close(t.done)
+	}
+	return t
+}
+
+func TestWriteBatch(t *testing.T) {
+	p := new(publisher)
+	s := New(p, Options{QoS: 1, Retained: true})
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC)
+	err := s.WriteBatch([]*module.Message{
+		{Module: "auth", Level: module.Warn, Time: t0},
+		{Module: "sensors/temp", Level: module.Error, Time: t0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.published) != 2 || p.published[0].topic != "logs/auth/warn" || p.published[1].topic != "logs/sensors_temp/error" {
+		t.Fatalf("unexpected publishes %v", p.published)
+	}
+	if p.published[0].qos != 1 || !p.published[0].retained {
+		t.Fatalf("unexpected options %v", p.published[0])
+	}
+	var msg module.Message
+	if err := json.Unmarshal(p.published[0].payload, &msg); err != nil || msg.Module != "auth" {
+		t.Fatalf("unexpected payload %s, %v", p.published[0].payload, err)
+	}
+
+	p.err = errors.New("not connected")
+	if err := s.Write(&module.Message{Module: "auth", Level: module.Info, Time: t0}); err != p.err {
+		t.Fatalf("expected publish error, got %v", err)
+	}
+	p.err, p.hang = nil, true
+	s = New(p, Options{Timeout: time.Millisecond})
+	if err := s.Write(&module.Message{Module: "auth", Level: module.Info, Time: t0}); err == nil {
+		t.Fatal("expected timeout")
+	}
+}
+
+func TestTopic(t *testing.T) {
+	msg := &module.Message{
+		Module:    "auth",
+		Level:     module.Error,
+		ID:        "01GJ3Q2V4R8N6YH3T2KXW5Z9CD",
+		RichError: errors.NewRich("login_failed", 1001, "Login failed", "", nil, nil),
+		Data:      map[string]interface{}{"device": "sensor+17", "n": 3},
+	}
+	for template, want := range map[string]string{
+		"":                              "logs/auth/error",
+		"d/{data.device}/{name}/{code}": "d/sensor_17/login_failed/1001",
+		"{data.n}/{data.missing}/{id}":  "3/_/01GJ3Q2V4R8N6YH3T2KXW5Z9CD",
+		"{unknown}/{module":             "_/{module",
+	} {
+		if got := New(nil, Options{Topic: template}).Topic(msg); got != want {
+			t.Fatalf("Topic(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
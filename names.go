@@ -0,0 +1,68 @@
+package module
+
+import (
+	"context"
+	"strings"
+)
+
+// Message names can be hierarchical, with dots between the segments, like "auth.login.failed".
+// A prefix matches a name if it equals the name or one of its parent names ("auth", "auth.login").
+// The empty prefix matches all names.
+
+// HasPrefix reports whether name is prefix or below prefix.
+func HasPrefix(name string, prefix string) bool {
+	if prefix == "" || name == prefix {
+		return true
+	}
+	return strings.HasPrefix(name, prefix) && name[len(prefix)] == '.'
+}
+
+func parentName(name string) string {
+	i := strings.LastIndexByte(name, '.')
+	if i == -1 {
+		return ""
+	}
+	return name[:i]
+}
+
+// Names returns the catalog names below prefix, in catalog order.
+func (m *Module) Names(prefix string) []string {
+	var names []string
+	if m.catalog == nil {
+		return nil
+	}
+	for _, e := range m.catalog.entries {
+		if HasPrefix(e.name, prefix) && !containsString(names, e.name) {
+			names = append(names, e.name)
+		}
+	}
+	return names
+}
+
+// SetPrefixMask overrides the Mask for all messages below prefix. The longest matching prefix wins.
+func (m *Module) SetPrefixMask(prefix string, mask Level) {
+	m.maskMu.Lock()
+	defer m.maskMu.Unlock()
+	if m.prefixMasks == nil {
+		m.prefixMasks = make(map[string]Level)
+	}
+	m.prefixMasks[prefix] = mask
+}
+
+// ClearPrefixMask removes the override set with SetPrefixMask.
+func (m *Module) ClearPrefixMask(prefix string) {
+	m.maskMu.Lock()
+	defer m.maskMu.Unlock()
+	delete(m.prefixMasks, prefix)
+}
+
+// SamplePrefix is like Sample, but only samples messages below prefix.
+func SamplePrefix(prefix string, n int, levels Level) Processor {
+	sample := Sample(n, levels)
+	return ProcessorFunc(func(ctx context.Context, msg *Message) *Message {
+		if msg.RichError == nil || !HasPrefix(msg.Name, prefix) {
+			return msg
+		}
+		return sample.Process(ctx, msg)
+	})
+}
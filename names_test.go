@@ -0,0 +1,61 @@
+package module
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+const dottedMessages = `auth.login;0;Login
+auth.login.failed;0;Login failed
+auth.logout;0;Logout
+authz.denied;0;Denied
+db.timeout;0;Timeout
+`
+
+func TestNames(t *testing.T) {
+	var b bytes.Buffer
+	m := NewWithOptions("module", dottedMessages, WithLogger(log.New(&b, "", 0)), WithMask(Error|Warn))
+
+	if names := strings.Join(m.Names("auth"), ","); names != "auth.login,auth.login.failed,auth.logout" {
+		t.Fatalf("unexpected names %s", names)
+	}
+	if names := m.Names(""); len(names) != 5 {
+		t.Fatalf("unexpected names %v", names)
+	}
+
+	m.SetPrefixMask("auth", AllLevels)
+	m.SetPrefixMask("auth.login", Error)
+	m.Info("auth.login.failed")
+	m.Info("auth.logout")
+	m.Info("authz.denied")
+	m.Info("db.timeout")
+
+	if b.String() != "[INFO ] Logout\n" {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+}
+
+func TestWildcard(t *testing.T) {
+	var last *Message
+	m := NewWithOptions("module", "db.*;500;Database error: %v\ndb.timeout;501;Timeout\n*;1;Unknown\n",
+		WithLogger(log.New(&bytes.Buffer{}, "", 0)),
+		WithHook(func(msg *Message) *Message {
+			last = msg
+			return msg
+		}))
+
+	m.Err("db.conn.lost", "eof")
+	if last.Name != "db.conn.lost" || last.Code != 500 || last.Desc != "Database error: eof" {
+		t.Fatalf("unexpected message %+v", last.RichError)
+	}
+	m.Err("db.timeout")
+	if last.Code != 501 {
+		t.Fatalf("unexpected message %+v", last.RichError)
+	}
+	m.Err("http")
+	if last.Name != "http" || last.Code != 1 {
+		t.Fatalf("unexpected message %+v", last.RichError)
+	}
+}
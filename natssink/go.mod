@@ -0,0 +1,20 @@
+module github.com/halliday/go-module/natssink
+
+go 1.21
+
+require (
+	github.com/halliday/go-errors v0.0.0-20221117114904-701c88d594be
+	github.com/halliday/go-module v0.0.0-00010101000000-000000000000
+	github.com/nats-io/nats.go v1.31.0
+)
+
+require (
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/nats-io/nkeys v0.4.5 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	golang.org/x/crypto v0.6.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+)
+
+replace github.com/halliday/go-module => ../
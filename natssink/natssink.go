@@ -0,0 +1,93 @@
+// Package natssink publishes module messages to NATS, for log fan-out between services and live tailing:
+//
+//	nc, err := nats.Connect(nats.DefaultURL)
+//	m.Sinks = append(m.Sinks, natssink.New(nc, natssink.Options{}))
+//
+// Messages are published as JSON to subjects like "logs.auth.error", so other services can subscribe
+// to "logs.auth.>" or "logs.*.error", and "nats sub 'logs.>'" tails all modules.
+// The message ID is sent as Nats-Msg-Id, which JetStream streams use to drop duplicates.
+package natssink
+
+import (
+	"encoding/json"
+	"strings"
+
+	module "github.com/halliday/go-module"
+	"github.com/nats-io/nats.go"
+)
+
+// A Publisher publishes NATS messages, like *nats.Conn.
+// If it has a method Flush() error, like *nats.Conn, WriteBatch calls it after publishing a batch.
+type Publisher interface {
+	PublishMsg(m *nats.Msg) error
+}
+
+type Options struct {
+	// Prefix is the first token of the subjects. Default "logs".
+	Prefix string
+	// Subject returns the subject of a message, replacing the default "<Prefix>.<module>.<level>".
+	Subject func(msg *module.Message) string
+}
+
+// Sink is a module.Sink and module.BatchWriter publishing messages to NATS.
+type Sink struct {
+	pub  Publisher
+	opts Options
+}
+
+func New(pub Publisher, opts Options) *Sink {
+	if opts.Prefix == "" {
+		opts.Prefix = "logs"
+	}
+	if opts.Subject == nil {
+		prefix := opts.Prefix
+		opts.Subject = func(msg *module.Message) string {
+			return prefix + "." + Token(msg.Module) + "." + msg.Level.String()
+		}
+	}
+	return &Sink{pub: pub, opts: opts}
+}
+
+// Token returns s as a subject token: '.', '*', '>' and white space are replaced by '_'.
+func Token(s string) string {
+	if s == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func (s *Sink) Write(msg *module.Message) error {
+	return s.publish(msg)
+}
+
+func (s *Sink) WriteBatch(msgs []*module.Message) error {
+	for _, msg := range msgs {
+		if err := s.publish(msg); err != nil {
+			return err
+		}
+	}
+	if f, ok := s.pub.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func (s *Sink) publish(msg *module.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	m := nats.NewMsg(s.opts.Subject(msg))
+	m.Data = data
+	m.Header.Set("Content-Type", "application/json")
+	if msg.ID != "" {
+		m.Header.Set(nats.MsgIdHdr, msg.ID)
+	}
+	return s.pub.PublishMsg(m)
+}
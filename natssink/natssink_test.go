@@ -0,0 +1,72 @@
+package natssink
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+	"github.com/nats-io/nats.go"
+)
+
+var _ Publisher = (*nats.Conn)(nil)
+
+type publisher struct {
+	msgs    []*nats.Msg
+	flushes int
+}
+
+func (p *publisher) PublishMsg(m *nats.Msg) error {
+	p.msgs = append(p.msgs, m)
+	return nil
+}
+
+func (p *publisher) Flush() error {
+	p.flushes++
+	return nil
+}
+
+func TestWrite(t *testing.T) {
+	p := new(publisher)
+	s := New(p, Options{})
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC)
+	if err := s.Write(&module.Message{Module: "auth", Level: module.Error, Time: t0, ID: "01GJ3Q2V4R8N6YH3T2KXW5Z9CD"}); err != nil {
+		t.Fatal(err)
+	}
+	err := s.WriteBatch([]*module.Message{
+		{Module: "billing.v2", Level: module.Info, Time: t0},
+		{Module: "db", Level: module.Debug, Time: t0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var subjects []string
+	for _, m := range p.msgs {
+		subjects = append(subjects, m.Subject)
+	}
+	if len(subjects) != 3 || subjects[0] != "logs.auth.error" || subjects[1] != "logs.billing_v2.info" || subjects[2] != "logs.db.debug" {
+		t.Fatalf("unexpected subjects %v", subjects)
+	}
+	if p.flushes != 1 {
+		t.Fatalf("expected 1 flush, got %d", p.flushes)
+	}
+	m := p.msgs[0]
+	if m.Header.Get(nats.MsgIdHdr) != "01GJ3Q2V4R8N6YH3T2KXW5Z9CD" || m.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected header %v", m.Header)
+	}
+	var msg module.Message
+	if err := json.Unmarshal(m.Data, &msg); err != nil || msg.Module != "auth" || msg.Level != module.Error {
+		t.Fatalf("unexpected data %s, %v", m.Data, err)
+	}
+}
+
+func TestSubject(t *testing.T) {
+	p := new(publisher)
+	s := New(p, Options{Subject: func(msg *module.Message) string { return "audit." + Token(msg.Name) }})
+	s.Write(&module.Message{Module: "auth", Level: module.Info, Time: time.Now(), RichError: &errors.RichError{Name: "user.login"}})
+	if p.msgs[0].Subject != "audit.user_login" {
+		t.Fatalf("unexpected subject %q", p.msgs[0].Subject)
+	}
+}
@@ -0,0 +1,167 @@
+package module
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+type NDJSONSinkOptions struct {
+	// BufferSize collects lines up to BufferSize bytes before writing them, 0 writes every message at once.
+	// A line is never split: a line larger than the buffer is written on its own.
+	BufferSize int
+	// FlushInterval is the longest time a buffered line waits. Default 1s.
+	FlushInterval time.Duration
+	// Clock is used for FlushInterval. Default SystemClock.
+	Clock Clock
+}
+
+// NDJSONSink appends messages as JSON lines to a file for collectors like Vector or Fluent Bit that tail it.
+// Every write to the file is one write call of whole lines to a file opened with O_APPEND,
+// so lines of several sinks or processes writing the same file never interleave and a
+// tailing reader never sees a partial line, unless the disk is full.
+// It is a BatchWriter, and it does not rotate the file; after an external rotation call Reopen.
+type NDJSONSink struct {
+	path string
+	opts NDJSONSinkOptions
+
+	mu    sync.Mutex
+	f     *os.File
+	buf   []byte
+	timer Timer
+	err   error
+}
+
+// OpenNDJSONSink opens (or creates) the file for appending.
+func OpenNDJSONSink(path string, opts NDJSONSinkOptions) (*NDJSONSink, error) {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	opts.Clock = clockOrSystem(opts.Clock)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &NDJSONSink{path: path, opts: opts, f: f}, nil
+}
+
+func (s *NDJSONSink) Write(msg *Message) error {
+	return s.WriteBatch([]*Message{msg})
+}
+
+// WriteBatch appends the lines of msgs. If they do not fit into the buffer, the buffer is written first.
+// It returns the error of an earlier write in the background, if any.
+func (s *NDJSONSink) WriteBatch(msgs []*Message) error {
+	var lines []byte
+	for _, msg := range msgs {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, data...), '\n')
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	err := s.err
+	s.err = nil
+	if len(s.buf)+len(lines) > s.opts.BufferSize {
+		if ferr := s.flush(); err == nil {
+			err = ferr
+		}
+	}
+	if len(lines) > s.opts.BufferSize {
+		if _, werr := s.f.Write(lines); err == nil {
+			err = werr
+		}
+		return err
+	}
+	s.buf = append(s.buf, lines...)
+	if s.timer == nil {
+		s.timer = s.opts.Clock.AfterFunc(s.opts.FlushInterval, s.timeout)
+	}
+	return err
+}
+
+func (s *NDJSONSink) timeout() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timer = nil
+	if s.f != nil {
+		if err := s.flush(); err != nil && s.err == nil {
+			s.err = err
+		}
+	}
+}
+
+// flush writes the buffered lines.
+func (s *NDJSONSink) flush() error {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if len(s.buf) == 0 {
+		return nil
+	}
+	_, err := s.f.Write(s.buf)
+	s.buf = s.buf[:0]
+	return err
+}
+
+// Flush writes the buffered lines.
+func (s *NDJSONSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	return s.flush()
+}
+
+// Sync writes the buffered lines and commits the file to stable storage.
+func (s *NDJSONSink) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	if err := s.flush(); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// Reopen writes the buffered lines and opens the file again, after it was moved away by logrotate or a collector.
+func (s *NDJSONSink) Reopen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	err := s.flush()
+	f, oerr := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if oerr != nil {
+		return oerr
+	}
+	s.f.Close()
+	s.f = f
+	return err
+}
+
+// Close writes the buffered lines and closes the file.
+func (s *NDJSONSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.flush()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	s.f = nil
+	return err
+}
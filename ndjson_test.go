@@ -0,0 +1,105 @@
+package module
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNDJSONSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.ndjson")
+	clock := NewManualClock(time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC))
+	s, err := OpenNDJSONSink(path, NDJSONSinkOptions{BufferSize: 4096, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(&Message{Module: "a", Level: Info, Time: clock.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Fatalf("expected buffered line, got %s", data)
+	}
+	clock.Advance(time.Second)
+	if lines := readNDJSON(t, path); len(lines) != 1 || lines[0].Module != "a" {
+		t.Fatalf("expected flushed line, got %v", lines)
+	}
+
+	// a second sink, like another process, appends to the same file
+	other, err := OpenNDJSONSink(path, NDJSONSinkOptions{BufferSize: 512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for _, sink := range []*NDJSONSink{s, other} {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(sink *NDJSONSink, i int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					sink.Write(&Message{Module: "m" + strconv.Itoa(i), Level: Info, Time: time.Now(), Data: map[string]interface{}{"j": j, "pad": string(make([]byte, j*10))}})
+				}
+			}(sink, i)
+		}
+	}
+	wg.Wait()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := readNDJSON(t, path); len(lines) != 1+800 {
+		t.Fatalf("expected 801 lines, got %d", len(lines))
+	}
+	if err := s.Write(&Message{Module: "a", Time: time.Now()}); err != os.ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestNDJSONSinkReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "messages.ndjson")
+	s, err := OpenNDJSONSink(path, NDJSONSinkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Write(&Message{Module: "a", Level: Info, Time: time.Now()})
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	s.Write(&Message{Module: "b", Level: Info, Time: time.Now()})
+	if lines := readNDJSON(t, path+".1"); len(lines) != 1 || lines[0].Module != "a" {
+		t.Fatalf("unexpected rotated lines %v", lines)
+	}
+	if lines := readNDJSON(t, path); len(lines) != 1 || lines[0].Module != "b" {
+		t.Fatalf("unexpected lines %v", lines)
+	}
+}
+
+// readNDJSON decodes the lines of path, failing on partial or interleaved lines.
+func readNDJSON(t *testing.T, path string) (msgs []*Message) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		msg := new(Message)
+		if err := json.Unmarshal(scanner.Bytes(), msg); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
@@ -0,0 +1,157 @@
+package module
+
+import "log"
+
+// Option configures a Module created with NewWithOptions.
+type Option func(m *Module)
+
+// NewWithOptions creates a Module like New, configured by opts.
+func NewWithOptions(name string, messages string, opts ...Option) *Module {
+	m := newModule(name, mustParseCatalog(name, messages))
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.registerOrWarn()
+	return m
+}
+
+func WithMask(mask Level) Option {
+	return func(m *Module) {
+		m.Mask = mask
+		m.maskSet = true
+	}
+}
+
+// WithHook sets the Hook. Multiple hooks run in the given order.
+func WithHook(hooks ...Hook) Option {
+	return func(m *Module) {
+		if m.Hook == nil && len(hooks) == 1 {
+			// keep the function itself, for its name in metrics and panics
+			m.Hook = hooks[0]
+			return
+		}
+		hooks := append([]Hook{m.Hook}, hooks...)
+		m.Hook = func(msg *Message) *Message {
+			for _, hook := range hooks {
+				if hook != nil && msg != nil {
+					msg = hook(msg)
+				}
+			}
+			return msg
+		}
+	}
+}
+
+// WithHookPriority registers hook with priority, see Module.AddHook.
+func WithHookPriority(priority int, hook Hook) Option {
+	return func(m *Module) {
+		m.AddHook(priority, hook)
+	}
+}
+
+// WithoutGlobalHooks sets Module.NoGlobalHooks.
+func WithoutGlobalHooks() Option {
+	return func(m *Module) {
+		m.NoGlobalHooks = true
+	}
+}
+
+func WithSinks(sinks ...Sink) Option {
+	return func(m *Module) {
+		m.Sinks = append(m.Sinks, sinks...)
+	}
+}
+
+func WithLogger(logger *log.Logger) Option {
+	return func(m *Module) {
+		m.Logger = logger
+	}
+}
+
+func WithClock(clock Clock) Option {
+	return func(m *Module) {
+		m.Clock = clock
+	}
+}
+
+func WithUnknownPolicy(policy UnknownPolicy) Option {
+	return func(m *Module) {
+		m.Unknown = policy
+	}
+}
+
+func WithStrictFormat() Option {
+	return func(m *Module) {
+		m.StrictFormat = true
+	}
+}
+
+func WithCaller() Option {
+	return func(m *Module) {
+		m.Caller = true
+	}
+}
+
+func WithStack() Option {
+	return func(m *Module) {
+		m.Stack = true
+	}
+}
+
+func WithJSON() Option {
+	return func(m *Module) {
+		m.JSON = true
+	}
+}
+
+func WithShowID() Option {
+	return func(m *Module) {
+		m.ShowID = true
+	}
+}
+
+func WithMetrics(metrics Metrics) Option {
+	return func(m *Module) {
+		m.Metrics = metrics
+	}
+}
+
+func WithSigner(signer *Signer) Option {
+	return func(m *Module) {
+		m.Signer = signer
+	}
+}
+
+func WithVerbosity(n int) Option {
+	return func(m *Module) {
+		m.SetVerbosity(n)
+	}
+}
+
+func WithThrottle(opts ThrottleOptions) Option {
+	return func(m *Module) {
+		m.Throttle(opts)
+	}
+}
+
+func WithProfileLabels() Option {
+	return func(m *Module) {
+		m.ProfileLabels = true
+	}
+}
+
+// WithCodeRange reserves the code range, see Module.Reserve. It panics if the reservation fails.
+func WithCodeRange(r CodeRange) Option {
+	return func(m *Module) {
+		if err := m.Reserve(r); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+// WithLocale adds a translation of the catalog, see Module.AddLocale.
+func WithLocale(lang string, messages string) Option {
+	return func(m *Module) {
+		m.AddLocale(lang, messages)
+	}
+}
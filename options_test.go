@@ -0,0 +1,55 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	var b bytes.Buffer
+	var hooks []string
+	var sunk int
+	m := NewWithOptions("module", messages,
+		WithMask(Warn|Error),
+		WithLogger(log.New(&b, "", 0)),
+		WithHook(func(msg *Message) *Message {
+			hooks = append(hooks, "a")
+			return msg
+		}, func(msg *Message) *Message {
+			hooks = append(hooks, "b")
+			return msg
+		}),
+		WithSinks(SinkFunc(func(msg *Message) error {
+			sunk++
+			return nil
+		})),
+		WithCaller(),
+	)
+
+	m.Info("test")
+	m.Warn("test2")
+
+	if strings.Join(hooks, "") != "abab" || sunk != 2 {
+		t.Fatalf("unexpected hooks %v and sinks %d", hooks, sunk)
+	}
+	if !strings.HasPrefix(b.String(), "[WARN ] This is a another test message caller=") || !strings.Contains(b.String(), "/options_test.go:") {
+		t.Fatalf("unexpected log output: %q", b.String())
+	}
+}
+
+func TestJSONMode(t *testing.T) {
+	var b bytes.Buffer
+	m := NewWithOptions("module", messages, WithLogger(log.New(&b, "prefix ", log.LstdFlags)), WithJSON())
+	m.Warn("test", "A", 1)
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &msg); err != nil {
+		t.Fatalf("bad json %q: %v", b.String(), err)
+	}
+	if msg["name"] != "test" || msg["level"] != "warn" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+}
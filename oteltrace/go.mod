@@ -0,0 +1,18 @@
+module github.com/halliday/go-module/oteltrace
+
+go 1.21
+
+require (
+	github.com/halliday/go-module v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/halliday/go-errors v0.0.0-20221117114904-701c88d594be // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/stretchr/testify v1.9.0 // indirect
+	go.opentelemetry.io/otel v1.24.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+)
+
+replace github.com/halliday/go-module => ../
@@ -0,0 +1,27 @@
+// Package oteltrace adds the trace and span ID of OpenTelemetry spans to module messages.
+// Importing it is enough:
+//
+//	import _ "github.com/halliday/go-module/oteltrace"
+//
+// Messages logged with a context that carries a valid span context then get "trace_id" and "span_id".
+package oteltrace
+
+import (
+	"context"
+
+	module "github.com/halliday/go-module"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func init() {
+	module.AddTraceExtractor(Extract)
+}
+
+// Extract returns the trace and span ID of the span context of ctx.
+func Extract(ctx context.Context) (traceID, spanID string, ok bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", "", false
+	}
+	return sc.TraceID().String(), sc.SpanID().String(), true
+}
@@ -0,0 +1,27 @@
+package oteltrace
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+
+	module "github.com/halliday/go-module"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExtract(t *testing.T) {
+	var b bytes.Buffer
+	_, _, m := module.New("module", "test;1;Test message\n")
+	m.Logger = log.New(&b, "", 0)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+
+	m.Info("test", ctx)
+	m.Info("test", context.Background())
+	if want := "[INFO ] Test message trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7\n[INFO ] Test message\n"; b.String() != want {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+}
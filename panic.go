@@ -0,0 +1,130 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"time"
+)
+
+type internalKey struct{}
+
+// ctxInternal marks the messages a module logs about its own hooks and sinks.
+// They skip all hooks, so a broken hook can not recurse.
+func ctxInternal(ctx context.Context) context.Context {
+	return context.WithValue(ctx, internalKey{}, true)
+}
+
+func isInternal(ctx context.Context) bool {
+	internal, _ := ctx.Value(internalKey{}).(bool)
+	return internal
+}
+
+// funcName returns the name of the function f, like "main.notify.func1".
+func funcName(f interface{}) string {
+	if fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return fmt.Sprintf("%T", f)
+}
+
+// callHook runs hook and recovers from its panic. After a panic, the message continues unchanged
+// to the next hook and a "hook_panic" Error message names the hook.
+// With Metrics, the call is measured.
+func (m *Module) callHook(ctx context.Context, hook Hook, msg *Message) (out *Message) {
+	var start time.Time
+	if m.Metrics != nil {
+		start = m.now()
+	}
+	defer func() {
+		r := recover()
+		if m.Metrics != nil {
+			m.measure("hook", funcName(hook), start, "panics", r != nil)
+		}
+		if r != nil {
+			out = msg
+			name := funcName(hook)
+			m.log(ctxInternal(ctx), Error, "hook_panic", 0, fmt.Sprintf("hook %s panicked: %v", name, r), "", []interface{}{"hook", name}, nil)
+		}
+	}()
+	return hook(msg)
+}
+
+// writeSink writes msg to sink and turns a panic of the sink into an error, and, unless msg is
+// itself internal, into a "sink_panic" Error message that names the sink.
+// With Metrics, the write is measured. i is the position of sink in Sinks, or -1.
+func (m *Module) writeSink(ctx context.Context, i int, sink Sink, msg *Message) (err error) {
+	var start time.Time
+	if m.Metrics != nil {
+		start = m.now()
+	}
+	defer func() {
+		r := recover()
+		if r != nil {
+			name := m.sinkKey(i, sink)
+			err = fmt.Errorf("sink %s panicked: %v", name, r)
+			if !isInternal(ctx) {
+				m.log(ctxInternal(ctx), Error, "sink_panic", 0, err.Error(), "", []interface{}{"sink", name}, nil)
+			}
+		}
+		if m.Metrics != nil {
+			m.measure("sink", m.sinkKey(i, sink), start, "errors", err != nil)
+		}
+	}()
+	return sink.Write(msg)
+}
+
+// orphans reports the panics for messages that no module logged.
+var orphans Module
+
+// reporter returns the module that logged msg, which reports the panics of its pooled hooks and batch writers.
+func reporter(msg *Message) *Module {
+	if msg != nil && msg.origin != nil {
+		return msg.origin
+	}
+	return &orphans
+}
+
+// writeBatch writes batch to w and turns a panic of w into an error.
+func writeBatch(w BatchWriter, batch []*Message) (panicked bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			err = fmt.Errorf("sink %s panicked: %v", writerName(w), r)
+		}
+	}()
+	return false, w.WriteBatch(batch)
+}
+
+// reportBatchPanic logs a "sink_panic" Error message naming w, unless all messages of batch are internal.
+func reportBatchPanic(w BatchWriter, batch []*Message, err error) {
+	for _, msg := range batch {
+		if !msg.internal {
+			reporter(msg).log(ctxInternal(context.Background()), Error, "sink_panic", 0, err.Error(), "", []interface{}{"sink", writerName(w)}, nil)
+			return
+		}
+	}
+}
+
+// writerName returns the Name of w, the function name of a BatchWriterFunc, or the type of w.
+func writerName(w BatchWriter) string {
+	if n, ok := w.(Named); ok && n.Name() != "" {
+		return n.Name()
+	}
+	if f, ok := w.(BatchWriterFunc); ok {
+		return funcName(f)
+	}
+	return fmt.Sprintf("%T", w)
+}
+
+// sinkName returns the Name of sink, the function name of a SinkFunc, or the type of sink.
+func sinkName(sink Sink) string {
+	if n, ok := sink.(Named); ok && n.Name() != "" {
+		return n.Name()
+	}
+	if f, ok := sink.(SinkFunc); ok {
+		return funcName(f)
+	}
+	return fmt.Sprintf("%T", sink)
+}
@@ -0,0 +1,51 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+)
+
+func panickingHook(msg *Message) *Message {
+	panic("boom")
+}
+
+func TestHookPanic(t *testing.T) {
+	var b bytes.Buffer
+	var sunk []string
+	m := NewWithOptions("module", messages, WithLogger(log.New(&b, "", 0)),
+		WithHookPriority(0, panickingHook),
+		WithSinks(SinkFunc(func(msg *Message) error {
+			sunk = append(sunk, msg.Name)
+			return nil
+		})),
+	)
+	m.Info("test", "A", 1)
+
+	if strings.Join(sunk, ",") != "hook_panic,test" {
+		t.Fatalf("unexpected messages %v", sunk)
+	}
+	if b.String() != "[INFO ] This is a test message A=1\n[ERR  ] hook "+pkgPrefix+"panickingHook panicked: boom hook="+pkgPrefix+"panickingHook\n" {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+}
+
+type panickingSink struct{}
+
+func (panickingSink) Write(msg *Message) error {
+	panic("boom")
+}
+
+func TestSinkPanic(t *testing.T) {
+	var b bytes.Buffer
+	m := NewWithOptions("module", messages, WithLogger(log.New(&b, "", 0)), WithSinks(panickingSink{}))
+	err := m.log(context.Background(), Info, "test", 0, "test", "", nil, nil)
+	if err == nil || err.Error() != "sink module.panickingSink panicked: boom" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !strings.Contains(b.String(), "[ERR  ] sink module.panickingSink panicked: boom sink=module.panickingSink\n") {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+}
@@ -0,0 +1,143 @@
+// Package parquetexport writes module messages to Parquet files with a typed schema, for the
+// analysis of error patterns in DuckDB, Spark, Athena or other lakehouse tools:
+//
+//	f, err := os.Create("messages.parquet")
+//	n, err := parquetexport.ExportJournal("/var/log/app/journal", since, f, parquetexport.Options{})
+//
+// A Writer is a module.Sink, so it also takes messages from Journal.Replay or a live Module.
+// The columns are those of Row; data is a JSON column, trace_id and span_id are copied from it for joins.
+//
+//	SELECT name, count(*) FROM 'messages.parquet' WHERE level = 'error' GROUP BY name
+package parquetexport
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	module "github.com/halliday/go-module"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+	"github.com/parquet-go/parquet-go/compress/zstd"
+)
+
+// Row is the schema of the files.
+type Row struct {
+	Time     time.Time `parquet:"time,timestamp(microsecond)"`
+	Module   string    `parquet:"module,dict"`
+	Level    string    `parquet:"level,dict"`
+	Seq      uint64    `parquet:"seq,delta"`
+	Epoch    string    `parquet:"epoch,dict"`
+	ID       string    `parquet:"id"`
+	Name     string    `parquet:"name,dict"`
+	Code     int64     `parquet:"code"`
+	Desc     string    `parquet:"desc"`
+	Link     string    `parquet:"link,dict"`
+	Data     string    `parquet:"data,json"`
+	Caller   string    `parquet:"caller,dict"`
+	CausedBy string    `parquet:"caused_by"`
+	TraceID  string    `parquet:"trace_id"`
+	SpanID   string    `parquet:"span_id"`
+}
+
+// NewRow returns the row of msg. Empty values are empty strings or 0, data without keys is "{}".
+func NewRow(msg *module.Message) (Row, error) {
+	r := Row{
+		Time:   msg.Time.UTC(),
+		Module: msg.Module,
+		Level:  msg.Level.String(),
+		Seq:    msg.Seq,
+		Epoch:  msg.Epoch,
+		ID:     msg.ID,
+		Data:   "{}",
+		Caller: msg.Caller,
+	}
+	if msg.RichError != nil {
+		r.Name = msg.Name
+		r.Code = int64(msg.Code)
+		r.Desc = msg.Desc
+		r.Link = msg.Link
+		if msg.CausedBy != nil {
+			r.CausedBy = msg.CausedBy.Error()
+		}
+	}
+	if len(msg.Data) > 0 {
+		data, err := json.Marshal(msg.Data)
+		if err != nil {
+			return r, err
+		}
+		r.Data = string(data)
+	}
+	r.TraceID, _ = msg.Data[module.KeyTraceID].(string)
+	r.SpanID, _ = msg.Data[module.KeySpanID].(string)
+	return r, nil
+}
+
+type Options struct {
+	// Compression compresses the columns. Default zstd.
+	Compression compress.Codec
+	// RowGroupSize is the number of rows of a row group. Default 100000.
+	RowGroupSize int64
+}
+
+// Writer writes messages to a Parquet file. The file is complete after Close.
+type Writer struct {
+	w   *parquet.GenericWriter[Row]
+	buf []Row
+}
+
+func NewWriter(w io.Writer, opts Options) *Writer {
+	if opts.Compression == nil {
+		opts.Compression = &zstd.Codec{}
+	}
+	if opts.RowGroupSize <= 0 {
+		opts.RowGroupSize = 100000
+	}
+	return &Writer{w: parquet.NewGenericWriter[Row](w,
+		parquet.Compression(opts.Compression),
+		parquet.MaxRowsPerRowGroup(opts.RowGroupSize),
+	)}
+}
+
+// Write adds msg to the file. Rows are buffered and written in blocks.
+func (w *Writer) Write(msg *module.Message) error {
+	return w.WriteBatch([]*module.Message{msg})
+}
+
+func (w *Writer) WriteBatch(msgs []*module.Message) error {
+	for _, msg := range msgs {
+		r, err := NewRow(msg)
+		if err != nil {
+			return err
+		}
+		w.buf = append(w.buf, r)
+	}
+	if len(w.buf) >= 1024 {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *Writer) flush() error {
+	_, err := w.w.Write(w.buf)
+	w.buf = w.buf[:0]
+	return err
+}
+
+// Close writes the remaining rows and the footer of the file. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return w.w.Close()
+}
+
+// ExportJournal writes the messages of the journal at path (see module.ReplayJournal) since a time
+// to a Parquet file, and returns their number.
+func ExportJournal(path string, since time.Time, w io.Writer, opts Options) (n int, err error) {
+	pw := NewWriter(w, opts)
+	if n, err = module.ReplayJournal(path, since, pw); err != nil {
+		return n, err
+	}
+	return n, pw.Close()
+}
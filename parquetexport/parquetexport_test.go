@@ -0,0 +1,61 @@
+package parquetexport
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestExportJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := module.OpenJournal(path, module.FileSinkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 123456000, time.UTC)
+	msgs := []*module.Message{
+		{Module: "auth", Level: module.Info, Time: t0.Add(-time.Hour)},
+		{
+			Module:    "auth",
+			Level:     module.Error,
+			Time:      t0,
+			Seq:       7,
+			ID:        "01GJ3Q2V4R8N6YH3T2KXW5Z9CD",
+			RichError: errors.NewRich("login_failed", 1001, "Login failed", "", nil, errors.New("db down")),
+			Data:      map[string]interface{}{"user": "bob", module.KeyTraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		},
+		{Module: "billing", Level: module.Warn, Time: t0.Add(time.Minute)},
+	}
+	for _, msg := range msgs {
+		if err := j.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j.Close()
+
+	var b bytes.Buffer
+	n, err := ExportJournal(path, t0, &b, Options{})
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 messages, got %d, %v", n, err)
+	}
+	rows, err := parquet.Read[Row](bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	r := rows[0]
+	if !r.Time.Equal(t0) || r.Module != "auth" || r.Level != "error" || r.Seq != 7 || r.Name != "login_failed" || r.Code != 1001 ||
+		r.Desc != "Login failed" || r.CausedBy != "db down" || r.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("unexpected row %+v", r)
+	}
+	if r.Data != `{"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","user":"bob"}` || rows[1].Data != "{}" {
+		t.Fatalf("unexpected data %s, %s", r.Data, rows[1].Data)
+	}
+}
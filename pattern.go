@@ -0,0 +1,278 @@
+package module
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// fmtVerbs are the verbs of package fmt.
+const fmtVerbs = "vTtbcdoOqxXUeEfFgGsp"
+
+// A verb is a placeholder of a fmt pattern bound to the argument at index arg.
+// Arguments consumed by a '*' width or precision have c == '*'.
+type verb struct {
+	arg int
+	c   byte
+}
+
+// scanPattern parses pattern with the grammar of fmt: flags, width and precision
+// (each possibly '*'), and explicit argument indexes like %[2]d.
+// It returns the verbs in pattern order, the number of arguments consumed and,
+// if the pattern is malformed, the offending fragment in bad.
+func scanPattern(pattern string) (verbs []verb, nargs int, bad string) {
+	argNum := 0
+	use := func(c byte) {
+		verbs = append(verbs, verb{argNum, c})
+		argNum++
+		if argNum > nargs {
+			nargs = argNum
+		}
+	}
+	for i := 0; i < len(pattern); {
+		j := strings.IndexByte(pattern[i:], '%')
+		if j == -1 {
+			break
+		}
+		start := i + j
+		i = start + 1
+		for i < len(pattern) && strings.IndexByte("+-# 0", pattern[i]) != -1 {
+			i++
+		}
+		var ok, afterIndex bool
+		if argNum, i, afterIndex, ok = argNumber(pattern, i, argNum); !ok {
+			return verbs, nargs, pattern[start:i]
+		}
+		if i < len(pattern) && pattern[i] == '*' {
+			i++
+			use('*')
+			afterIndex = false
+		} else {
+			j := skipDigits(pattern, i)
+			if afterIndex && j != i {
+				return verbs, nargs, pattern[start:j]
+			}
+			i = j
+		}
+		if i < len(pattern) && pattern[i] == '.' {
+			i++
+			if afterIndex {
+				return verbs, nargs, pattern[start:i]
+			}
+			if argNum, i, afterIndex, ok = argNumber(pattern, i, argNum); !ok {
+				return verbs, nargs, pattern[start:i]
+			}
+			if i < len(pattern) && pattern[i] == '*' {
+				i++
+				use('*')
+				afterIndex = false
+			} else {
+				i = skipDigits(pattern, i)
+			}
+		}
+		if !afterIndex {
+			if argNum, i, afterIndex, ok = argNumber(pattern, i, argNum); !ok {
+				return verbs, nargs, pattern[start:i]
+			}
+		}
+		if i >= len(pattern) {
+			return verbs, nargs, pattern[start:]
+		}
+		c, size := utf8.DecodeRuneInString(pattern[i:])
+		i += size
+		if c == '%' {
+			continue
+		}
+		if c >= utf8.RuneSelf || strings.IndexByte(fmtVerbs, byte(c)) == -1 {
+			return verbs, nargs, pattern[start:i]
+		}
+		use(byte(c))
+	}
+	return verbs, nargs, ""
+}
+
+// unusedArgs returns the one-based indexes below nargs that no verb refers to.
+// Such gaps only occur with explicit indexes, e.g. "%[2]s" consumes two arguments
+// but never formats the first one.
+func unusedArgs(verbs []verb, nargs int) (unused []int) {
+	used := make([]bool, nargs)
+	for _, v := range verbs {
+		used[v.arg] = true
+	}
+	for i, u := range used {
+		if !u {
+			unused = append(unused, i+1)
+		}
+	}
+	return unused
+}
+
+// argNumber parses an optional "[n]" at pattern[i:] and returns the zero-based argument index.
+func argNumber(pattern string, i int, argNum int) (int, int, bool, bool) {
+	if i >= len(pattern) || pattern[i] != '[' {
+		return argNum, i, false, true
+	}
+	end := strings.IndexByte(pattern[i:], ']')
+	if end == -1 {
+		return argNum, len(pattern), false, false
+	}
+	n := 0
+	for _, c := range pattern[i+1 : i+end] {
+		if c < '0' || c > '9' || n > 1e6 {
+			return argNum, i + end + 1, false, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	if n < 1 {
+		return argNum, i + end + 1, false, false
+	}
+	return n - 1, i + end + 1, true, true
+}
+
+func skipDigits(pattern string, i int) int {
+	for i < len(pattern) && pattern[i] >= '0' && pattern[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+// verbAccepts reports whether fmt can format arg with verb without a "%!verb(...)" error.
+// Like fmt, it formats the elements of slices, arrays and maps and the fields of structs with verb.
+func verbAccepts(verb byte, arg interface{}) bool {
+	if verb == 'T' {
+		return true
+	}
+	if verb == 'p' {
+		switch reflect.ValueOf(arg).Kind() {
+		case reflect.Ptr, reflect.Chan, reflect.Func, reflect.Map, reflect.Slice, reflect.UnsafePointer:
+			return true
+		}
+		return false
+	}
+	return valueAccepts(verb, reflect.ValueOf(arg), 0)
+}
+
+func valueAccepts(verb byte, v reflect.Value, depth int) bool {
+	if verb == 'v' {
+		return true
+	}
+	if !v.IsValid() {
+		// fmt writes "<nil>" for nil elements
+		return depth > 0
+	}
+	if v.CanInterface() {
+		switch v.Interface().(type) {
+		case fmt.Formatter:
+			return true
+		case error, fmt.Stringer:
+			if strings.IndexByte("sqxX", verb) != -1 {
+				return true
+			}
+		}
+	}
+	t := v.Type()
+	kind := t.Kind()
+	switch kind {
+	case reflect.Interface:
+		return v.IsNil() || valueAccepts(verb, v.Elem(), depth+1)
+	case reflect.Slice, reflect.Array:
+		if strings.IndexByte("sqxX", verb) != -1 && t.Elem().Kind() == reflect.Uint8 {
+			return true
+		}
+		for i := 0; i < v.Len(); i++ {
+			if !valueAccepts(verb, v.Index(i), depth+1) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if !valueAccepts(verb, iter.Key(), depth+1) || !valueAccepts(verb, iter.Value(), depth+1) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !valueAccepts(verb, v.Field(i), depth+1) {
+				return false
+			}
+		}
+		return true
+	case reflect.Ptr:
+		// fmt writes &{...} for pointers to composites, only at the top
+		if depth == 0 && !v.IsNil() {
+			switch t.Elem().Kind() {
+			case reflect.Array, reflect.Slice, reflect.Struct, reflect.Map:
+				return valueAccepts(verb, v.Elem(), depth+1)
+			}
+		}
+		return strings.IndexByte("bodxX", verb) != -1
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return strings.IndexByte("bodxX", verb) != -1
+	}
+	switch verb {
+	case 's', 'q', 'x', 'X':
+		if kind == reflect.String {
+			return true
+		}
+		if verb == 's' {
+			return false
+		}
+		if verb == 'q' {
+			return isInt(kind)
+		}
+		return isInt(kind) || isFloat(kind)
+	case 'd', 'o', 'O', 'c', 'U':
+		return isInt(kind)
+	case 'b':
+		return isInt(kind) || isFloat(kind)
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		return isFloat(kind)
+	case 't':
+		return kind == reflect.Bool
+	}
+	return false
+}
+
+func isInt(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Uintptr
+}
+
+func isFloat(kind reflect.Kind) bool {
+	return kind >= reflect.Float32 && kind <= reflect.Complex128
+}
+
+// checkArgs returns a description of the first argument that does not match its verb, or "".
+// Arguments of a '*' width or precision must be ints.
+func checkArgs(verbs []verb, args []interface{}) string {
+	for _, v := range verbs {
+		if v.arg >= len(args) {
+			continue
+		}
+		arg := args[v.arg]
+		if v.c == '*' {
+			if _, ok := arg.(int); !ok {
+				return fmt.Sprintf("argument %d: * with %T", v.arg+1, arg)
+			}
+		} else if !verbAccepts(v.c, arg) {
+			return fmt.Sprintf("argument %d: %%%c with %T", v.arg+1, v.c, arg)
+		}
+	}
+	return ""
+}
+
+// placeholders renders verbs like "%s %d" for error messages.
+func placeholders(verbs []verb) string {
+	var b strings.Builder
+	for i, v := range verbs {
+		if i != 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteByte('%')
+		b.WriteByte(v.c)
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestScanPattern(t *testing.T) {
+	for pattern, expected := range map[string]string{
+		"no verbs":           "",
+		"100%% sure":         "",
+		"%s and %d":          "0s 1d",
+		"%-8s|%+v|%08.3f|%x": "0s 1v 2f 3x",
+		"%*d and %-.*f":      "0* 1d 2* 3f",
+		"%[2]s before %[1]d": "1s 0d",
+		"%[1]s %[1]q %s":     "0s 0q 1s",
+		"%[2]*[1]d":          "1* 0d",
+		"%3.[2]*[1]f":        "1* 0f",
+	} {
+		verbs, _, bad := scanPattern(pattern)
+		var s []string
+		for _, v := range verbs {
+			s = append(s, fmt.Sprintf("%d%c", v.arg, v.c))
+		}
+		if got := strings.Join(s, " "); got != expected || bad != "" {
+			t.Errorf("scanPattern(%q) = %q, %q, expected %q", pattern, got, bad, expected)
+		}
+	}
+
+	for pattern, expected := range map[string]int{
+		"%[1]s %[1]s": 1,
+		"%[3]d %[1]d": 3,
+		"%[2]d %d":    3,
+		"%*.*f":       3,
+	} {
+		if _, n, _ := scanPattern(pattern); n != expected {
+			t.Errorf("scanPattern(%q) consumes %d argument(s), expected %d", pattern, n, expected)
+		}
+	}
+
+	for pattern, expected := range map[string]string{
+		"trailing %":     "%",
+		"%-8":            "%-8",
+		"%y":             "%y",
+		"%[0]d":          "%[0]",
+		"%[x]d":          "%[x]",
+		"%[1d":           "%[1d",
+		"%[1]2d":         "%[1]2",
+		"%ä":             "%ä",
+		"ok %d then %[2": "%[2",
+	} {
+		if _, _, bad := scanPattern(pattern); bad != expected {
+			t.Errorf("scanPattern(%q) reports %q, expected %q", pattern, bad, expected)
+		}
+	}
+}
+
+func TestIndexedArgs(t *testing.T) {
+	var b bytes.Buffer
+	m := NewWithOptions("module", "a;1;%-6[2]s|%[1]*[3]d\n", WithLogger(log.New(&b, "", 0)))
+	m.Info("a", 4, "ab", 7, "key", "value")
+	if b.String() != "[INFO ] ab    |   7 key=value\n" {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+}
+
+func TestArgCount(t *testing.T) {
+	m := NewWithOptions("module", "a;1;%-8s has %d items\n")
+	defer func() {
+		r, _ := recover().(string)
+		if !strings.Contains(r, `message "a" (line 1): 1 argument(s) for 2 placeholder(s) %s %d`) {
+			t.Fatalf("unexpected panic %q", r)
+		}
+	}()
+	m.NewError("a", "x")
+}
+
+func TestIndexedTail(t *testing.T) {
+	m := NewWithOptions("module", "a;1;%[2]s logged in as %[1]s, again %[2]s\n")
+	cause := errors.New("cause")
+	ctx := CtxWithRequestID(context.Background(), "r1")
+	_, desc, _, tail, c, causedBy := m.Lookup("a", "admin", "bob", ctx, cause, "key", "value")
+	if desc != "bob logged in as admin, again bob" {
+		t.Fatalf("unexpected desc %q", desc)
+	}
+	if len(tail) != 2 || tail[0] != "key" || c != ctx || causedBy != cause {
+		t.Fatalf("unexpected tail %v, context %v, cause %v", tail, c, causedBy)
+	}
+	if unused := unusedArgs(m.catalog.entries[0].verbs, m.catalog.entries[0].nargs); len(unused) != 0 {
+		t.Fatalf("unexpected unused arguments %v", unused)
+	}
+}
+
+func TestCheckArgs(t *testing.T) {
+	type stringer struct{ fmt.Stringer }
+	for _, c := range []struct {
+		pattern string
+		args    []interface{}
+		bad     string
+	}{
+		{"%s %d %v %f", []interface{}{"a", 1, nil, 1.5}, ""},
+		{"%s %x %t", []interface{}{errors.New("e"), []byte("b"), true}, ""},
+		{"%s", []interface{}{stringer{&strings.Builder{}}}, ""},
+		{"%d", []interface{}{"a"}, "argument 1: %d with string"},
+		{"%s %f", []interface{}{"a", 1}, "argument 2: %f with int"},
+		{"%[2]d %[1]s", []interface{}{"a", 1}, ""},
+		{"%[2]d %[1]s", []interface{}{1, "a"}, "argument 2: %d with string"},
+		{"%*d", []interface{}{"4", 1}, "argument 1: * with string"},
+		{"%d %s %x", []interface{}{[]int{1}, []string{"a"}, []string{"b"}}, ""},
+		{"%d %s", []interface{}{map[int]uint{1: 2}, [2]error{errors.New("e")}}, ""},
+		{"%f %d", []interface{}{struct{ A float64 }{1}, &[]int{1}}, ""},
+		{"%d", []interface{}{[]interface{}{1, nil}}, ""},
+		{"%d", []interface{}{[]string{"a"}}, "argument 1: %d with []string"},
+		{"%d", []interface{}{map[string]int{"a": 1}}, "argument 1: %d with map[string]int"},
+		{"%d", []interface{}{[]interface{}{1, "a"}}, "argument 1: %d with []interface {}"},
+	} {
+		verbs, _, _ := scanPattern(c.pattern)
+		if bad := checkArgs(verbs, c.args); bad != c.bad {
+			t.Errorf("checkArgs(%q, %v) = %q, expected %q", c.pattern, c.args, bad, c.bad)
+		}
+		if bad := strings.Contains(fmt.Sprintf(c.pattern, c.args...), "%!"); bad != (c.bad != "") {
+			t.Errorf("fmt.Sprintf(%q, %v) disagrees", c.pattern, c.args)
+		}
+	}
+}
+
+func TestFormatMismatch(t *testing.T) {
+	var b bytes.Buffer
+	m := NewWithOptions("module", "a;1;%d items\nb;2;%d others\n", WithLogger(log.New(&b, "", 0)), WithMask(Warn|Error|Info))
+	m.Info("a", "many")
+	m.Info("a", "many")
+	if b.String() != "[WARN ] message \"a\": argument 1: %d with string message=a\n[INFO ] %!d(string=many) items\n[INFO ] %!d(string=many) items\n" {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+
+	// not checked for disabled levels
+	b.Reset()
+	m.Debug("b", "many")
+	if b.Len() != 0 {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+
+	m.StrictFormat = true
+	defer func() {
+		if recover() == nil {
+			t.Fatal("mismatch did not panic in strict mode")
+		}
+	}()
+	m.Info("a", "many")
+}
+
+func TestCatalogBadPattern(t *testing.T) {
+	_, err := parseCatalog("a;1;A\nb;2;%[0]d items\n")
+	if err == nil || err.Error() != `line 2: "b": bad fmt verb "%[0]"` {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
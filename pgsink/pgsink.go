@@ -0,0 +1,204 @@
+// Package pgsink writes module messages into a PostgreSQL table, so services can query their logs with SQL.
+//
+// The sink works with any database/sql Postgres driver, like github.com/jackc/pgx/v5/stdlib or github.com/lib/pq:
+//
+//	db, err := sql.Open("pgx", os.Getenv("DATABASE_URL"))
+//	err = pgsink.Migrate(ctx, db, "")
+//	sink, err := pgsink.New(db, "")
+//	m.Sinks = append(m.Sinks, module.NewBatchSink(sink, module.BatchOptions{}))
+//
+// Use Migrations to apply the schema with a migration tool instead.
+package pgsink
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	module "github.com/halliday/go-module"
+)
+
+// DefaultTable is the table used if the table name is empty.
+const DefaultTable = "messages"
+
+// maxRows is the number of rows per INSERT statement, which keeps the parameters below the limit of 65535.
+const maxRows = 1000
+
+var columns = []string{"msg_id", "time", "module", "level", "name", "code", "description", "data", "message"}
+
+// Sink is a module.Sink and module.BatchWriter that inserts messages with multi-row INSERT statements.
+type Sink struct {
+	db    *sql.DB
+	table string
+}
+
+// New returns a Sink writing to table. It returns an error if table is not a lowercase identifier.
+func New(db *sql.DB, table string) (*Sink, error) {
+	t, err := tableName(table)
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{db: db, table: t}, nil
+}
+
+func (s *Sink) Write(msg *module.Message) error {
+	return s.WriteBatch([]*module.Message{msg})
+}
+
+// WriteBatch inserts msgs in one transaction.
+func (s *Sink) WriteBatch(msgs []*module.Message) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for len(msgs) > 0 {
+		n := len(msgs)
+		if n > maxRows {
+			n = maxRows
+		}
+		q, args, err := s.insert(msgs[:n])
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(q, args...); err != nil {
+			return err
+		}
+		msgs = msgs[n:]
+	}
+	return tx.Commit()
+}
+
+// insert returns the INSERT statement for msgs and its arguments.
+func (s *Sink) insert(msgs []*module.Message) (string, []interface{}, error) {
+	var b strings.Builder
+	b.WriteString("INSERT INTO " + s.table + " (" + strings.Join(columns, ", ") + ") VALUES ")
+	args := make([]interface{}, 0, len(msgs)*len(columns))
+	for i, msg := range msgs {
+		row, err := row(msg)
+		if err != nil {
+			return "", nil, err
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for j := range row {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(len(args) + j + 1))
+		}
+		b.WriteByte(')')
+		args = append(args, row...)
+	}
+	return b.String(), args, nil
+}
+
+// row returns the values of columns for msg.
+func row(msg *module.Message) ([]interface{}, error) {
+	full, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if len(msg.Data) > 0 {
+		b, err := json.Marshal(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		data = string(b)
+	}
+	var id interface{}
+	if msg.ID != "" {
+		id = msg.ID
+	}
+	var name, desc string
+	var code int
+	if msg.RichError != nil {
+		name, code, desc = msg.Name, msg.Code, msg.Desc
+	}
+	return []interface{}{id, msg.Time.UTC(), msg.Module, msg.Level.String(), name, code, desc, data, string(full)}, nil
+}
+
+// Migrations returns the statements that create and update table, one schema version per entry.
+// It returns an error if table is not a lowercase identifier.
+func Migrations(table string) ([]string, error) {
+	t, err := tableName(table)
+	if err != nil {
+		return nil, err
+	}
+	return []string{
+		// version 1
+		"CREATE TABLE IF NOT EXISTS " + t + ` (
+	id bigserial PRIMARY KEY,
+	msg_id text,
+	time timestamptz NOT NULL,
+	module text NOT NULL,
+	level text NOT NULL,
+	name text NOT NULL,
+	code integer NOT NULL,
+	description text NOT NULL,
+	data jsonb,
+	message jsonb NOT NULL
+);
+CREATE INDEX IF NOT EXISTS ` + t + "_time ON " + t + ` (time);
+CREATE INDEX IF NOT EXISTS ` + t + "_module ON " + t + ` (module, time);
+CREATE INDEX IF NOT EXISTS ` + t + "_level ON " + t + ` (level, time);
+CREATE INDEX IF NOT EXISTS ` + t + "_name ON " + t + ` (name, time);
+CREATE INDEX IF NOT EXISTS ` + t + "_code ON " + t + ` (code) WHERE code <> 0;
+CREATE INDEX IF NOT EXISTS ` + t + "_data ON " + t + " USING gin (data jsonb_path_ops)",
+	}, nil
+}
+
+// Migrate applies the Migrations of table that are missing in db.
+// The applied versions are kept in the table "<table>_migrations", which is locked while migrating,
+// so instances of a service can migrate concurrently on startup.
+func Migrate(ctx context.Context, db *sql.DB, table string) error {
+	t, err := tableName(table)
+	if err != nil {
+		return err
+	}
+	steps, _ := Migrations(t)
+	versions := t + "_migrations"
+	if _, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+versions+" (version integer PRIMARY KEY, applied_at timestamptz NOT NULL DEFAULT now())"); err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE "+versions+" IN EXCLUSIVE MODE"); err != nil {
+		return err
+	}
+	var current int
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM "+versions).Scan(&current); err != nil {
+		return err
+	}
+	for v := current + 1; v <= len(steps); v++ {
+		if _, err := tx.ExecContext(ctx, steps[v-1]); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO "+versions+" (version) VALUES ($1)", v); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func tableName(table string) (string, error) {
+	if table == "" {
+		return DefaultTable, nil
+	}
+	for i, c := range table {
+		if c != '_' && (c < 'a' || c > 'z') && (i == 0 || c < '0' || c > '9') {
+			return "", fmt.Errorf("pgsink: invalid table name %q", table)
+		}
+	}
+	return table, nil
+}
@@ -0,0 +1,183 @@
+package pgsink
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+// recorder is a database/sql driver that records the statements and answers queries with version.
+type recorder struct {
+	mu      sync.Mutex
+	execs   []exec
+	version int64
+}
+
+type exec struct {
+	query string
+	args  []driver.Value
+}
+
+func (r *recorder) Open(name string) (driver.Conn, error) { return conn{r}, nil }
+func (r *recorder) record(query string, args []driver.Value) {
+	r.mu.Lock()
+	r.execs = append(r.execs, exec{query, args})
+	r.mu.Unlock()
+}
+
+type conn struct{ r *recorder }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.r, query}, nil }
+func (c conn) Close() error                              { return nil }
+func (c conn) Begin() (driver.Tx, error)                 { c.r.record("BEGIN", nil); return tx{c.r}, nil }
+
+type tx struct{ r *recorder }
+
+func (t tx) Commit() error   { t.r.record("COMMIT", nil); return nil }
+func (t tx) Rollback() error { t.r.record("ROLLBACK", nil); return nil }
+
+type stmt struct {
+	r     *recorder
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.r.record(s.query, args)
+	return driver.RowsAffected(1), nil
+}
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.r.record(s.query, args)
+	return &rows{values: []int64{s.r.version}}, nil
+}
+
+type rows struct{ values []int64 }
+
+func (r *rows) Columns() []string { return []string{"version"} }
+func (r *rows) Close() error      { return nil }
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.values[0]
+	r.values = r.values[1:]
+	return nil
+}
+
+var registerOnce sync.Once
+var rec = new(recorder)
+
+func openDB(t *testing.T) (*sql.DB, *recorder) {
+	registerOnce.Do(func() { sql.Register("pgsink-recorder", rec) })
+	rec.mu.Lock()
+	rec.execs = nil
+	rec.version = 0
+	rec.mu.Unlock()
+	db, err := sql.Open("pgsink-recorder", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, rec
+}
+
+func TestWriteBatch(t *testing.T) {
+	db, r := openDB(t)
+	s, err := New(db, "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC)
+	msgs := make([]*module.Message, maxRows+1)
+	for i := range msgs {
+		msgs[i] = &module.Message{Module: "auth", Level: module.Warn, Time: t0, RichError: errors.NewRich("login_failed", 1001, "Login failed", "", nil, nil)}
+	}
+	msgs[0].ID = "01GJ3Q2V4R8N6YH3T2KXW5Z9CD"
+	msgs[0].Data = map[string]interface{}{"user": "bob"}
+	if err := s.WriteBatch(msgs); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.execs) != 4 || r.execs[0].query != "BEGIN" || r.execs[3].query != "COMMIT" {
+		t.Fatalf("unexpected statements %d", len(r.execs))
+	}
+	first, second := r.execs[1], r.execs[2]
+	if !strings.HasPrefix(first.query, "INSERT INTO logs (msg_id, time, module, level, name, code, description, data, message) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9), ($10, ") {
+		t.Fatalf("unexpected insert %.200q", first.query)
+	}
+	if !strings.HasSuffix(first.query, ", $9000)") || len(first.args) != 9000 {
+		t.Fatalf("unexpected first batch with %d args", len(first.args))
+	}
+	if !strings.HasSuffix(second.query, "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)") || len(second.args) != 9 {
+		t.Fatalf("unexpected second insert %q", second.query)
+	}
+
+	args := first.args
+	if args[0] != "01GJ3Q2V4R8N6YH3T2KXW5Z9CD" || !args[1].(time.Time).Equal(t0) || args[2] != "auth" || args[3] != "warn" ||
+		args[4] != "login_failed" || args[5] != int64(1001) || args[6] != "Login failed" || args[7] != `{"user":"bob"}` {
+		t.Fatalf("unexpected args %v", args[:8])
+	}
+	if !strings.Contains(args[8].(string), `"schema_version":1`) {
+		t.Fatalf("unexpected message %v", args[8])
+	}
+	if args := first.args[9:]; args[0] != nil || args[7] != nil {
+		t.Fatalf("expected NULL id and data, got %v", args[:8])
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db, r := openDB(t)
+	migrations, err := Migrations("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(context.Background(), db, ""); err != nil {
+		t.Fatal(err)
+	}
+	var queries []string
+	for _, e := range r.execs {
+		queries = append(queries, e.query)
+	}
+	want := []string{
+		"CREATE TABLE IF NOT EXISTS messages_migrations (version integer PRIMARY KEY, applied_at timestamptz NOT NULL DEFAULT now())",
+		"BEGIN",
+		"LOCK TABLE messages_migrations IN EXCLUSIVE MODE",
+		"SELECT COALESCE(MAX(version), 0) FROM messages_migrations",
+		migrations[0],
+		"INSERT INTO messages_migrations (version) VALUES ($1)",
+		"COMMIT",
+	}
+	if strings.Join(queries, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected statements:\n%s", strings.Join(queries, "\n"))
+	}
+
+	r.execs = nil
+	r.version = int64(len(migrations))
+	if err := Migrate(context.Background(), db, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.execs) != 5 || r.execs[4].query != "COMMIT" {
+		t.Fatalf("expected no migration, got %d statements", len(r.execs))
+	}
+}
+
+func TestInvalidTable(t *testing.T) {
+	if _, err := New(nil, "Messages"); err == nil || err.Error() != `pgsink: invalid table name "Messages"` {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := Migrations("logs;"); err == nil {
+		t.Fatal("invalid table name was accepted")
+	}
+	if err := Migrate(context.Background(), nil, "logs;"); err == nil {
+		t.Fatal("invalid table name was accepted")
+	}
+}
@@ -0,0 +1,113 @@
+package module
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// A Processor enriches, transforms, drops or routes messages. Unlike hooks, processors run
+// before the message is logged, so they can change what is written. Returning nil drops the message.
+type Processor interface {
+	Process(ctx context.Context, msg *Message) *Message
+}
+
+type ProcessorFunc func(ctx context.Context, msg *Message) *Message
+
+func (f ProcessorFunc) Process(ctx context.Context, msg *Message) *Message {
+	return f(ctx, msg)
+}
+
+func WithProcessors(processors ...Processor) Option {
+	return func(m *Module) {
+		m.Processors = append(m.Processors, processors...)
+	}
+}
+
+// WithEnvFields adds the EnvFields processor.
+func WithEnvFields(names ...string) Option {
+	return WithProcessors(EnvFields(names...))
+}
+
+// Set sets a Data value, allocating Data if necessary.
+func (msg *Message) Set(key string, value interface{}) {
+	if msg.Data == nil {
+		msg.Data = make(map[string]interface{})
+		if msg.RichError != nil {
+			msg.RichError.Data = msg.Data
+		}
+	}
+	if _, ok := msg.Data[key]; !ok {
+		msg.keys = append(msg.keys, key)
+	}
+	msg.Data[key] = value
+}
+
+const Redacted = "[REDACTED]"
+
+// Redact replaces the Data values of the given keys with Redacted.
+func Redact(keys ...string) Processor {
+	return ProcessorFunc(func(ctx context.Context, msg *Message) *Message {
+		for _, key := range keys {
+			if _, ok := msg.Data[key]; ok {
+				msg.Data[key] = Redacted
+			}
+		}
+		return msg
+	})
+}
+
+// Enrich sets key to value on every message that does not have the key already.
+func Enrich(key string, value interface{}) Processor {
+	return ProcessorFunc(func(ctx context.Context, msg *Message) *Message {
+		if _, ok := msg.Data[key]; !ok {
+			msg.Set(key, value)
+		}
+		return msg
+	})
+}
+
+// Sample keeps only every n-th message of each name for messages with a level in levels.
+// Other messages pass unchanged.
+func Sample(n int, levels Level) Processor {
+	var counters sync.Map
+	return ProcessorFunc(func(ctx context.Context, msg *Message) *Message {
+		if n <= 1 || msg.Level&levels == 0 {
+			return msg
+		}
+		var name string
+		if msg.RichError != nil {
+			name = msg.Name
+		}
+		c, ok := counters.Load(name)
+		if !ok {
+			c, _ = counters.LoadOrStore(name, new(uint64))
+		}
+		if (atomic.AddUint64(c.(*uint64), 1)-1)%uint64(n) != 0 {
+			return nil
+		}
+		return msg
+	})
+}
+
+// Drop drops all messages that match.
+func Drop(match func(msg *Message) bool) Processor {
+	return ProcessorFunc(func(ctx context.Context, msg *Message) *Message {
+		if match(msg) {
+			return nil
+		}
+		return msg
+	})
+}
+
+// Route writes all messages that match to the given sinks, in addition to the sinks of the Module.
+// They are written like the sinks of the Module, once the message is stamped and has passed the hooks.
+// Combine it with Drop to route messages exclusively.
+func Route(match func(msg *Message) bool, sinks ...Sink) Processor {
+	return ProcessorFunc(func(ctx context.Context, msg *Message) *Message {
+		if match(msg) {
+			msg.routes = append(msg.routes, sinks...)
+		}
+		return msg
+	})
+}
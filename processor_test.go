@@ -0,0 +1,48 @@
+package module
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+)
+
+func TestProcessors(t *testing.T) {
+	var b bytes.Buffer
+	var routed, sunk int
+	m := NewWithOptions("module", messages,
+		WithLogger(log.New(&b, "", 0)),
+		WithProcessors(
+			Redact("password"),
+			Enrich("host", "h1"),
+			Sample(2, Info),
+			Route(func(msg *Message) bool { return msg.Level == Error }, SinkFunc(func(msg *Message) error {
+				if msg.Seq == 0 || msg.Epoch == "" {
+					t.Fatal("routed message is not stamped")
+				}
+				routed++
+				return errors.New("routed sink failed")
+			})),
+		),
+		WithSinks(SinkFunc(func(msg *Message) error {
+			sunk++
+			return nil
+		})),
+	)
+
+	data := map[string]interface{}{"user": "bob", "password": "secret"}
+	m.Info("test", data)
+	m.Info("test", data)
+	m.Err("test3")
+
+	if data["password"] != "secret" {
+		t.Fatal("argument map was modified")
+	}
+	expected := "[INFO ] This is a test message password=[REDACTED] user=bob host=h1\n[ERR  ] Some more tests over here. host=h1\n[ERR  ] sink failed: routed sink failed\n"
+	if b.String() != expected {
+		t.Fatalf("unexpected log output: %q", b.String())
+	}
+	if routed != 1 || sunk != 2 {
+		t.Fatalf("unexpected routing: %d routed, %d sunk", routed, sunk)
+	}
+}
@@ -0,0 +1,152 @@
+package module
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// PanicName is the name of the message that RecoverHandler logs for a panic.
+// If the catalog has an entry of that name, it is the body of the 500 response.
+const PanicName = "http_panic"
+
+type stackKey struct{}
+
+// ctxWithStack makes the next message logged with ctx carry the call stack, like Module.Stack.
+func ctxWithStack(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stackKey{}, true)
+}
+
+func wantsStack(ctx context.Context) bool {
+	stack, _ := ctx.Value(stackKey{}).(bool)
+	return stack
+}
+
+// RecoverHandler returns a handler that recovers from panics of next. It logs a PanicName Error message
+// with the request and the stack of the panic, and answers with 500 Internal Server Error, unless next
+// already started the response. The body is the PanicName entry of the catalog as JSON, translated with
+// Accept-Language, and the message ID as reference:
+//
+//	{"name": "http_panic", "code": 5000, "description": "Something went wrong.", "id": "01GJ3Q2V4R8N6YH3T2KXW5Z9CD"}
+//
+// Without such an entry the body is the plain status text.
+// http.ErrAbortHandler is not recovered, as the server handles it.
+//
+// The request context of next is tagged with the trace and span ID of a traceparent or B3 header,
+// so its messages correlate with the trace even without a tracing SDK.
+func RecoverHandler(m *Module, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if traceID, spanID, ok := requestTrace(r.Header); ok {
+			r = r.WithContext(ctxWithTrace(r.Context(), traceID, spanID))
+		}
+		rw := &recoverWriter{ResponseWriter: w}
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			id := newID(m.now())
+			ctx := ctxWithStack(ctxWithID(r.Context(), id))
+			tail := []interface{}{
+				"method", r.Method,
+				"path", r.URL.Path,
+				"remote_addr", r.RemoteAddr,
+				"user_agent", r.UserAgent(),
+				"panic", fmt.Sprint(rec),
+			}
+			var code int
+			var link string
+			if e, ok := m.catalog.lookup(PanicName); ok {
+				code, link = e.code, e.link
+			}
+			m.log(ctx, Error, PanicName, code, fmt.Sprintf("%s %s panicked: %v", r.Method, r.URL.Path, rec), link, tail, nil)
+			if !rw.started {
+				m.writePanicResponse(w, r, id)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+func (m *Module) writePanicResponse(w http.ResponseWriter, r *http.Request, id string) {
+	e, ok := m.catalog.lookup(PanicName)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	body := struct {
+		Name string `json:"name"`
+		exportedEntry
+		ID string `json:"id"`
+	}{Name: PanicName, exportedEntry: exportedEntry{Code: e.code, Description: e.desc, Link: e.link}, ID: id}
+	if locale := m.negotiateLocale(r.Header.Get("Accept-Language")); locale != "" {
+		if c, ok := m.locale(locale); ok {
+			if le, ok := c.lookup(PanicName); ok {
+				body.Description = le.desc
+				if le.link != "" {
+					body.Link = le.link
+				}
+				w.Header().Set("Content-Language", locale)
+			}
+		}
+	}
+	data, _ := json.Marshal(body)
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write(data)
+}
+
+// recoverWriter tracks whether the response was started.
+type recoverWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *recoverWriter) WriteHeader(code int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recoverWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *recoverWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.started = true
+		f.Flush()
+	}
+}
+
+// Hijack hijacks the connection, e.g. for websockets. After that, no error response is written.
+func (w *recoverWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.started = true
+	}
+	return conn, rw, err
+}
+
+func (w *recoverWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (w *recoverWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
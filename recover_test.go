@@ -0,0 +1,119 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoverHandler(t *testing.T) {
+	var b bytes.Buffer
+	_, _, m := New("module", messages+"\nhttp_panic;5000;Something went wrong.\n")
+	m.AddLocale("de", "http_panic;5000;Etwas ist schiefgegangen.\n")
+	m.Logger = log.New(&b, "", 0)
+	var msg *Message
+	m.Hook = func(mm *Message) *Message {
+		msg = mm
+		return mm
+	}
+
+	h := RecoverHandler(m, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	req := httptest.NewRequest("GET", "/items/1?token=secret", nil)
+	req.Header.Set("Accept-Language", "de")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Language") != "de" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["name"] != PanicName || body["code"] != 5000.0 || body["description"] != "Etwas ist schiefgegangen." || body["id"] != msg.ID {
+		t.Fatalf("unexpected body %v", body)
+	}
+	if msg.Name != PanicName || msg.Code != 5000 || msg.Data["path"] != "/items/1" || msg.Data["panic"] != "boom" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	if len(msg.Frames) == 0 || !strings.HasSuffix(msg.Frames[0].File, "recover_test.go") {
+		t.Fatalf("unexpected stack %v", msg.Frames)
+	}
+	if !strings.HasPrefix(b.String(), "[ERR  ] GET /items/1 panicked: boom method=GET path=/items/1") {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+}
+
+func TestRecoverHandlerStarted(t *testing.T) {
+	_, _, m := New("module", messages)
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+
+	h := RecoverHandler(m, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusAccepted || rec.Body.Len() != 0 {
+		t.Fatalf("started response was changed: %d %q", rec.Code, rec.Body.String())
+	}
+
+	h = RecoverHandler(m, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("plain")
+	}))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusInternalServerError || rec.Body.String() != "Internal Server Error\n" {
+		t.Fatalf("unexpected response without catalog entry: %d %q", rec.Code, rec.Body.String())
+	}
+
+	defer func() {
+		if recover() != http.ErrAbortHandler {
+			t.Fatal("ErrAbortHandler was recovered")
+		}
+	}()
+	RecoverHandler(m, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestRecoverHandlerHijack(t *testing.T) {
+	_, _, m := New("module", messages)
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+
+	s := httptest.NewServer(RecoverHandler(m, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("no http.Hijacker")
+			return
+		}
+		conn, rw, err := h.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\nhello")
+		rw.Flush()
+	})))
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n"))
+	data, _ := io.ReadAll(conn)
+	if !strings.HasSuffix(string(data), "\r\n\r\nhello") {
+		t.Fatalf("unexpected response %q", data)
+	}
+}
@@ -0,0 +1,141 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// CodeRange is an inclusive range of message codes reserved by a Module.
+type CodeRange struct {
+	Min int
+	Max int
+}
+
+func (r CodeRange) Contains(code int) bool {
+	return code >= r.Min && code <= r.Max
+}
+
+func (r CodeRange) overlaps(o CodeRange) bool {
+	return r.Min <= o.Max && o.Min <= r.Max
+}
+
+func (r CodeRange) String() string {
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+var registry struct {
+	sync.Mutex
+	ranges map[string]CodeRange
+	// codes maps the catalog codes to the names of the modules using them.
+	codes map[int][]string
+}
+
+// register registers the catalog codes (other than 0) of m in the process wide registry, so that
+// Reserve can find them. Modules without a reserved range may share codes; register only fails,
+// without registering anything, if a code lies inside a range reserved by another module.
+// Registering again under the same module name replaces the previous codes.
+func (m *Module) register() error {
+	registry.Lock()
+	defer registry.Unlock()
+	var codes []int
+	if m.catalog != nil {
+		for _, e := range m.catalog.entries {
+			if e.code == 0 {
+				continue
+			}
+			for name, r := range registry.ranges {
+				if name != m.Name && r.Contains(e.code) {
+					return fmt.Errorf("module %q: %s: %q: code %d inside range %s reserved by module %q", m.Name, e.pos(), e.name, e.code, r, name)
+				}
+			}
+			codes = append(codes, e.code)
+		}
+	}
+	if registry.codes == nil {
+		registry.codes = make(map[int][]string)
+	}
+	for code, names := range registry.codes {
+		if names = without(names, m.Name); len(names) == 0 {
+			delete(registry.codes, code)
+		} else {
+			registry.codes[code] = names
+		}
+	}
+	for _, code := range codes {
+		if names := registry.codes[code]; len(names) == 0 || names[len(names)-1] != m.Name {
+			registry.codes[code] = append(names, m.Name)
+		}
+	}
+	return nil
+}
+
+// registerOrWarn registers m like register, and logs a "code_conflict" warning if that fails.
+func (m *Module) registerOrWarn() {
+	if err := m.register(); err != nil {
+		m.log(context.Background(), Warn, "code_conflict", 0, err.Error(), "", nil, nil)
+	}
+}
+
+func without(names []string, name string) []string {
+	out := names[:0:0]
+	for _, n := range names {
+		if n != name {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// Reserve registers the code range r for this module in the process wide registry.
+// It fails if r overlaps a range reserved by another module or contains a code of another module,
+// if a catalog code (other than 0) lies outside of r, or if the catalog uses a code twice.
+// Reserving again under the same module name replaces the previous reservation.
+func (m *Module) Reserve(r CodeRange) error {
+	if r.Min > r.Max {
+		return fmt.Errorf("module %q: bad code range %s", m.Name, r)
+	}
+	seen := make(map[int]string)
+	if m.catalog != nil {
+		for _, e := range m.catalog.entries {
+			if e.code == 0 {
+				continue
+			}
+			if !r.Contains(e.code) {
+				return fmt.Errorf("module %q: %s: %q: code %d outside of reserved range %s", m.Name, e.pos(), e.name, e.code, r)
+			}
+			if other, ok := seen[e.code]; ok && other != e.name {
+				return fmt.Errorf("module %q: %s: %q: code %d already used by %q", m.Name, e.pos(), e.name, e.code, other)
+			}
+			seen[e.code] = e.name
+		}
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	for name, other := range registry.ranges {
+		if name != m.Name && r.overlaps(other) {
+			return fmt.Errorf("module %q: code range %s overlaps range %s of module %q", m.Name, r, other, name)
+		}
+	}
+	for code, names := range registry.codes {
+		for _, name := range names {
+			if name != m.Name && r.Contains(code) {
+				return fmt.Errorf("module %q: code range %s contains code %d of module %q", m.Name, r, code, name)
+			}
+		}
+	}
+	if registry.ranges == nil {
+		registry.ranges = make(map[string]CodeRange)
+	}
+	registry.ranges[m.Name] = r
+	return nil
+}
+
+// Reserved returns the code range reserved by the named module.
+func Reserved(module string) (r CodeRange, ok bool) {
+	registry.Lock()
+	defer registry.Unlock()
+	r, ok = registry.ranges[module]
+	return r, ok
+}
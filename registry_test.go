@@ -0,0 +1,68 @@
+package module
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestReserve(t *testing.T) {
+	a, err := NewReserved("reserve_a", "a1;7100;A\na2;7399;A\n", CodeRange{7100, 7399})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, b := New("reserve_b", "b1;8000;B\nb2;8001;B\n")
+	if err := b.Reserve(CodeRange{7300, 8999}); err == nil {
+		t.Fatal("overlapping range was accepted")
+	}
+	if err := b.Reserve(CodeRange{8001, 8999}); err == nil {
+		t.Fatal("code outside of range was accepted")
+	}
+	if err := b.Reserve(CodeRange{8000, 8999}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Reserve(CodeRange{7100, 7499}); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := Reserved("reserve_a"); r != (CodeRange{7100, 7499}) {
+		t.Fatalf("unexpected range %s", r)
+	}
+
+	_, _, c := New("reserve_c", "c1;9000;C\nc2;9000;C\n")
+	if err := c.Reserve(CodeRange{9000, 9099}); err == nil {
+		t.Fatal("duplicate code was accepted")
+	}
+}
+
+func TestRegisterCodes(t *testing.T) {
+	// modules without a reserved range may share codes
+	New("register_d", "d1;9500;D\n")
+	New("register_e", "e1;9500;E\n")
+	if _, err := NewReserved("register_e", "e1;9500;E\n", CodeRange{9500, 9599}); err == nil {
+		t.Fatal("range with a code of another module was accepted")
+	}
+	if _, err := NewReserved("register_e", "e1;9501;E\n", CodeRange{9501, 9599}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewReserved("register_f", "f1;9550;F\n", CodeRange{9550, 9550}); err == nil {
+		t.Fatal("code inside the range of another module was accepted")
+	}
+
+	var b bytes.Buffer
+	NewWithOptions("register_f", "f1;9550;F\n", WithLogger(log.New(&b, "", 0)))
+	if !strings.Contains(b.String(), `[WARN ] module "register_f": line 1: "f1": code 9550 inside range 9501-9599 reserved by module "register_e"`) {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+
+	_, _, g := New("register_g", "g1;9450;G\n")
+	if err := g.Reserve(CodeRange{9450, 9500}); err == nil {
+		t.Fatal("range with a code of another module was accepted")
+	}
+	// a new catalog under the same name replaces the codes
+	New("register_d", "d1;9700;D\n")
+	if err := g.Reserve(CodeRange{9450, 9500}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,111 @@
+package module
+
+import "context"
+
+// KeyCount is the number of identical errors that a message of ReportAll or ReportEach stands for.
+const KeyCount = "count"
+
+// ReportAll reports errs like Report, but identical errors (with the same Error text) only once,
+// with their number in Data[KeyCount]. nil errors are skipped.
+// Use it at the end of batch jobs that accumulate failures.
+func (m *Module) ReportAll(errs ...error) {
+	m.ReportEach(func(yield func(err error) bool) {
+		for _, err := range errs {
+			if !yield(err) {
+				return
+			}
+		}
+	})
+}
+
+// ReportEach is like ReportAll for the errors of seq, which calls yield for each error
+// and stops if yield returns false:
+//
+//	m.ReportEach(func(yield func(error) bool) {
+//		for _, job := range jobs {
+//			if !yield(job.Err) {
+//				return
+//			}
+//		}
+//	})
+func (m *Module) ReportEach(seq func(yield func(err error) bool)) {
+	type group struct {
+		err error
+		n   int
+	}
+	var groups []*group
+	index := make(map[string]*group)
+	seq(func(err error) bool {
+		if err == nil {
+			return true
+		}
+		key := err.Error()
+		g, ok := index[key]
+		if !ok {
+			g = &group{err: err}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.n++
+		return true
+	})
+	for _, g := range groups {
+		if g.n > 1 {
+			m.report(g.err, KeyCount, g.n)
+		} else {
+			m.report(g.err)
+		}
+	}
+}
+
+// report reports err, with the key/value pairs of kv added to its Data.
+func (m *Module) report(err error, kv ...interface{}) {
+	r := reportable(err)
+	if !m.allow(r.Name) {
+		return
+	}
+	data := r.Data
+	rd, _ := r.Data.(map[string]interface{})
+	if _, ok := rd[KeyFormatArgs]; ok || len(kv) != 0 {
+		d := make(map[string]interface{})
+		for key, value := range rd {
+			if key != KeyFormatArgs {
+				d[key] = value
+			}
+		}
+		for i := 0; i+1 < len(kv); i += 2 {
+			d[kv[i].(string)] = kv[i+1]
+		}
+		data = d
+	}
+	m.log(context.Background(), Error, r.Name, r.Code, r.Desc, r.Link, []interface{}{data}, r.CausedBy)
+}
+
+// Reported reports err like Report and returns it, to log and return an error in one expression:
+//
+//	return m.Reported(doThing())
+func (m *Module) Reported(err error) error {
+	m.Report(err)
+	return err
+}
+
+// KeyFunction is the function that returned the error reported by ReportP.
+const KeyFunction = "function"
+
+// ReportP reports the error that errp points to, if any, with the calling function in Data[KeyFunction].
+// Use it with defer and a named error result:
+//
+//	func sync() (err error) {
+//		defer m.ReportP(&err)
+//		...
+//	}
+func (m *Module) ReportP(errp *error) {
+	if errp == nil || *errp == nil {
+		return
+	}
+	var function string
+	if stack := stackOf(1); len(stack) != 0 {
+		function = stack[0].Function
+	}
+	m.report(*errp, KeyFunction, function)
+}
@@ -0,0 +1,83 @@
+package module
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/halliday/go-errors"
+)
+
+func TestReportAll(t *testing.T) {
+	var b bytes.Buffer
+	_, e, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+
+	m.ReportAll(e("test2"), nil, e("test", "A", 1), e("test2"), e("test2"))
+	if want := "[ERR  ] This is a another test message count=3\n[ERR  ] This is a test message A=1\n"; b.String() != want {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+
+	b.Reset()
+	m.ReportEach(func(yield func(error) bool) {
+		for _, err := range []error{errors.New("a"), errors.New("b"), errors.New("c")} {
+			if !yield(err) {
+				return
+			}
+			if err.Error() == "b" {
+				return
+			}
+		}
+	})
+	if want := "[ERR  ] a\n[ERR  ] b\n"; b.String() != want {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+}
+
+func TestReported(t *testing.T) {
+	var b bytes.Buffer
+	_, e, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+
+	err := e("test2")
+	if m.Reported(err) != err || m.Reported(nil) != nil {
+		t.Fatal("Reported did not return its error")
+	}
+	if want := "[ERR  ] This is a another test message\n"; b.String() != want {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+}
+
+func reportPFails(m *Module) (err error) {
+	defer m.ReportP(&err)
+	return errors.New("failed")
+}
+
+func reportPSucceeds(m *Module) (err error) {
+	defer m.ReportP(&err)
+	return nil
+}
+
+func TestReportP(t *testing.T) {
+	var b bytes.Buffer
+	_, _, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+	var function interface{}
+	m.Hook = func(msg *Message) *Message {
+		function = msg.Data[KeyFunction]
+		return msg
+	}
+
+	if reportPSucceeds(m) != nil || b.Len() != 0 {
+		t.Fatal("reported without error")
+	}
+	if reportPFails(m) == nil {
+		t.Fatal("error was lost")
+	}
+	if function != "github.com/halliday/go-module.reportPFails" {
+		t.Fatalf("unexpected function %v", function)
+	}
+	if want := "[ERR  ] failed function=github.com/halliday/go-module.reportPFails\n"; b.String() != want {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+}
@@ -0,0 +1,110 @@
+package module
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/halliday/go-errors"
+)
+
+type RetryOptions struct {
+	// Attempts is the maximum number of writes, including the first one. Default 3.
+	Attempts int
+	// Backoff is the delay before the first retry, doubled for every further retry. Default 100ms.
+	Backoff time.Duration
+	// MaxBackoff limits the delay between retries. Default 10s.
+	MaxBackoff time.Duration
+	// MaxWait limits the total delay of a Write, which blocks the logging caller. A retry that would
+	// exceed it is not made. Default 10s.
+	MaxWait time.Duration
+	// Jitter randomizes every delay by up to ±Jitter (0 to 1) of its length.
+	Jitter float64
+	// Context aborts pending retries when done, e.g. on shutdown.
+	Context context.Context
+	// Clock is used for the delays. Default SystemClock.
+	Clock Clock
+}
+
+// RetrySink wraps a Sink and retries failed writes with exponential backoff.
+// Errors marked with Permanent are not retried. Write waits between the attempts, which blocks
+// the logging caller for up to MaxWait in total.
+type RetrySink struct {
+	sink Sink
+	opts RetryOptions
+}
+
+func NewRetrySink(sink Sink, opts RetryOptions) *RetrySink {
+	if opts.Attempts <= 0 {
+		opts.Attempts = 3
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Second
+	}
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = 10 * time.Second
+	}
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
+	opts.Clock = clockOrSystem(opts.Clock)
+	return &RetrySink{sink: sink, opts: opts}
+}
+
+func (s *RetrySink) Write(msg *Message) error {
+	backoff := s.opts.Backoff
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		err := s.sink.Write(msg)
+		if err == nil || attempt >= s.opts.Attempts || IsPermanent(err) {
+			return err
+		}
+		delay := backoff
+		if s.opts.Jitter > 0 {
+			delay += time.Duration((rand.Float64()*2 - 1) * s.opts.Jitter * float64(delay))
+		}
+		if waited += delay; waited > s.opts.MaxWait {
+			return err
+		}
+		done := make(chan struct{})
+		timer := s.opts.Clock.AfterFunc(delay, func() { close(done) })
+		select {
+		case <-done:
+		case <-s.opts.Context.Done():
+			timer.Stop()
+			return errors.Join(err, s.opts.Context.Err())
+		}
+		backoff *= 2
+		if backoff > s.opts.MaxBackoff {
+			backoff = s.opts.MaxBackoff
+		}
+	}
+}
+
+type permanentError struct {
+	error
+}
+
+func (err permanentError) Unwrap() error {
+	return err.error
+}
+
+// Permanent marks err as not retryable for RetrySink.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+func IsPermanent(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if _, ok := err.(permanentError); ok {
+			return true
+		}
+	}
+	return false
+}
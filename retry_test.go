@@ -0,0 +1,76 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetrySink(t *testing.T) {
+	attempts := 0
+	var failure error
+	sink := NewRetrySink(SinkFunc(func(msg *Message) error {
+		attempts++
+		if attempts < 3 {
+			return failure
+		}
+		return nil
+	}), RetryOptions{Attempts: 3, Backoff: time.Millisecond, Jitter: 0.5})
+
+	failure = errors.New("unavailable")
+	if err := sink.Write(&Message{}); err != nil || attempts != 3 {
+		t.Fatalf("unexpected result after %d attempts: %v", attempts, err)
+	}
+
+	attempts = 0
+	failure = Permanent(errors.New("bad request"))
+	if err := sink.Write(&Message{}); !IsPermanent(err) || attempts != 1 {
+		t.Fatalf("permanent error was retried %d times: %v", attempts, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	failure = errors.New("unavailable")
+	sink = NewRetrySink(sink.sink, RetryOptions{Attempts: 5, Backoff: time.Hour, Context: ctx})
+	if err := sink.Write(&Message{}); err == nil || attempts != 1 {
+		t.Fatalf("canceled retry: %d attempts: %v", attempts, err)
+	}
+}
+
+func TestRetrySinkClock(t *testing.T) {
+	clock := NewManualClock(time.Time{})
+	attempts := 0
+	sink := NewRetrySink(SinkFunc(func(msg *Message) error {
+		attempts++
+		return errors.New("unavailable")
+	}), RetryOptions{Attempts: 10, Backoff: time.Second, MaxWait: 4 * time.Second, Clock: clock})
+
+	done := make(chan error)
+	go func() { done <- sink.Write(&Message{}) }()
+	for {
+		select {
+		case err := <-done:
+			// waits of 1s and 2s, a third of 4s would exceed MaxWait
+			if err == nil || attempts != 3 {
+				t.Fatalf("unexpected result after %d attempts: %v", attempts, err)
+			}
+			if now := clock.Now(); now != (time.Time{}).Add(3*time.Second) {
+				t.Fatalf("waited until %s", now)
+			}
+			return
+		default:
+			var next time.Duration
+			clock.mu.Lock()
+			if len(clock.timers) > 0 {
+				next = clock.timers[0].at.Sub(clock.now)
+			}
+			clock.mu.Unlock()
+			if next > 0 {
+				clock.Advance(next)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Package rfc5424 formats module messages as RFC 5424 syslog records, with the fields and the data
+// of a message as two elements of structured data that syslog parsers (rsyslog mmpstrucdata,
+// syslog-ng, Graylog) recover field by field:
+//
+//	<12>1 2022-11-17T11:49:04.123Z web-1 shop 4711 login_failed [module@32473 module="auth" level="warn" code="1001" id="01GJ3Q2V4R8N6YH3T2KXW5Z9CD"][data@32473 user="bob"] Login failed for user bob
+//
+// The records can be sent with a module.SocketSink:
+//
+//	enc := rfc5424.New(rfc5424.Options{SDID: "module@12345"})
+//	sink := module.NewSocketSink("udp", "syslog:514", module.SocketSinkOptions{Marshal: enc.Marshal})
+//
+// Data values that are not strings are JSON encoded. Values may contain newlines, so
+// newline framed transports (TCP without octet counting) should not be used with such data.
+package rfc5424
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	module "github.com/halliday/go-module"
+)
+
+// DefaultSDID is the SD-ID of the structured data if Options.SDID is empty. 32473 is the
+// private enterprise number reserved for documentation (RFC 5612); organizations with their
+// own number should use it.
+const DefaultSDID = "module@32473"
+
+type Options struct {
+	// Facility is the syslog facility. Default 1 (user-level); 16-23 are local0 to local7.
+	Facility int
+	// Hostname is the HOSTNAME of the records. Default os.Hostname.
+	Hostname string
+	// AppName is the APP-NAME of the records. Default the base name of the executable.
+	AppName string
+	// SDID is the SD-ID of the fields of a message, "name@<private enterprise number>". Default DefaultSDID.
+	SDID string
+	// DataSDID is the SD-ID of the data of a message. Default "data@" with the enterprise number of SDID.
+	DataSDID string
+}
+
+// Encoder formats messages as RFC 5424 records.
+type Encoder struct {
+	opts   Options
+	procID string
+}
+
+// New returns an Encoder for opts. It panics if the facility or the SD-IDs are invalid.
+func New(opts Options) *Encoder {
+	if opts.Facility == 0 {
+		opts.Facility = 1
+	}
+	if opts.Facility < 0 || opts.Facility > 23 {
+		panic("rfc5424.New: invalid facility " + strconv.Itoa(opts.Facility))
+	}
+	if opts.SDID == "" {
+		opts.SDID = DefaultSDID
+	}
+	if !validSDID(opts.SDID) {
+		panic("rfc5424.New: invalid SD-ID " + strconv.Quote(opts.SDID))
+	}
+	if opts.DataSDID == "" {
+		opts.DataSDID = "data" + opts.SDID[strings.IndexByte(opts.SDID, '@'):]
+	}
+	if !validSDID(opts.DataSDID) || opts.DataSDID == opts.SDID {
+		panic("rfc5424.New: invalid data SD-ID " + strconv.Quote(opts.DataSDID))
+	}
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+	if opts.AppName == "" {
+		opts.AppName = filepath.Base(os.Args[0])
+	}
+	return &Encoder{opts: opts, procID: strconv.Itoa(os.Getpid())}
+}
+
+// Severity returns the syslog severity of level.
+func Severity(level module.Level) int {
+	switch level {
+	case module.Trace, module.Debug:
+		return 7
+	case module.Info:
+		return 6
+	case module.Warn:
+		return 4
+	case module.Error:
+		return 3
+	}
+	return 5 // notice
+}
+
+var newlines = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+// Marshal returns the record of msg. The MSGID is the name of the message.
+// It fails with a module.Permanent error if a data key can not be encoded, see Name.
+func (e *Encoder) Marshal(msg *module.Message) ([]byte, error) {
+	b := make([]byte, 0, 256)
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(e.opts.Facility*8+Severity(msg.Level)), 10)
+	b = append(b, ">1 "...)
+	b = msg.Time.UTC().AppendFormat(b, "2006-01-02T15:04:05.999999Z07:00")
+	b = append(b, ' ')
+	b = appendHeader(b, e.opts.Hostname, 255)
+	b = append(b, ' ')
+	b = appendHeader(b, e.opts.AppName, 48)
+	b = append(b, ' ')
+	b = appendHeader(b, e.procID, 128)
+	b = append(b, ' ')
+	var name string
+	if msg.RichError != nil {
+		name = msg.Name
+	}
+	b = appendHeader(b, name, 32)
+	b = append(b, ' ')
+	sd, err := e.StructuredData(msg)
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, sd...)
+	if msg.RichError != nil && msg.Desc != "" {
+		b = append(b, ' ')
+		b = append(b, newlines.Replace(msg.Desc)...)
+	}
+	return b, nil
+}
+
+// StructuredData returns the SD-ELEMENT of msg with the parameters module, level, code, id, caller
+// and caused_by, followed by the SD-ELEMENT of its data with the keys (see Name) in the order of msg.Keys.
+func (e *Encoder) StructuredData(msg *module.Message) (string, error) {
+	var b strings.Builder
+	b.WriteByte('[')
+	b.WriteString(e.opts.SDID)
+	writeParam(&b, "module", msg.Module)
+	writeParam(&b, "level", msg.Level.String())
+	if msg.RichError != nil {
+		if msg.Code != 0 {
+			writeParam(&b, "code", strconv.Itoa(msg.Code))
+		}
+		if msg.CausedBy != nil {
+			writeParam(&b, "caused_by", msg.CausedBy.Error())
+		}
+	}
+	if msg.ID != "" {
+		writeParam(&b, "id", msg.ID)
+	}
+	if msg.Caller != "" {
+		writeParam(&b, "caller", msg.Caller)
+	}
+	b.WriteByte(']')
+	keys := msg.Keys()
+	if len(keys) == 0 {
+		return b.String(), nil
+	}
+	b.WriteByte('[')
+	b.WriteString(e.opts.DataSDID)
+	for _, key := range keys {
+		name, err := Name(key)
+		if err != nil {
+			return "", module.Permanent(err)
+		}
+		var value string
+		switch v := msg.Data[key].(type) {
+		case string:
+			value = v
+		case time.Time:
+			value = v.Format(time.RFC3339Nano)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return "", fmt.Errorf("data %s: %w", key, err)
+			}
+			value = string(data)
+		}
+		writeParam(&b, name, value)
+	}
+	b.WriteByte(']')
+	return b.String(), nil
+}
+
+// writeParam writes ` name="value"` with '"', '\' and ']' escaped and invalid UTF-8 replaced.
+func writeParam(b *strings.Builder, name, value string) {
+	b.WriteByte(' ')
+	b.WriteString(name)
+	b.WriteString(`="`)
+	for _, r := range value {
+		switch r {
+		case '"', '\\', ']':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+}
+
+// Name returns key as an SD-NAME. Printable US-ASCII characters are kept, except '=', ']', '"' and '%';
+// other bytes are percent-encoded, so that url.PathUnescape recovers the key, e.g. "user%20id" for "user id".
+// It fails for an empty key and for names longer than 32 characters.
+func Name(key string) (string, error) {
+	if key == "" {
+		return "", errors.New("empty data key")
+	}
+	const hex = "0123456789ABCDEF"
+	b := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' || c == '%' {
+			b = append(b, '%', hex[c>>4], hex[c&15])
+		} else {
+			b = append(b, c)
+		}
+	}
+	if len(b) > 32 {
+		return "", fmt.Errorf("data key %q longer than 32 characters as SD-NAME %s", key, b)
+	}
+	return string(b), nil
+}
+
+// validSDID reports whether id is an SD-ID "name@<private enterprise number>".
+func validSDID(id string) bool {
+	i := strings.IndexByte(id, '@')
+	if i <= 0 || i == len(id)-1 || len(id) > 32 {
+		return false
+	}
+	for j := 0; j < len(id); j++ {
+		c := id[j]
+		if c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' || (c == '@' && j != i) || (j > i && (c < '0' || c > '9') && c != '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// appendHeader appends a header field of at most n printable US-ASCII characters, or "-" if it is empty.
+func appendHeader(b []byte, s string, n int) []byte {
+	if s == "" {
+		return append(b, '-')
+	}
+	for i := 0; i < len(s) && i < n; i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return b
+}
@@ -0,0 +1,89 @@
+package rfc5424
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+func TestMarshal(t *testing.T) {
+	e := New(Options{Facility: 16, Hostname: "web-1", AppName: "shop", SDID: "module@12345"})
+	e.procID = "4711"
+	msg := &module.Message{
+		Module:    "auth",
+		Level:     module.Warn,
+		Time:      time.Date(2022, 11, 17, 12, 49, 4, 123456789, time.FixedZone("CET", 3600)),
+		ID:        "01GJ3Q2V4R8N6YH3T2KXW5Z9CD",
+		RichError: errors.NewRich("login_failed", 1001, "Login failed\nfor user bob", "", nil, nil),
+		Data: map[string]interface{}{
+			"user":      "bob",
+			"query":     `a="b]" \c`,
+			"attempts":  3,
+			"tags":      []string{"x"},
+			"bad key=1": true,
+		},
+	}
+	data, err := e.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<132>1 2022-11-17T11:49:04.123456Z web-1 shop 4711 login_failed [module@12345 module="auth" level="warn" code="1001" id="01GJ3Q2V4R8N6YH3T2KXW5Z9CD"]` +
+		`[data@12345 attempts="3" bad%20key%3D1="true" query="a=\"b\]\" \\c" tags="[\"x\"\]" user="bob"] Login failed for user bob`
+	if string(data) != want {
+		t.Fatalf("Marshal =\n%s\nwant\n%s", data, want)
+	}
+
+	data, _ = e.Marshal(&module.Message{Module: "billing", Level: module.Info, Time: msg.Time})
+	if !strings.HasPrefix(string(data), "<134>1 ") || !strings.HasSuffix(string(data), ` 4711 - [module@12345 module="billing" level="info"]`) {
+		t.Fatalf("unexpected %s", data)
+	}
+
+	msg.Data = map[string]interface{}{strings.Repeat("k", 33): 1}
+	if _, err := e.Marshal(msg); !module.IsPermanent(err) {
+		t.Fatalf("unexpected error %v for a long key", err)
+	}
+}
+
+func TestName(t *testing.T) {
+	for key, want := range map[string]string{
+		"user.name":             "user.name",
+		"module":                "module",
+		"user id":               "user%20id",
+		"user_id":               "user_id",
+		"a=b]\"%ü":              "a%3Db%5D%22%25%C3%BC",
+		strings.Repeat("k", 32): strings.Repeat("k", 32),
+	} {
+		got, err := Name(key)
+		if err != nil || got != want {
+			t.Fatalf("Name(%q) = %q, %v, want %q", key, got, err, want)
+		}
+		if k, err := url.PathUnescape(got); err != nil || k != key {
+			t.Fatalf("Name(%q) = %q is not reversible", key, got)
+		}
+	}
+	for _, key := range []string{"", strings.Repeat("k", 33), strings.Repeat("ü", 6)} {
+		if _, err := Name(key); err == nil {
+			t.Fatalf("Name(%q) did not fail", key)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, opts := range []Options{{SDID: "module"}, {SDID: "@123"}, {SDID: "my module@123"}, {SDID: "module@abc"}, {DataSDID: DefaultSDID}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic for SD-IDs %q, %q", opts.SDID, opts.DataSDID)
+				}
+			}()
+			New(opts)
+		}()
+	}
+	if e := New(Options{SDID: "app@1.2"}); e.opts.DataSDID != "data@1.2" {
+		t.Fatalf("unexpected data SD-ID %q", e.opts.DataSDID)
+	}
+}
@@ -0,0 +1,141 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/halliday/go-errors"
+)
+
+// upgrades[v] converts a decoded JSON message of schema version v to v+1,
+// downgrades[v] one of version v+1 to v.
+var (
+	upgrades   = []func(m map[string]interface{}) error{upgrade0}
+	downgrades = []func(m map[string]interface{}) error{downgrade0}
+)
+
+// ConvertJSON converts a JSON message to schema version, see SchemaVersion. Messages without
+// schema_version are version 0: the encoding of Message by encoding/json before versioning, with numeric
+// levels and without time. Converting to SchemaVersion returns the encoding of Message.MarshalJSON, so
+// stored messages can be upgraded; older versions are for consumers that were not updated yet.
+// Fields that a version does not have are lost.
+func ConvertJSON(data []byte, version int) ([]byte, error) {
+	if version < 0 || version > SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema_version %d", version)
+	}
+	var m map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	v, err := schemaVersion(m)
+	if err != nil {
+		return nil, err
+	}
+	if v == version {
+		return data, nil
+	}
+	for ; v < version; v++ {
+		if err := upgrades[v](m); err != nil {
+			return nil, fmt.Errorf("schema_version %d to %d: %w", v, v+1, err)
+		}
+	}
+	for ; v > version; v-- {
+		if err := downgrades[v-1](m); err != nil {
+			return nil, fmt.Errorf("schema_version %d to %d: %w", v, v-1, err)
+		}
+	}
+	if data, err = json.Marshal(m); err != nil {
+		return nil, err
+	}
+	if version == SchemaVersion {
+		// restore the field order of MarshalJSON
+		var msg Message
+		if err := msg.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		return msg.MarshalJSON()
+	}
+	return data, nil
+}
+
+func schemaVersion(m map[string]interface{}) (int, error) {
+	n, ok := m["schema_version"].(json.Number)
+	if !ok {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(n.String())
+	if err != nil || v < 0 || v > SchemaVersion {
+		return 0, fmt.Errorf("unsupported schema_version %s", n)
+	}
+	return v, nil
+}
+
+// upgrade0 converts numeric levels to names, adds a zero time and turns the nested causedBy into caused_by.
+func upgrade0(m map[string]interface{}) error {
+	m["schema_version"] = 1
+	if n, ok := m["level"].(json.Number); ok {
+		l, err := strconv.Atoi(n.String())
+		if err != nil {
+			return err
+		}
+		s := Level(l).String()
+		if _, err := ParseLevel(s); err != nil {
+			return fmt.Errorf("unknown level %d", l)
+		}
+		m["level"] = s
+	}
+	if _, ok := m["time"]; !ok {
+		m["time"] = "0001-01-01T00:00:00Z"
+	}
+	if c, ok := m["causedBy"].(map[string]interface{}); ok {
+		m["caused_by"] = causeChain(nestedCause(c))
+	}
+	delete(m, "causedBy")
+	return nil
+}
+
+// nestedCause returns the error of a causedBy of version 0.
+func nestedCause(c map[string]interface{}) error {
+	name, _ := c["name"].(string)
+	desc, _ := c["desc"].(string)
+	link, _ := c["link"].(string)
+	var code int
+	if n, ok := c["code"].(json.Number); ok {
+		code, _ = strconv.Atoi(n.String())
+	}
+	var next error
+	if c, ok := c["causedBy"].(map[string]interface{}); ok {
+		next = nestedCause(c)
+	}
+	return errors.NewRich(name, code, desc, link, nil, next)
+}
+
+// downgrade0 converts level names to numbers, caused_by to a nested causedBy
+// and removes the fields that version 0 did not have.
+func downgrade0(m map[string]interface{}) error {
+	if s, ok := m["level"].(string); ok {
+		l, err := ParseLevel(s)
+		if err != nil {
+			return err
+		}
+		m["level"] = int(l)
+	}
+	if chain, ok := m["caused_by"]; ok {
+		data, _ := json.Marshal(chain)
+		var causes []jsonCause
+		if err := json.Unmarshal(data, &causes); err != nil {
+			return err
+		}
+		if err := decodeCauses(causes); err != nil {
+			m["causedBy"] = errors.Rich(err)
+		}
+	}
+	for _, key := range []string{"schema_version", "time", "seq", "epoch", "id", "caller", "frames", "caused_by", "sig"} {
+		delete(m, key)
+	}
+	return nil
+}
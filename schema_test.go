@@ -0,0 +1,57 @@
+package module
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// v0 is a message encoded by encoding/json before schema versions.
+const v0 = `{"module":"auth","level":8,"name":"login_failed","code":1001,"desc":"Login failed","data":{"user":"bob"},"causedBy":{"name":"db_timeout","code":1500,"desc":"Timeout","causedBy":{"name":"eof"}}}`
+
+func TestConvertJSON(t *testing.T) {
+	data, err := ConvertJSON([]byte(v0), SchemaVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"schema_version":1,"time":"0001-01-01T00:00:00Z","module":"auth","level":"warn","name":"login_failed","code":1001,"desc":"Login failed","data":{"user":"bob"},` +
+		`"caused_by":[{"name":"db_timeout","code":1500,"desc":"Timeout","error":"1500 db_timeout Timeout (0 eof)"},{"name":"eof","error":"0 eof"}]}`
+	if string(data) != want {
+		t.Fatalf("unexpected upgrade:\n%s", data)
+	}
+
+	data, err = ConvertJSON(data, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, expected interface{}
+	json.Unmarshal(data, &got)
+	json.Unmarshal([]byte(v0), &expected)
+	if a, b := mustJSON(got), mustJSON(expected); a != b {
+		t.Fatalf("unexpected downgrade:\n%s\n%s", a, b)
+	}
+
+	if _, err := ConvertJSON([]byte(`{"schema_version":2}`), SchemaVersion); err == nil {
+		t.Fatal("expected error for a future version")
+	}
+	if _, err := ConvertJSON([]byte(`{"level":3}`), SchemaVersion); err == nil {
+		t.Fatal("expected error for an unknown level")
+	}
+}
+
+func TestUnmarshalJSONVersion0(t *testing.T) {
+	var msg Message
+	if err := json.Unmarshal([]byte(v0), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Level != Warn || msg.Name != "login_failed" || msg.Data["user"] != "bob" || NamedError(msg.CausedBy) == nil {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
+
+func mustJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,18 @@
+package module
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Epoch identifies this process. Together with Module and Seq it orders messages and reveals
+// gaps, even across restarts where sequence numbers start again at 1.
+var Epoch = newEpoch()
+
+func newEpoch() string {
+	var b [4]byte
+	rand.Read(b[:])
+	return strconv.FormatInt(time.Now().Unix(), 36) + "-" + hex.EncodeToString(b[:])
+}
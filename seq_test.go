@@ -0,0 +1,39 @@
+package module
+
+import (
+	"bytes"
+	"log"
+	"sync"
+	"testing"
+)
+
+func TestSeq(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[uint64]bool)
+	m := NewWithOptions("module", messages, WithLogger(log.New(&bytes.Buffer{}, "", 0)), WithSinks(SinkFunc(func(msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if msg.Epoch != Epoch || seen[msg.Seq] {
+			t.Errorf("bad sequence number %s/%d", msg.Epoch, msg.Seq)
+		}
+		seen[msg.Seq] = true
+		return nil
+	})))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				m.Info("test")
+			}
+		}()
+	}
+	wg.Wait()
+	for seq := uint64(1); seq <= 100; seq++ {
+		if !seen[seq] {
+			t.Fatalf("missing sequence number %d", seq)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package siem
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	module "github.com/halliday/go-module"
+)
+
+// CEF formats messages as ArcSight Common Event Format (CEF:0) records:
+//
+//	CEF:0|Acme|Shop|2.4|login_failed|Login failed for user bob|5|rt=1668685744123 cat=auth externalId=01GJ3Q2V4R8N6YH3T2KXW5Z9CD suser=bob
+//
+// The signature ID is the name of the message, the severity is Options.Severity, rt is the time,
+// cat the module, externalId the ID and msg the error of the causes.
+type CEF struct {
+	opts Options
+}
+
+func NewCEF(opts Options) *CEF {
+	opts.defaults()
+	return &CEF{opts: opts}
+}
+
+var (
+	cefHeader    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtension = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+// Marshal returns the CEF record of msg.
+func (c *CEF) Marshal(msg *module.Message) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("CEF:0|")
+	for _, h := range []string{c.opts.Vendor, c.opts.Product, c.opts.Version, eventID(msg), name(msg)} {
+		cefHeader.WriteString(&b, h)
+		b.WriteByte('|')
+	}
+	b.WriteString(strconv.Itoa(c.opts.Severity(msg.Level)))
+	b.WriteString("|rt=")
+	b.WriteString(strconv.FormatInt(msg.Time.UnixMilli(), 10))
+	writeCEF(&b, "cat", msg.Module)
+	writeCEF(&b, "externalId", msg.ID)
+	if msg.RichError != nil && msg.CausedBy != nil {
+		writeCEF(&b, "msg", msg.CausedBy.Error())
+	}
+	for _, key := range msg.Keys() {
+		writeCEF(&b, c.opts.field(key), fmt.Sprint(msg.Data[key]))
+	}
+	return []byte(b.String()), nil
+}
+
+func writeCEF(b *strings.Builder, key, value string) {
+	if key == "" || value == "" {
+		return
+	}
+	b.WriteByte(' ')
+	b.WriteString(key)
+	b.WriteByte('=')
+	cefExtension.WriteString(b, value)
+}
+
+// name returns the description of msg, or its name or module if it has none.
+func name(msg *module.Message) string {
+	if msg.RichError != nil && msg.Desc != "" {
+		return msg.Desc
+	}
+	return eventID(msg)
+}
@@ -0,0 +1,67 @@
+package siem
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	module "github.com/halliday/go-module"
+)
+
+// LEEF formats messages as IBM QRadar Log Event Extended Format (LEEF:2.0) records with tab-separated attributes:
+//
+//	LEEF:2.0|Acme|Shop|2.4|login_failed|x09|devTime=Nov 17 2022 11:49:04.123 UTC	sev=5	cat=auth	usrName=bob
+//
+// The event ID is the name of the message, sev is Options.Severity, devTime the time, cat the module,
+// externalId the ID, msg the description and reason the error of the causes.
+type LEEF struct {
+	opts Options
+}
+
+func NewLEEF(opts Options) *LEEF {
+	opts.defaults()
+	return &LEEF{opts: opts}
+}
+
+// leefTime is the default devTimeFormat "MMM dd yyyy HH:mm:ss.SSS zzz".
+const leefTime = "Jan 02 2006 15:04:05.000 MST"
+
+var (
+	leefHeader    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\t", " ", "\n", " ", "\r", " ")
+	leefAttribute = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+)
+
+// Marshal returns the LEEF record of msg.
+func (l *LEEF) Marshal(msg *module.Message) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("LEEF:2.0|")
+	for _, h := range []string{l.opts.Vendor, l.opts.Product, l.opts.Version, eventID(msg)} {
+		leefHeader.WriteString(&b, h)
+		b.WriteByte('|')
+	}
+	b.WriteString("x09|devTime=")
+	b.WriteString(msg.Time.UTC().Format(leefTime))
+	writeLEEF(&b, "sev", strconv.Itoa(l.opts.Severity(msg.Level)))
+	writeLEEF(&b, "cat", msg.Module)
+	writeLEEF(&b, "externalId", msg.ID)
+	if msg.RichError != nil {
+		writeLEEF(&b, "msg", msg.Desc)
+		if msg.CausedBy != nil {
+			writeLEEF(&b, "reason", msg.CausedBy.Error())
+		}
+	}
+	for _, key := range msg.Keys() {
+		writeLEEF(&b, l.opts.field(key), fmt.Sprint(msg.Data[key]))
+	}
+	return []byte(b.String()), nil
+}
+
+func writeLEEF(b *strings.Builder, key, value string) {
+	if key == "" || value == "" {
+		return
+	}
+	b.WriteByte('\t')
+	b.WriteString(key)
+	b.WriteByte('=')
+	leefAttribute.WriteString(b, value)
+}
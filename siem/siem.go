@@ -0,0 +1,77 @@
+// Package siem formats module messages as ArcSight CEF and IBM QRadar LEEF records,
+// for feeding audit events into a SIEM. The records are usually sent by syslog or a socket:
+//
+//	cef := siem.NewCEF(siem.Options{Vendor: "Acme", Product: "Shop", Version: "2.4",
+//		Fields: map[string]string{"user": "suser", "ip": "src"}})
+//	sink := module.NewSocketSink("tcp", "arcsight:514", module.SocketSinkOptions{Marshal: cef.Marshal})
+//
+// Data keys are mapped to the keys of the format by Options.Fields; keys without a mapping keep their
+// name, with characters other than letters and digits removed.
+package siem
+
+import (
+	"strings"
+
+	module "github.com/halliday/go-module"
+)
+
+type Options struct {
+	// Vendor, Product and Version identify the device. Default "halliday", "go-module" and "1".
+	Vendor  string
+	Product string
+	Version string
+	// Fields maps data keys to the keys of the format, like "user" to "suser" (CEF) or "usrName" (LEEF).
+	// A key mapped to "" is left out.
+	Fields map[string]string
+	// Severity returns the severity (0-10) of a level, replacing DefaultSeverity.
+	Severity func(level module.Level) int
+}
+
+func (opts *Options) defaults() {
+	if opts.Vendor == "" {
+		opts.Vendor = "halliday"
+	}
+	if opts.Product == "" {
+		opts.Product = "go-module"
+	}
+	if opts.Version == "" {
+		opts.Version = "1"
+	}
+	if opts.Severity == nil {
+		opts.Severity = DefaultSeverity
+	}
+}
+
+// DefaultSeverity returns 8 (high) for errors, 5 (medium) for warnings, 3 (low) for infos and 1 else.
+func DefaultSeverity(level module.Level) int {
+	switch level {
+	case module.Error:
+		return 8
+	case module.Warn:
+		return 5
+	case module.Info:
+		return 3
+	}
+	return 1
+}
+
+// field returns the key of a data key, or "" if it is left out.
+func (opts *Options) field(key string) string {
+	if f, ok := opts.Fields[key]; ok {
+		return f
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, key)
+}
+
+// eventID returns the name of msg, or its module if it has none.
+func eventID(msg *module.Message) string {
+	if msg.RichError != nil && msg.Name != "" {
+		return msg.Name
+	}
+	return msg.Module
+}
@@ -0,0 +1,53 @@
+package siem
+
+import (
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+)
+
+var msg = &module.Message{
+	Module:    "auth",
+	Level:     module.Warn,
+	Time:      time.Date(2022, 11, 17, 11, 49, 4, 123000000, time.UTC),
+	ID:        "01GJ3Q2V4R8N6YH3T2KXW5Z9CD",
+	RichError: errors.NewRich("login_failed", 1001, "Login failed | user=bob", "", nil, errors.New("bad\npassword")),
+	Data:      map[string]interface{}{"user": "bob", "ip": "10.0.0.1", "secret": "x", "query": "a=b\\c\td"},
+}
+
+var opts = Options{Vendor: "Acme", Product: "Shop", Version: "2.4"}
+
+func TestCEF(t *testing.T) {
+	opts := opts
+	opts.Fields = map[string]string{"user": "suser", "ip": "src", "secret": ""}
+	data, err := NewCEF(opts).Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `CEF:0|Acme|Shop|2.4|login_failed|Login failed \| user=bob|5|rt=1668685744123 cat=auth externalId=01GJ3Q2V4R8N6YH3T2KXW5Z9CD msg=bad\npassword src=10.0.0.1 query=a\=b\\c` + "\td suser=bob"
+	if string(data) != want {
+		t.Fatalf("CEF =\n%s\nwant\n%s", data, want)
+	}
+
+	data, _ = NewCEF(Options{}).Marshal(&module.Message{Module: "billing", Level: module.Error, Time: msg.Time})
+	if string(data) != "CEF:0|halliday|go-module|1|billing|billing|8|rt=1668685744123 cat=billing" {
+		t.Fatalf("unexpected %s", data)
+	}
+}
+
+func TestLEEF(t *testing.T) {
+	opts := opts
+	opts.Fields = map[string]string{"user": "usrName", "ip": "src", "secret": ""}
+	opts.Severity = func(module.Level) int { return 10 }
+	data, err := NewLEEF(opts).Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "LEEF:2.0|Acme|Shop|2.4|login_failed|x09|devTime=Nov 17 2022 11:49:04.123 UTC\tsev=10\tcat=auth\texternalId=01GJ3Q2V4R8N6YH3T2KXW5Z9CD" +
+		"\tmsg=Login failed | user=bob\treason=bad password\tsrc=10.0.0.1\tquery=a=b\\c d\tusrName=bob"
+	if string(data) != want {
+		t.Fatalf("LEEF =\n%q\nwant\n%q", data, want)
+	}
+}
@@ -0,0 +1,89 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSigner(t *testing.T) {
+	key := []byte("secret")
+	var records bytes.Buffer
+	l, _, m := New("module", messages)
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+	m.Signer = NewSigner(key)
+	m.Sinks = []Sink{SinkFunc(func(msg *Message) error {
+		data, err := json.Marshal(msg)
+		records.Write(data)
+		records.WriteByte('\n')
+		return err
+	})}
+
+	l.Info("test", "A", 1)
+	l.Warn("test2", "B", "foo")
+	l.Err("test3")
+
+	if n, err := VerifyLines(bytes.NewReader(records.Bytes()), key); err != nil || n != 3 {
+		t.Fatalf("verification failed after %d records: %v", n, err)
+	}
+	if _, err := VerifyLines(bytes.NewReader(records.Bytes()), []byte("wrong")); err == nil {
+		t.Fatal("wrong key was accepted")
+	}
+
+	lines := strings.SplitAfter(records.String(), "\n")
+	if _, err := VerifyLines(strings.NewReader(lines[0]+lines[2]), key); err == nil {
+		t.Fatal("removed record was not detected")
+	}
+	tampered := strings.Replace(records.String(), `"A":1`, `"A":2`, 1)
+	if _, err := VerifyLines(strings.NewReader(tampered), key); err == nil {
+		t.Fatal("modified record was not detected")
+	}
+}
+
+func TestSignerConcurrent(t *testing.T) {
+	key := []byte("secret")
+	var records, flaky bytes.Buffer
+	var n int
+	l, _, m := New("module", messages)
+	m.Logger = log.New(&bytes.Buffer{}, "", 0)
+	m.Signer = NewSigner(key)
+	// not safe for concurrent use, the Signer serializes the writes
+	write := func(b *bytes.Buffer, msg *Message) error {
+		data, err := json.Marshal(msg)
+		b.Write(data)
+		b.WriteByte('\n')
+		return err
+	}
+	m.Sinks = []Sink{SinkFunc(func(msg *Message) error {
+		return write(&records, msg)
+	}), SinkFunc(func(msg *Message) error {
+		if n++; n%7 == 0 {
+			return errors.New("unavailable")
+		}
+		return write(&flaky, msg)
+	})}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				l.Info("test", "A", i*100+j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// a failing sink does not break the chain of the others
+	if got, err := VerifyLines(bytes.NewReader(records.Bytes()), key); err != nil || got != 400 {
+		t.Fatalf("verification failed after %d of 400 records: %v", got, err)
+	}
+	if got, err := VerifyLines(bytes.NewReader(flaky.Bytes()), key); err == nil || got != 6 {
+		t.Fatalf("missing record was not detected after %d records: %v", got, err)
+	}
+}
@@ -0,0 +1,58 @@
+package module
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/halliday/go-errors"
+)
+
+// A Sink receives every Message of a Module after all hooks ran.
+type Sink interface {
+	Write(msg *Message) error
+}
+
+// Named is implemented by sinks and batch writers that have a name, e.g. "audit-archive".
+// It identifies them in metrics and in "sink_panic" messages instead of their type.
+type Named interface {
+	Name() string
+}
+
+type SinkFunc func(msg *Message) error
+
+func (f SinkFunc) Write(msg *Message) error {
+	return f(msg)
+}
+
+func (m *Module) writeSinks(ctx context.Context, msg *Message) error {
+	var errs errors.Multi
+	for i, sink := range m.Sinks {
+		err := m.writeSink(ctx, i, sink, msg)
+		m.trackHealth(i, sink, err)
+		if err != nil {
+			m.logger().Println("[ERR  ] sink failed: " + err.Error())
+			errs.Append(err)
+		}
+	}
+	for _, sink := range msg.routes {
+		if err := m.writeSink(ctx, -1, sink, msg); err != nil {
+			m.logger().Println("[ERR  ] sink failed: " + err.Error())
+			errs.Append(err)
+		}
+	}
+	return errs.Reduce()
+}
+
+// sinkKey returns the name of the i-th sink, followed by "#i" if an earlier sink has the same name,
+// so that sinks of the same type have their own metrics.
+func (m *Module) sinkKey(i int, sink Sink) string {
+	name := sinkName(sink)
+	if i > 0 && i < len(m.Sinks) {
+		for _, other := range m.Sinks[:i] {
+			if sinkName(other) == name {
+				return name + "#" + strconv.Itoa(i)
+			}
+		}
+	}
+	return name
+}
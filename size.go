@@ -0,0 +1,44 @@
+package module
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// sizeStats keeps a rolling average of the rendered size of messages per name,
+// so builders can be grown once to the expected size.
+type sizeStats struct {
+	m sync.Map // name -> *uint64, average size in bytes
+}
+
+// sizeShift weights a new size by 1/8 in the rolling average.
+const sizeShift = 3
+
+// hint returns the expected size of a message named name, or min if there is no average yet.
+func (s *sizeStats) hint(name string, min int) int {
+	if v, ok := s.m.Load(name); ok {
+		if avg := int(atomic.LoadUint64(v.(*uint64))); avg > min {
+			return avg
+		}
+	}
+	return min
+}
+
+// observe adds the rendered size n of a message named name to the average.
+// Concurrent updates may get lost, which only affects the accuracy of the hint.
+func (s *sizeStats) observe(name string, n int) {
+	v, ok := s.m.Load(name)
+	if !ok {
+		avg := uint64(n)
+		v, _ = s.m.LoadOrStore(name, &avg)
+		return
+	}
+	p := v.(*uint64)
+	avg := atomic.LoadUint64(p)
+	if uint64(n) > avg {
+		avg += (uint64(n) - avg) >> sizeShift
+	} else {
+		avg -= (avg - uint64(n)) >> sizeShift
+	}
+	atomic.StoreUint64(p, avg)
+}
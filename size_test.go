@@ -0,0 +1,24 @@
+package module
+
+import "testing"
+
+func TestSizeStats(t *testing.T) {
+	var s sizeStats
+	if n := s.hint("big", 16); n != 16 {
+		t.Fatalf("hint without average: %d", n)
+	}
+	s.observe("big", 800)
+	if n := s.hint("big", 16); n != 800 {
+		t.Fatalf("hint after first observation: %d", n)
+	}
+	s.observe("big", 0)
+	if n := s.hint("big", 16); n != 700 {
+		t.Fatalf("hint after second observation: %d", n)
+	}
+	if n := s.hint("big", 1000); n != 1000 {
+		t.Fatalf("hint below min: %d", n)
+	}
+	if n := s.hint("small", 16); n != 16 {
+		t.Fatalf("hint of other name: %d", n)
+	}
+}
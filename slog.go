@@ -0,0 +1,95 @@
+package module
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// UseSlog makes the Module write its messages to logger instead of the Logger, so they end up
+// in the slog handlers an application configures centrally. nil returns to the Logger.
+// It is safe while the Module is in use.
+//
+// The description is the message of the record. The attributes are "module", "name", "code" and "link"
+// (if not empty), the Data in order, "id", "caller" and "caused_by".
+// Data values of type map[string]interface{} become groups. Levels map to slog levels,
+// with None as slog.LevelInfo. The Mask applies before the level of the handler.
+func (m *Module) UseSlog(logger *slog.Logger) {
+	m.configMu.Lock()
+	m.slog = logger
+	m.configMu.Unlock()
+}
+
+func (m *Module) slogger() *slog.Logger {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	return m.slog
+}
+
+func slogLevel(l Level) slog.Level {
+	switch l {
+	case Trace:
+		return slog.LevelDebug - 4
+	case Debug:
+		return slog.LevelDebug
+	case Warn:
+		return slog.LevelWarn
+	case Error:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+func (m *Module) writeSlog(ctx context.Context, logger *slog.Logger, msg *Message) {
+	level := slogLevel(msg.Level)
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	r := slog.NewRecord(msg.Time, level, msg.Desc, 0)
+	r.AddAttrs(slog.String("module", msg.Module))
+	if msg.Name != "" {
+		r.AddAttrs(slog.String("name", msg.Name))
+	}
+	if msg.Code != 0 {
+		r.AddAttrs(slog.Int("code", msg.Code))
+	}
+	if msg.Link != "" {
+		r.AddAttrs(slog.String("link", msg.Link))
+	}
+	for _, key := range msg.Keys() {
+		r.AddAttrs(slogAttr(key, msg.Data[key]))
+	}
+	if msg.ID != "" {
+		r.AddAttrs(slog.String("id", msg.ID))
+	}
+	if msg.Caller != "" {
+		r.AddAttrs(slog.String("caller", msg.Caller))
+	}
+	if msg.CausedBy != nil {
+		var b strings.Builder
+		writeCause(&b, msg.CausedBy)
+		r.AddAttrs(slog.String("caused_by", b.String()))
+	}
+	if err := logger.Handler().Handle(ctx, r); err != nil {
+		m.logger().Println("[ERR  ] slog: " + err.Error())
+	}
+}
+
+// slogAttr returns value as attribute, with maps as groups of their sorted keys.
+func slogAttr(key string, value interface{}) slog.Attr {
+	data, ok := value.(map[string]interface{})
+	if !ok {
+		return slog.Any(key, value)
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, len(keys))
+	for i, k := range keys {
+		attrs[i] = slogAttr(k, data[k])
+	}
+	return slog.Attr{Key: key, Value: slog.GroupValue(attrs...)}
+}
@@ -0,0 +1,58 @@
+package module
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestUseSlog(t *testing.T) {
+	var b, s bytes.Buffer
+	_, e, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+	handler := slog.NewTextHandler(&s, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 || a.Key == "id" && len(groups) == 1 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	m.UseSlog(slog.New(handler).WithGroup("app"))
+
+	m.Info("test", "A", 1)
+	m.Warn("test", e("test2"), "A", 1, "req", map[string]interface{}{"path": "/x", "method": "GET"})
+	if want := `level=WARN msg="This is a test message" app.module=module app.name=test app.code=123 app.A=1 app.req.method=GET app.req.path=/x app.caused_by="234 test2 This is a another test message"` + "\n"; s.String() != want {
+		t.Fatalf("unexpected slog output %q", s.String())
+	}
+	if b.Len() != 0 {
+		t.Fatalf("logger was used: %q", b.String())
+	}
+
+	m.UseSlog(nil)
+	m.Info("test", "A", 1)
+	if b.String() != "[INFO ] This is a test message A=1\n" {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+}
+
+func TestSlogAttrArgs(t *testing.T) {
+	var b bytes.Buffer
+	_, _, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+
+	m.Info("test", "A", 1, slog.Int("B", 2), slog.Group("req", slog.String("method", "GET"), slog.Group("url", "path", "/x")), slog.Group("", "C", 3), slog.Attr{}, "D", 4)
+	if want := "[INFO ] This is a test message A=1 B=2 req.method=GET req.url.path=/x C=3 D=4\n"; b.String() != want {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+
+	b.Reset()
+	m.Info("test", "A", 1, slog.Bool("ok", true))
+	m.Print("done", slog.Duration("took", 1500*time.Millisecond))
+	if want := "[INFO ] This is a test message A=1 ok=true\n[     ] done took=1.5s\n"; b.String() != want {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+}
@@ -0,0 +1,230 @@
+package module
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SocketFormat int
+
+const (
+	// NDJSON writes every message as a JSON line, see Message.MarshalJSON.
+	NDJSON SocketFormat = iota
+	// Logfmt writes every message as a logfmt line, see Message.MarshalLogfmt.
+	Logfmt
+)
+
+// maxDatagram is the largest UDP payload.
+const maxDatagram = 65507
+
+type SocketSinkOptions struct {
+	// Format is the format of the records. Default NDJSON.
+	Format SocketFormat
+	// Marshal returns the record of a message, replacing Format, e.g. for the CEF and LEEF encoders of package siem.
+	Marshal func(msg *Message) ([]byte, error)
+	// TLS is used to connect if not nil. Not supported for datagram networks.
+	TLS *tls.Config
+	// DialTimeout limits connecting. Default 10s.
+	DialTimeout time.Duration
+	// WriteTimeout limits writing a record. Default 10s.
+	WriteTimeout time.Duration
+	// Backoff is the delay before reconnecting after a failure, doubled on every further failure
+	// up to MaxBackoff. Default 1s and 1min.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Spill is the maximum number of records kept while disconnected. If it is exceeded,
+	// the oldest records are dropped. Default 1000.
+	Spill int
+	// Clock is used for the backoff. Default SystemClock.
+	Clock Clock
+}
+
+// SocketSink writes messages as lines to a TCP, UDP or Unix socket, e.g. to rsyslog (imtcp with
+// a JSON parser) or the tcp input of Fluentd. It connects on the first Write and reconnects
+// after failures. While disconnected, records are kept in a spill buffer and written in order
+// after the connection is back. Datagram networks write one record per datagram.
+type SocketSink struct {
+	network string
+	addr    string
+	opts    SocketSinkOptions
+
+	mu      sync.Mutex
+	conn    net.Conn
+	closed  bool
+	spill   [][]byte
+	dropped int64
+	delay   time.Duration
+	retry   time.Time
+	err     error
+}
+
+// NewSocketSink returns a SocketSink for a network and address as accepted by net.Dial,
+// like "tcp" and "logs.example.com:5170". It panics for TLS on a datagram network.
+func NewSocketSink(network, addr string, opts SocketSinkOptions) *SocketSink {
+	if opts.TLS != nil && isDatagram(network) {
+		panic("module.NewSocketSink: TLS over " + network)
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 10 * time.Second
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = 10 * time.Second
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Minute
+	}
+	if opts.MaxBackoff < opts.Backoff {
+		opts.MaxBackoff = opts.Backoff
+	}
+	if opts.Spill <= 0 {
+		opts.Spill = 1000
+	}
+	opts.Clock = clockOrSystem(opts.Clock)
+	return &SocketSink{network: network, addr: addr, opts: opts}
+}
+
+func isDatagram(network string) bool {
+	return strings.HasPrefix(network, "udp") || network == "unixgram"
+}
+
+// Write writes msg after the spilled records. If the socket is disconnected, msg is spilled
+// and Write returns nil, unless the spill buffer overflowed.
+func (s *SocketSink) Write(msg *Message) error {
+	var record []byte
+	var err error
+	switch {
+	case s.opts.Marshal != nil:
+		record, err = s.opts.Marshal(msg)
+	case s.opts.Format == Logfmt:
+		record = msg.MarshalLogfmt()
+	default:
+		record, err = json.Marshal(msg)
+	}
+	if err != nil {
+		return err
+	}
+	record = append(record, '\n')
+	if len(record) > maxDatagram && isDatagram(s.network) {
+		return Permanent(fmt.Errorf("record of %d bytes exceeds datagram size", len(record)))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return net.ErrClosed
+	}
+	s.spill = append(s.spill, record)
+	s.flush()
+	if len(s.spill) > s.opts.Spill {
+		n := len(s.spill) - s.opts.Spill
+		s.spill = append(s.spill[:0], s.spill[n:]...)
+		s.dropped += int64(n)
+		return fmt.Errorf("socket spill buffer full, dropped %d record(s): %w", n, s.err)
+	}
+	return nil
+}
+
+// Flush writes the spilled records, reconnecting if the backoff passed, and returns the error of the connection.
+func (s *SocketSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return net.ErrClosed
+	}
+	return s.flush()
+}
+
+func (s *SocketSink) flush() error {
+	if len(s.spill) == 0 {
+		return nil
+	}
+	if s.conn == nil {
+		if s.opts.Clock.Now().Before(s.retry) {
+			return s.err
+		}
+		if err := s.dial(); err != nil {
+			s.fail(err)
+			return err
+		}
+	}
+	for len(s.spill) > 0 {
+		s.conn.SetWriteDeadline(time.Now().Add(s.opts.WriteTimeout))
+		if _, err := s.conn.Write(s.spill[0]); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			s.fail(err)
+			return err
+		}
+		s.spill[0] = nil
+		s.spill = s.spill[1:]
+	}
+	s.spill = nil
+	s.delay = 0
+	s.err = nil
+	return nil
+}
+
+func (s *SocketSink) dial() (err error) {
+	dialer := &net.Dialer{Timeout: s.opts.DialTimeout}
+	if s.opts.TLS != nil {
+		s.conn, err = tls.DialWithDialer(dialer, s.network, s.addr, s.opts.TLS)
+	} else {
+		s.conn, err = dialer.Dial(s.network, s.addr)
+	}
+	if err != nil {
+		s.conn = nil
+	}
+	return err
+}
+
+// fail schedules the next connection attempt after err.
+func (s *SocketSink) fail(err error) {
+	if s.delay == 0 {
+		s.delay = s.opts.Backoff
+	} else if s.delay = 2 * s.delay; s.delay > s.opts.MaxBackoff {
+		s.delay = s.opts.MaxBackoff
+	}
+	s.retry = s.opts.Clock.Now().Add(s.delay)
+	s.err = err
+}
+
+// Len returns the number of spilled records.
+func (s *SocketSink) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.spill)
+}
+
+// Dropped returns the number of records dropped because the spill buffer was full.
+func (s *SocketSink) Dropped() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close tries to write the spilled records once more and closes the connection.
+func (s *SocketSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.retry = time.Time{}
+	err := s.flush()
+	if s.conn != nil {
+		if cerr := s.conn.Close(); err == nil {
+			err = cerr
+		}
+		s.conn = nil
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package module
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSocketSink(t *testing.T) {
+	// reserve an address with nothing listening yet
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	clock := NewManualClock(time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC))
+	s := NewSocketSink("tcp", addr, SocketSinkOptions{Spill: 2, Clock: clock})
+	for _, module := range []string{"a", "b", "c"} {
+		if err := s.Write(&Message{Module: module, Level: Info, Time: clock.Now()}); err != nil && module != "c" {
+			t.Fatal(err)
+		} else if err == nil && module == "c" {
+			t.Fatal("expected overflow of the spill buffer")
+		}
+	}
+	if s.Len() != 2 || s.Dropped() != 1 {
+		t.Fatalf("unexpected %d spilled and %d dropped records", s.Len(), s.Dropped())
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip("address taken again:", err)
+	}
+	defer ln.Close()
+	lines := make(chan string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	// the backoff has not passed yet
+	if err := s.Flush(); err == nil || s.Len() != 2 {
+		t.Fatalf("expected backoff, got %v with %d spilled records", err, s.Len())
+	}
+	clock.Advance(time.Second)
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"b", "c"} {
+		var msg Message
+		if err := json.Unmarshal([]byte(<-lines), &msg); err != nil || msg.Module != want {
+			t.Fatalf("expected module %s, got %v, %v", want, msg.Module, err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(&Message{Module: "d", Time: clock.Now()}); err != net.ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestSocketSinkUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	s := NewSocketSink("udp", pc.LocalAddr().String(), SocketSinkOptions{Format: Logfmt})
+	defer s.Close()
+	if err := s.Write(&Message{Module: "auth", Level: Warn, Time: time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "time=2022-11-17T12:00:00Z level=warn module=auth\n" {
+		t.Fatalf("unexpected datagram %q", got)
+	}
+
+	big := &Message{Module: "auth", Time: time.Now(), Data: map[string]interface{}{"x": strings.Repeat("x", maxDatagram)}}
+	if err := s.Write(big); !IsPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+}
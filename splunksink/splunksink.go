@@ -0,0 +1,198 @@
+// Package splunksink sends module messages to the Splunk HTTP Event Collector (HEC).
+//
+//	sink := splunksink.New("https://splunk:8088", splunksink.Options{Token: token, Index: "app", Ack: true})
+//	m.Sinks = append(m.Sinks, module.NewBatchSink(sink, module.BatchOptions{}))
+//
+// Each message is an event with its JSON encoding, see module.SchemaVersion, and the indexed
+// fields "module" and "level". With Ack, WriteBatch returns only after Splunk indexed the events,
+// which is required for guaranteed delivery.
+package splunksink
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	module "github.com/halliday/go-module"
+)
+
+type Options struct {
+	// Token is the HEC token. Required.
+	Token string
+	// SourceType of the events. Default "_json".
+	SourceType string
+	// Source and Index of the events. Default the ones of the token.
+	Source, Index string
+	// Host of the events. Default os.Hostname.
+	Host string
+	// Fields are data keys that become indexed fields of the messages that have them.
+	Fields []string
+	// Ack waits for the indexer acknowledgment of every batch, which must be enabled for the token.
+	Ack bool
+	// Channel identifies the client for acknowledgments. Default a random UUID.
+	Channel string
+	// AckTimeout is the time to wait for an acknowledgment before WriteBatch fails. Default 1m.
+	AckTimeout time.Duration
+	// AckInterval is the time between polls for acknowledgments. Default 1s.
+	AckInterval time.Duration
+	// Client sends the requests. Default http.DefaultClient.
+	Client *http.Client
+}
+
+// Sink is a module.Sink and module.BatchWriter sending messages to a HEC.
+type Sink struct {
+	url  string
+	opts Options
+}
+
+// New returns a Sink for the HEC at url. It panics if opts.Token is empty.
+func New(url string, opts Options) *Sink {
+	if opts.Token == "" {
+		panic("splunksink.New: empty token")
+	}
+	if opts.SourceType == "" {
+		opts.SourceType = "_json"
+	}
+	if opts.Host == "" {
+		opts.Host, _ = os.Hostname()
+	}
+	if opts.Channel == "" {
+		opts.Channel = newUUID()
+	}
+	if opts.AckTimeout <= 0 {
+		opts.AckTimeout = time.Minute
+	}
+	if opts.AckInterval <= 0 {
+		opts.AckInterval = time.Second
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return &Sink{url: url + "/services/collector", opts: opts}
+}
+
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	s := hex.EncodeToString(b[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+type event struct {
+	Time       string            `json:"time"`
+	Host       string            `json:"host,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	SourceType string            `json:"sourcetype"`
+	Index      string            `json:"index,omitempty"`
+	Event      *module.Message   `json:"event"`
+	Fields     map[string]string `json:"fields"`
+}
+
+// response is the body of HEC responses.
+type response struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+func (s *Sink) Write(msg *module.Message) error {
+	return s.WriteBatch([]*module.Message{msg})
+}
+
+func (s *Sink) WriteBatch(msgs []*module.Message) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, msg := range msgs {
+		e := event{
+			Time:       strconv.FormatFloat(float64(msg.Time.UnixNano())/1e9, 'f', 6, 64),
+			Host:       s.opts.Host,
+			Source:     s.opts.Source,
+			SourceType: s.opts.SourceType,
+			Index:      s.opts.Index,
+			Event:      msg,
+			Fields:     map[string]string{"module": msg.Module, "level": msg.Level.String()},
+		}
+		for _, key := range s.opts.Fields {
+			if value, ok := msg.Data[key]; ok {
+				e.Fields[key] = fmt.Sprint(value)
+			}
+		}
+		if err := enc.Encode(&e); err != nil {
+			return err
+		}
+	}
+	var r response
+	if err := s.post("/event", body.Bytes(), &r); err != nil {
+		return err
+	}
+	if !s.opts.Ack {
+		return nil
+	}
+	if r.AckID == nil {
+		return module.Permanent(fmt.Errorf("%s: no ackId, indexer acknowledgment is disabled for the token", s.url))
+	}
+	return s.waitAck(*r.AckID)
+}
+
+// waitAck polls the ack endpoint until id is acknowledged or AckTimeout passed.
+func (s *Sink) waitAck(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.AckTimeout)
+	defer cancel()
+	body := []byte(`{"acks":[` + strconv.FormatInt(id, 10) + `]}`)
+	ticker := time.NewTicker(s.opts.AckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: no acknowledgment for ackId %d within %s", s.url, id, s.opts.AckTimeout)
+		case <-ticker.C:
+		}
+		var r struct {
+			Acks map[string]bool `json:"acks"`
+		}
+		if err := s.post("/ack", body, &r); err != nil {
+			return err
+		}
+		if r.Acks[strconv.FormatInt(id, 10)] {
+			return nil
+		}
+	}
+}
+
+// post sends body to the endpoint path of the HEC and decodes the response into v.
+func (s *Sink) post(path string, body []byte, v interface{}) error {
+	u := s.url + path
+	if s.opts.Ack {
+		u += "?channel=" + url.QueryEscape(s.opts.Channel)
+	}
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return module.Permanent(err)
+	}
+	req.Header.Set("Authorization", "Splunk "+s.opts.Token)
+	req.Header.Set("Content-Type", "application/json")
+	if s.opts.Ack {
+		req.Header.Set("X-Splunk-Request-Channel", s.opts.Channel)
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		resp.Body = io.NopCloser(io.LimitReader(resp.Body, 512))
+		return module.ResponseError(resp)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,105 @@
+package splunksink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	module "github.com/halliday/go-module"
+)
+
+// hec is a HEC that acknowledges an event after the second poll.
+type hec struct {
+	mu      sync.Mutex
+	events  []map[string]interface{}
+	polls   int
+	channel string
+}
+
+func (h *hec) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if r.Header.Get("Authorization") != "Splunk token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"text":"Invalid token","code":4}`)
+		return
+	}
+	h.channel = r.Header.Get("X-Splunk-Request-Channel")
+	if h.channel != "" && r.URL.Query().Get("channel") != h.channel {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"text":"Data channel is missing","code":10}`)
+		return
+	}
+	switch r.URL.Path {
+	case "/services/collector/event":
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var e map[string]interface{}
+			json.Unmarshal(scanner.Bytes(), &e)
+			h.events = append(h.events, e)
+		}
+		if h.channel != "" {
+			fmt.Fprint(w, `{"text":"Success","code":0,"ackId":7}`)
+		} else {
+			fmt.Fprint(w, `{"text":"Success","code":0}`)
+		}
+	case "/services/collector/ack":
+		h.polls++
+		fmt.Fprintf(w, `{"acks":{"7":%t}}`, h.polls >= 2)
+	}
+}
+
+func TestWriteBatch(t *testing.T) {
+	h := new(hec)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	s := New(srv.URL, Options{Token: "token", Index: "app", Host: "host-1", Fields: []string{"tenant"}})
+	msg := &module.Message{Module: "auth", Level: module.Warn, Time: time.Date(2022, 11, 17, 12, 0, 0, 500000000, time.UTC), Data: map[string]interface{}{"tenant": "acme"}}
+	if err := s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	e := h.events[0]
+	if e["time"] != "1668686400.500000" || e["host"] != "host-1" || e["index"] != "app" || e["sourcetype"] != "_json" {
+		t.Fatalf("unexpected event %v", e)
+	}
+	if fmt.Sprint(e["fields"]) != "map[level:warn module:auth tenant:acme]" {
+		t.Fatalf("unexpected fields %v", e["fields"])
+	}
+	if e["event"].(map[string]interface{})["module"] != "auth" {
+		t.Fatalf("unexpected event %v", e["event"])
+	}
+	if h.channel != "" {
+		t.Fatal("unexpected channel without ack")
+	}
+
+	if err := New(srv.URL, Options{Token: "wrong"}).Write(msg); !module.IsPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+}
+
+func TestAck(t *testing.T) {
+	h := new(hec)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	s := New(srv.URL, Options{Token: "token", Ack: true, AckInterval: time.Millisecond})
+	if err := s.Write(&module.Message{Module: "auth", Level: module.Info, Time: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if h.polls != 2 || len(h.channel) != 36 {
+		t.Fatalf("unexpected %d polls on channel %q", h.polls, h.channel)
+	}
+
+	h.polls = -1000
+	s = New(srv.URL, Options{Token: "token", Ack: true, AckInterval: time.Millisecond, AckTimeout: 20 * time.Millisecond})
+	err := s.Write(&module.Message{Module: "auth", Level: module.Info, Time: time.Now()})
+	if err == nil || module.IsPermanent(err) {
+		t.Fatalf("expected temporary error, got %v", err)
+	}
+}
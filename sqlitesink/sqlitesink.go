@@ -0,0 +1,255 @@
+// Package sqlitesink writes module messages into a SQLite database, for desktop and edge apps without a log stack.
+//
+// The sink works with any database/sql SQLite driver, for example modernc.org/sqlite (no cgo):
+//
+//	db, err := sql.Open("sqlite", "app-log.db?_pragma=journal_mode(WAL)")
+//	sink, err := sqlitesink.New(db, "")
+//	m.Sinks = append(m.Sinks, module.NewBatchSink(sink, module.BatchOptions{}))
+//
+// Messages are stored with indexed columns for time, module, level, name and code,
+// their data as JSON (usable with json_extract) and the full JSON message, see module.SchemaVersion.
+// Query reads them back.
+package sqlitesink
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	module "github.com/halliday/go-module"
+)
+
+// DefaultTable is the table used if New is called with an empty table name.
+const DefaultTable = "messages"
+
+// Sink is a module.Sink and module.BatchWriter that inserts messages into a table.
+type Sink struct {
+	db     *sql.DB
+	table  string
+	insert string
+}
+
+// New creates the table and its indexes in db if they do not exist and returns a Sink writing to it.
+// It returns an error if table is not a plain identifier.
+func New(db *sql.DB, table string) (*Sink, error) {
+	if table == "" {
+		table = DefaultTable
+	}
+	if !isIdent(table) {
+		return nil, fmt.Errorf("sqlitesink: invalid table name %q", table)
+	}
+	s := &Sink{
+		db:     db,
+		table:  table,
+		insert: "INSERT INTO " + table + " (time, module, level, name, code, description, data, message) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+	}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Sink) migrate() error {
+	t := s.table
+	stmts := []string{
+		"CREATE TABLE IF NOT EXISTS " + t + ` (
+			id INTEGER PRIMARY KEY,
+			time INTEGER NOT NULL,
+			module TEXT NOT NULL,
+			level INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			code INTEGER NOT NULL,
+			description TEXT NOT NULL,
+			data TEXT,
+			message TEXT NOT NULL
+		)`,
+		"CREATE INDEX IF NOT EXISTS " + t + "_time ON " + t + " (time)",
+		"CREATE INDEX IF NOT EXISTS " + t + "_module ON " + t + " (module, time)",
+		"CREATE INDEX IF NOT EXISTS " + t + "_level ON " + t + " (level, time)",
+		"CREATE INDEX IF NOT EXISTS " + t + "_name ON " + t + " (name, time)",
+		"CREATE INDEX IF NOT EXISTS " + t + "_code ON " + t + " (code)",
+	}
+	for _, stmt := range stmts {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Sink) Write(msg *module.Message) error {
+	return s.WriteBatch([]*module.Message{msg})
+}
+
+// WriteBatch inserts msgs in one transaction.
+func (s *Sink) WriteBatch(msgs []*module.Message) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(s.insert)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, msg := range msgs {
+		args, err := row(msg)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// row returns the column values of msg in the order of the insert statement.
+func row(msg *module.Message) ([]interface{}, error) {
+	full, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if len(msg.Data) > 0 {
+		b, err := json.Marshal(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		data = string(b)
+	}
+	var name, desc string
+	var code int
+	if msg.RichError != nil {
+		name, code, desc = msg.Name, msg.Code, msg.Desc
+	}
+	return []interface{}{msg.Time.UnixNano(), msg.Module, int(msg.Level), name, code, desc, data, string(full)}, nil
+}
+
+// A Filter selects messages for Query. Zero fields match all messages.
+type Filter struct {
+	// Since and Until limit the time of the messages to [Since, Until).
+	Since, Until time.Time
+	Module       string
+	// Levels is a mask of levels, like module.AllLevels.
+	Levels module.Level
+	Name   string
+	Code   int
+	// Data matches messages whose data has all of these keys with equal values.
+	Data map[string]interface{}
+	// Limit is the maximum number of messages.
+	Limit int
+	// Desc returns the newest messages first.
+	Desc bool
+}
+
+// Query returns the messages matching f, oldest first unless f.Desc.
+func (s *Sink) Query(ctx context.Context, f Filter) ([]*module.Message, error) {
+	q, args := s.query("message", f)
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var msgs []*module.Message
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		msg := new(module.Message)
+		if err := json.Unmarshal(b, msg); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, rows.Err()
+}
+
+// Count returns the number of messages matching f, ignoring f.Limit.
+func (s *Sink) Count(ctx context.Context, f Filter) (n int, err error) {
+	f.Limit = 0
+	q, args := s.query("COUNT(*)", f)
+	err = s.db.QueryRowContext(ctx, q, args...).Scan(&n)
+	return n, err
+}
+
+// Prune deletes the messages older than before and returns their number, to keep the database small.
+func (s *Sink) Prune(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM "+s.table+" WHERE time < ?", before.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// query builds the SELECT of cols for f.
+func (s *Sink) query(cols string, f Filter) (string, []interface{}) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if !f.Since.IsZero() {
+		add("time >= ?", f.Since.UnixNano())
+	}
+	if !f.Until.IsZero() {
+		add("time < ?", f.Until.UnixNano())
+	}
+	if f.Module != "" {
+		add("module = ?", f.Module)
+	}
+	if f.Levels != 0 {
+		add("level & ? != 0", int(f.Levels))
+	}
+	if f.Name != "" {
+		add("name = ?", f.Name)
+	}
+	if f.Code != 0 {
+		add("code = ?", f.Code)
+	}
+	for key, value := range f.Data {
+		where = append(where, "json_extract(data, ?) = ?")
+		args = append(args, jsonPath(key), value)
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT " + cols + " FROM " + s.table)
+	if len(where) > 0 {
+		b.WriteString(" WHERE " + strings.Join(where, " AND "))
+	}
+	if cols != "COUNT(*)" {
+		b.WriteString(" ORDER BY time")
+		if f.Desc {
+			b.WriteString(" DESC")
+		}
+		b.WriteString(", id")
+		if f.Desc {
+			b.WriteString(" DESC")
+		}
+	}
+	if f.Limit > 0 {
+		b.WriteString(" LIMIT ?")
+		args = append(args, f.Limit)
+	}
+	return b.String(), args
+}
+
+// jsonPath returns the JSON path of a top level key, quoted so keys like "a.b" work.
+func jsonPath(key string) string {
+	return `$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+}
+
+func isIdent(s string) bool {
+	for i, c := range s {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
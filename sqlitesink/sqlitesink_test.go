@@ -0,0 +1,135 @@
+package sqlitesink
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+	_ "modernc.org/sqlite"
+)
+
+func openSink(t *testing.T) *Sink {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "log.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := New(db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func message(mod string, level module.Level, name string, code int, t time.Time, data map[string]interface{}) *module.Message {
+	return &module.Message{
+		Module:    mod,
+		Level:     level,
+		Time:      t,
+		RichError: errors.NewRich(name, code, "Message "+name, "", data, nil),
+		Data:      data,
+	}
+}
+
+func TestSink(t *testing.T) {
+	s := openSink(t)
+	t0 := time.Date(2022, 11, 17, 12, 0, 0, 0, time.UTC)
+	msgs := []*module.Message{
+		message("auth", module.Info, "login", 0, t0, map[string]interface{}{"user": "bob"}),
+		message("auth", module.Warn, "login_failed", 1001, t0.Add(time.Second), map[string]interface{}{"user": "alice", "attempts": 3}),
+		message("db", module.Error, "timeout", 1500, t0.Add(2*time.Second), nil),
+	}
+	if err := s.WriteBatch(msgs[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(msgs[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, c := range []struct {
+		f    Filter
+		want []string
+	}{
+		{Filter{}, []string{"login", "login_failed", "timeout"}},
+		{Filter{Desc: true, Limit: 2}, []string{"timeout", "login_failed"}},
+		{Filter{Module: "auth"}, []string{"login", "login_failed"}},
+		{Filter{Levels: module.Warn | module.Error}, []string{"login_failed", "timeout"}},
+		{Filter{Name: "timeout"}, []string{"timeout"}},
+		{Filter{Code: 1001}, []string{"login_failed"}},
+		{Filter{Since: t0.Add(time.Second), Until: t0.Add(2 * time.Second)}, []string{"login_failed"}},
+		{Filter{Data: map[string]interface{}{"user": "alice", "attempts": 3}}, []string{"login_failed"}},
+		{Filter{Data: map[string]interface{}{"user": "carol"}}, nil},
+	} {
+		got, err := s.Query(ctx, c.f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, msg := range got {
+			names = append(names, msg.Name)
+		}
+		if len(names) != len(c.want) {
+			t.Fatalf("filter %+v: got %v, want %v", c.f, names, c.want)
+		}
+		for i := range names {
+			if names[i] != c.want[i] {
+				t.Fatalf("filter %+v: got %v, want %v", c.f, names, c.want)
+			}
+		}
+		n, err := s.Count(ctx, c.f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.f.Limit == 0 && n != len(c.want) {
+			t.Fatalf("filter %+v: count %d, want %d", c.f, n, len(c.want))
+		}
+	}
+
+	got, err := s.Query(ctx, Filter{Name: "login_failed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := got[0]; msg.Module != "auth" || msg.Level != module.Warn || !msg.Time.Equal(t0.Add(time.Second)) || msg.Desc != "Message login_failed" || msg.Data["user"] != "alice" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	n, err := s.Prune(ctx, t0.Add(2*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("pruned %d messages, want 2", n)
+	}
+	if n, _ := s.Count(ctx, Filter{}); n != 1 {
+		t.Fatalf("%d messages left, want 1", n)
+	}
+}
+
+func TestModule(t *testing.T) {
+	s := openSink(t)
+	_, _, m := module.New("auth", "login_failed;1001;Login failed\n")
+	m.Logger = log.New(io.Discard, "", 0)
+	m.Sinks = append(m.Sinks, s)
+	m.Warn("login_failed", "user", "bob")
+
+	got, err := s.Query(context.Background(), Filter{Code: 1001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Desc != "Login failed" || got[0].Data["user"] != "bob" {
+		t.Fatalf("unexpected messages %+v", got)
+	}
+}
+
+func TestInvalidTable(t *testing.T) {
+	if _, err := New(nil, "messages; DROP TABLE x"); err == nil || err.Error() != `sqlitesink: invalid table name "messages; DROP TABLE x"` {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
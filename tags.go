@@ -0,0 +1,92 @@
+package module
+
+import "context"
+
+// Standard keys used by the context helpers below.
+const (
+	KeyRequestID = "request_id"
+	KeyTraceID   = "trace_id"
+	KeyUserID    = "user_id"
+)
+
+// CtxTag returns a context that adds key=value to the Data of every Message
+// logged with it, by any Module. Tags set later override earlier tags with the same key,
+// and explicit message arguments override tags.
+func CtxTag(ctx context.Context, key string, value interface{}) context.Context {
+	return &tagContext{
+		Context: ctx,
+		key:     key,
+		value:   value,
+	}
+}
+
+func CtxWithRequestID(ctx context.Context, id string) context.Context {
+	return CtxTag(ctx, KeyRequestID, id)
+}
+
+func CtxWithTraceID(ctx context.Context, id string) context.Context {
+	return CtxTag(ctx, KeyTraceID, id)
+}
+
+func CtxWithUserID(ctx context.Context, id string) context.Context {
+	return CtxTag(ctx, KeyUserID, id)
+}
+
+type tagContextKey struct{}
+
+type tagContext struct {
+	context.Context
+	key   string
+	value interface{}
+}
+
+func (ctx *tagContext) Value(key any) any {
+	if _, ok := key.(tagContextKey); ok {
+		return ctx
+	}
+	return ctx.Context.Value(key)
+}
+
+func ctxTag(ctx context.Context) *tagContext {
+	t, _ := ctx.Value(tagContextKey{}).(*tagContext)
+	return t
+}
+
+// CtxTags returns all tags of the context.
+func CtxTags(ctx context.Context) map[string]interface{} {
+	return ctxTags(nil, ctx, nil)
+}
+
+// CtxTagValue returns the value of the tag key, if any.
+func CtxTagValue(ctx context.Context, key string) (value interface{}, ok bool) {
+	for t := ctxTag(ctx); t != nil; t = ctxTag(t.Context) {
+		if t.key == key {
+			return t.value, true
+		}
+	}
+	return nil, false
+}
+
+// ctxTags adds all tags of ctx that are not yet present to data, appending their keys to keys (if not nil).
+// data is copied before it is modified.
+func ctxTags(keys *[]string, ctx context.Context, data map[string]interface{}) map[string]interface{} {
+	copied := false
+	for t := ctxTag(ctx); t != nil; t = ctxTag(t.Context) {
+		if _, ok := data[t.key]; ok {
+			continue
+		}
+		if !copied {
+			m := make(map[string]interface{}, len(data)+1)
+			for key, value := range data {
+				m[key] = value
+			}
+			data = m
+			copied = true
+		}
+		data[t.key] = t.value
+		if keys != nil {
+			*keys = append(*keys, t.key)
+		}
+	}
+	return data
+}
@@ -0,0 +1,35 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+)
+
+func TestCtxTag(t *testing.T) {
+	var lastMessage *Message
+	var b bytes.Buffer
+	l, _, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+	m.Hook = func(msg *Message) *Message {
+		lastMessage = msg
+		return msg
+	}
+
+	ctx := CtxWithRequestID(context.Background(), "r1")
+	ctx = CtxWithUserID(ctx, "bob")
+	ctx = CtxWithRequestID(ctx, "r2")
+
+	l.Info("test", ctx, "A", 1, KeyUserID, "alice")
+
+	if lastMessage.Data[KeyRequestID] != "r2" || lastMessage.Data[KeyUserID] != "alice" || lastMessage.Data["A"] != 1 {
+		t.Fatalf("unexpected data: %v", lastMessage.Data)
+	}
+	if b.String() != "[INFO ] This is a test message A=1 user_id=alice request_id=r2\n" {
+		t.Fatalf("unexpected log output: %q", b.String())
+	}
+	if v, _ := CtxTagValue(ctx, KeyRequestID); v != "r2" {
+		t.Fatalf("unexpected tag value %v", v)
+	}
+}
@@ -0,0 +1,64 @@
+package module
+
+import "context"
+
+const KeyTenant = "tenant"
+
+// CtxWithTenant returns a context that stamps tenant on every Message logged with it.
+// Use Module.SetTenantMask to log at a different level for a single tenant.
+func CtxWithTenant(ctx context.Context, tenant string) context.Context {
+	return CtxTag(ctx, KeyTenant, tenant)
+}
+
+// CtxTenant returns the tenant of the context, or "".
+func CtxTenant(ctx context.Context) string {
+	tenant, _ := CtxTagValue(ctx, KeyTenant)
+	s, _ := tenant.(string)
+	return s
+}
+
+// SetTenantMask overrides the Mask for all messages logged with a context of the given tenant.
+func (m *Module) SetTenantMask(tenant string, mask Level) {
+	m.maskMu.Lock()
+	defer m.maskMu.Unlock()
+	if m.tenantMasks == nil {
+		m.tenantMasks = make(map[string]Level)
+	}
+	m.tenantMasks[tenant] = mask
+}
+
+// ClearTenantMask removes the override set with SetTenantMask.
+func (m *Module) ClearTenantMask(tenant string) {
+	m.maskMu.Lock()
+	defer m.maskMu.Unlock()
+	delete(m.tenantMasks, tenant)
+}
+
+// mask returns the mask for the message name logged with ctx. CtxVerbose takes precedence
+// over tenant masks, which take precedence over prefix masks and the Mask.
+func (m *Module) mask(ctx context.Context, name string) Level {
+	if CtxIsVerbose(ctx) {
+		return AllLevels | Debug | Trace
+	}
+	m.maskMu.RLock()
+	defer m.maskMu.RUnlock()
+	if len(m.tenantMasks) != 0 {
+		if mask, ok := m.tenantMasks[CtxTenant(ctx)]; ok {
+			return mask
+		}
+	}
+	if len(m.prefixMasks) != 0 {
+		for prefix := name; ; prefix = parentName(prefix) {
+			if mask, ok := m.prefixMasks[prefix]; ok {
+				return mask
+			}
+			if prefix == "" {
+				break
+			}
+		}
+	}
+	if m.Mask == 0 && !m.maskSet {
+		return AllLevels
+	}
+	return m.Mask
+}
@@ -0,0 +1,31 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+)
+
+func TestTenantMask(t *testing.T) {
+	var b bytes.Buffer
+	l, _, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+	m.Mask = Error
+	m.SetTenantMask("acme", AllLevels)
+
+	l.Info("test", CtxWithTenant(context.Background(), "other"))
+	l.Info("test", CtxWithTenant(context.Background(), "acme"))
+	l.Info("test")
+
+	if b.String() != "[INFO ] This is a test message tenant=acme\n" {
+		t.Fatalf("unexpected log output: %q", b.String())
+	}
+
+	m.ClearTenantMask("acme")
+	b.Reset()
+	l.Info("test", CtxWithTenant(context.Background(), "acme"))
+	if b.Len() != 0 {
+		t.Fatalf("unexpected log output: %q", b.String())
+	}
+}
@@ -0,0 +1,97 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+type ThrottleOptions struct {
+	// Rate is the number of messages per second allowed for each message name.
+	Rate float64
+	// Burst is the number of messages per name that may exceed the Rate at once.
+	// Default the Rate rounded up, at least 1.
+	Burst int
+	// Summary is the interval of the "suppressed" warning, 30s by default.
+	Summary time.Duration
+}
+
+// Throttle limits the messages of every name with a token bucket. Suppressed messages
+// are counted, and a "suppressed" warning with the name and count is logged at most once
+// per Summary interval. Messages without a name (Print, Printf) and audit messages are not throttled.
+// A zero Rate disables throttling.
+func (m *Module) Throttle(opts ThrottleOptions) {
+	if opts.Summary == 0 {
+		opts.Summary = 30 * time.Second
+	}
+	if opts.Burst < 1 {
+		opts.Burst = int(math.Max(1, math.Min(math.Ceil(opts.Rate), math.MaxInt32)))
+	}
+	m.throttleMu.Lock()
+	defer m.throttleMu.Unlock()
+	m.throttle = opts
+	m.buckets = nil
+}
+
+type bucket struct {
+	tokens     float64
+	last       time.Time
+	suppressed int
+	summary    Timer
+}
+
+func (m *Module) allow(name string) bool {
+	if name == "" || m.audit {
+		return true
+	}
+	m.throttleMu.Lock()
+	defer m.throttleMu.Unlock()
+	if m.throttle.Rate <= 0 {
+		return true
+	}
+	clock := clockOrSystem(m.Clock)
+	now := clock.Now()
+	b, ok := m.buckets[name]
+	if !ok {
+		if m.buckets == nil {
+			m.buckets = make(map[string]*bucket)
+		}
+		b = &bucket{tokens: float64(m.throttle.Burst), last: now}
+		m.buckets[name] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * m.throttle.Rate
+	if max := float64(m.throttle.Burst); b.tokens > max {
+		b.tokens = max
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	b.suppressed++
+	if m.Metrics != nil {
+		m.Metrics.Add("suppressed", 1)
+		m.Metrics.Add("suppressed."+name, 1)
+	}
+	if b.summary == nil {
+		interval := m.throttle.Summary
+		b.summary = clock.AfterFunc(interval, func() {
+			m.summarize(name, b, interval)
+		})
+	}
+	return false
+}
+
+func (m *Module) summarize(name string, b *bucket, interval time.Duration) {
+	m.throttleMu.Lock()
+	n := b.suppressed
+	b.suppressed = 0
+	b.summary = nil
+	m.throttleMu.Unlock()
+	if n == 0 {
+		return
+	}
+	desc := fmt.Sprintf("suppressed %d '%s' messages in the last %s", n, name, interval)
+	m.log(context.Background(), Warn, "suppressed", 0, desc, "", []interface{}{"name", name, "count", n}, nil)
+}
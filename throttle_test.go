@@ -0,0 +1,55 @@
+package module
+
+import (
+	"bytes"
+	"expvar"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	var b bytes.Buffer
+	clock := NewManualClock(time.Date(2022, 11, 17, 0, 0, 0, 0, time.UTC))
+	l, _, m := New("throttle", messages)
+	m.Logger = log.New(&b, "", 0)
+	m.Clock = clock
+	metrics := new(expvar.Map).Init()
+	m.Metrics = metrics
+	m.Throttle(ThrottleOptions{Rate: 1, Burst: 2})
+
+	for i := 0; i < 5; i++ {
+		l.Info("test")
+	}
+	l.Info("test2")
+	clock.Advance(time.Second)
+	l.Info("test")
+	l.Info("test")
+	clock.Advance(29 * time.Second)
+
+	expected := "[INFO ] This is a test message\n[INFO ] This is a test message\n[INFO ] This is a another test message\n[INFO ] This is a test message\n[WARN ] suppressed 4 'test' messages in the last 30s name=test count=4\n"
+	if b.String() != expected {
+		t.Fatalf("unexpected log output: %q", b.String())
+	}
+	if v := metrics.Get("suppressed.test"); v == nil || v.String() != "4" {
+		t.Fatalf("unexpected suppressed count %v", v)
+	}
+}
+
+func TestThrottleDefaultBurst(t *testing.T) {
+	var b bytes.Buffer
+	clock := NewManualClock(time.Date(2022, 11, 17, 0, 0, 0, 0, time.UTC))
+	l, _, m := New("throttle", messages)
+	m.Logger = log.New(&b, "", 0)
+	m.Clock = clock
+	m.Throttle(ThrottleOptions{Rate: 0.5})
+
+	l.Info("test")
+	l.Info("test")
+	clock.Advance(2 * time.Second)
+	l.Info("test")
+
+	if b.String() != "[INFO ] This is a test message\n[INFO ] This is a test message\n" {
+		t.Fatalf("unexpected log output: %q", b.String())
+	}
+}
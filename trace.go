@@ -0,0 +1,141 @@
+package module
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// KeySpanID is the key of the span ID, next to KeyTraceID.
+const KeySpanID = "span_id"
+
+// A TraceExtractor returns the trace and span ID of ctx, e.g. of an OpenTelemetry span (see package oteltrace).
+type TraceExtractor func(ctx context.Context) (traceID, spanID string, ok bool)
+
+var traceExtractors struct {
+	sync.RWMutex
+	list []*TraceExtractor
+}
+
+// AddTraceExtractor registers an extractor for the messages of all modules. If a message has no
+// "trace_id" yet, the first extractor that finds a trace in the context sets "trace_id" and "span_id".
+func AddTraceExtractor(extract TraceExtractor) (remove func()) {
+	p := &extract
+	traceExtractors.Lock()
+	traceExtractors.list = append(traceExtractors.list, p)
+	traceExtractors.Unlock()
+	return func() {
+		traceExtractors.Lock()
+		defer traceExtractors.Unlock()
+		for i, q := range traceExtractors.list {
+			if q == p {
+				traceExtractors.list = append(traceExtractors.list[:i:i], traceExtractors.list[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// CtxWithTraceParent tags the context with the trace and span ID of a W3C traceparent header,
+// like "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01". Invalid headers are ignored.
+func CtxWithTraceParent(ctx context.Context, traceparent string) context.Context {
+	traceID, spanID, ok := ParseTraceParent(traceparent)
+	if !ok {
+		return ctx
+	}
+	return ctxWithTrace(ctx, traceID, spanID)
+}
+
+func ctxWithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	// tags are listed from the outermost, so the trace ID goes last to come first
+	return CtxTag(CtxTag(ctx, KeySpanID, spanID), KeyTraceID, traceID)
+}
+
+// ParseTraceParent returns the trace and span ID of a W3C traceparent header.
+func ParseTraceParent(s string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return "", "", false
+	}
+	traceID, spanID = parts[1], parts[2]
+	if !isTraceHex(parts[0]) || !isTraceHex(traceID) || !isTraceHex(spanID) || !isTraceHex(parts[3]) ||
+		len(traceID) != 32 || len(spanID) != 16 || len(parts[3]) != 2 || isZeros(traceID) || isZeros(spanID) {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+// isTraceHex reports whether s consists of lowercase hex digits only.
+func isTraceHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isZeros(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// ctxTrace sets the trace and span ID of the first TraceExtractor that finds a trace in ctx,
+// unless data has a trace ID already.
+func ctxTrace(keys *[]string, ctx context.Context, data map[string]interface{}) map[string]interface{} {
+	if _, ok := data[KeyTraceID]; ok {
+		return data
+	}
+	traceExtractors.RLock()
+	defer traceExtractors.RUnlock()
+	for _, extract := range traceExtractors.list {
+		traceID, spanID, ok := (*extract)(ctx)
+		if !ok {
+			continue
+		}
+		if data == nil {
+			data = make(map[string]interface{}, 2)
+		}
+		setArg(keys, data, KeyTraceID, traceID)
+		if spanID != "" {
+			setArg(keys, data, KeySpanID, spanID)
+		}
+		break
+	}
+	return data
+}
+
+// ParseB3 returns the trace and span ID of a B3 single header, like
+// "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1". Trace IDs have 16 or 32 hex digits.
+func ParseB3(s string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 2 {
+		return "", "", false
+	}
+	return validB3(parts[0], parts[1])
+}
+
+func validB3(traceID, spanID string) (string, string, bool) {
+	traceID, spanID = strings.ToLower(traceID), strings.ToLower(spanID)
+	if len(traceID) != 16 && len(traceID) != 32 || len(spanID) != 16 ||
+		!isTraceHex(traceID) || !isTraceHex(spanID) || isZeros(traceID) || isZeros(spanID) {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+// requestTrace returns the trace and span ID of the request headers, trying in this order:
+// W3C traceparent, B3 single header, X-B3-TraceId and X-B3-SpanId.
+func requestTrace(h http.Header) (traceID, spanID string, ok bool) {
+	if v := h.Get("Traceparent"); v != "" {
+		if traceID, spanID, ok = ParseTraceParent(v); ok {
+			return traceID, spanID, true
+		}
+	}
+	if v := h.Get("B3"); v != "" {
+		if traceID, spanID, ok = ParseB3(v); ok {
+			return traceID, spanID, true
+		}
+	}
+	return validB3(h.Get("X-B3-TraceId"), h.Get("X-B3-SpanId"))
+}
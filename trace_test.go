@@ -0,0 +1,92 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	for s, ok := range map[string]bool{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":       true,
+		" 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00 ":     true,
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra": true,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra": false,
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":       false,
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01":       false,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01":       false,
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01":       false,
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01":        false,
+		"": false,
+	} {
+		traceID, spanID, got := ParseTraceParent(s)
+		if got != ok || ok && (traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || spanID != "00f067aa0ba902b7") {
+			t.Fatalf("ParseTraceParent(%q) = %q, %q, %v", s, traceID, spanID, got)
+		}
+	}
+}
+
+type traceTestKey struct{}
+
+func TestTraceExtractor(t *testing.T) {
+	var b bytes.Buffer
+	_, _, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+
+	remove := AddTraceExtractor(func(ctx context.Context) (string, string, bool) {
+		id, ok := ctx.Value(traceTestKey{}).(string)
+		return id, "s1", ok
+	})
+	m.Info("test", context.WithValue(context.Background(), traceTestKey{}, "t1"), "A", 1)
+	m.Info("test", CtxWithTraceParent(context.WithValue(context.Background(), traceTestKey{}, "t1"), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"), "A", 2)
+	remove()
+	m.Info("test", context.WithValue(context.Background(), traceTestKey{}, "t1"), "A", 3)
+	if want := "[INFO ] This is a test message A=1 trace_id=t1 span_id=s1\n" +
+		"[INFO ] This is a test message A=2 trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7\n" +
+		"[INFO ] This is a test message A=3\n"; b.String() != want {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+}
+
+func TestRecoverHandlerTrace(t *testing.T) {
+	var traceID, spanID interface{}
+	_, _, m := New("module", messages)
+	h := RecoverHandler(m, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID, _ = CtxTagValue(r.Context(), KeyTraceID)
+		spanID, _ = CtxTagValue(r.Context(), KeySpanID)
+	}))
+	for _, c := range []struct {
+		header          http.Header
+		traceID, spanID interface{}
+	}{
+		{http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, "B3": {"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1"}}, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+		{http.Header{"Traceparent": {"invalid"}, "B3": {"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90"}}, "80f198ee56343ba864fe8b2a57d3eff7", "e457b5a2e4d86bd1"},
+		{http.Header{"B3": {"0"}, "X-B3-Traceid": {"A3CE929D0E0E4736"}, "X-B3-Spanid": {"00f067aa0ba902b7"}}, "a3ce929d0e0e4736", "00f067aa0ba902b7"},
+		{http.Header{"X-B3-Traceid": {"a3ce929d0e0e4736"}}, nil, nil},
+		{http.Header{}, nil, nil},
+	} {
+		traceID, spanID = nil, nil
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header = c.header
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		if traceID != c.traceID || spanID != c.spanID {
+			t.Fatalf("%v: got %v %v", c.header, traceID, spanID)
+		}
+	}
+
+	// the panic message of RecoverHandler carries the trace too
+	var b bytes.Buffer
+	m.Logger = log.New(&b, "", 0)
+	h = RecoverHandler(m, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if !bytes.Contains(b.Bytes(), []byte(" trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7")) {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+}
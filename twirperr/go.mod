@@ -0,0 +1,17 @@
+module github.com/halliday/go-module/twirperr
+
+go 1.21
+
+require (
+	github.com/halliday/go-errors v0.0.0-20221117114904-701c88d594be
+	github.com/halliday/go-module v0.0.0-00010101000000-000000000000
+	github.com/twitchtv/twirp v8.1.3+incompatible
+)
+
+require (
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	golang.org/x/text v0.13.0 // indirect
+)
+
+replace github.com/halliday/go-module => ../
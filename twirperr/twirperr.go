@@ -0,0 +1,139 @@
+// Package twirperr maps module errors to Twirp errors.
+//
+// The name, code, link and data of the first named error in the chain travel as error metadata:
+//
+//	return nil, twirperr.Error(err)
+//
+// and on the client side:
+//
+//	err = twirperr.FromError(err)
+//
+// The Twirp code is derived from the error, see Code.
+package twirperr
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	rich "github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+
+	"github.com/twitchtv/twirp"
+)
+
+// Metadata keys of the error. Data is JSON encoded.
+const (
+	KeyName = "module_name"
+	KeyCode = "module_code"
+	KeyLink = "module_link"
+	KeyData = "module_data"
+)
+
+// CodeOf, if set, maps a module error to a Twirp code before Code does, e.g. for catalog codes
+// that are not HTTP statuses. It returns false for the default mapping. Set it during initialization.
+var CodeOf func(e *module.EncodedError) (twirp.ErrorCode, bool)
+
+// httpCodes maps HTTP statuses to Twirp codes.
+var httpCodes = map[int]twirp.ErrorCode{
+	http.StatusBadRequest:                   twirp.InvalidArgument,
+	http.StatusUnauthorized:                 twirp.Unauthenticated,
+	http.StatusForbidden:                    twirp.PermissionDenied,
+	http.StatusNotFound:                     twirp.NotFound,
+	http.StatusRequestTimeout:               twirp.DeadlineExceeded,
+	http.StatusConflict:                     twirp.AlreadyExists,
+	http.StatusPreconditionFailed:           twirp.FailedPrecondition,
+	http.StatusRequestedRangeNotSatisfiable: twirp.OutOfRange,
+	http.StatusTooManyRequests:              twirp.ResourceExhausted,
+	499:                                     twirp.Canceled,
+	http.StatusInternalServerError:          twirp.Internal,
+	http.StatusNotImplemented:               twirp.Unimplemented,
+	http.StatusServiceUnavailable:           twirp.Unavailable,
+	http.StatusGatewayTimeout:               twirp.DeadlineExceeded,
+}
+
+// codes are the Twirp codes that names can end with.
+var codes = []twirp.ErrorCode{
+	twirp.Canceled, twirp.InvalidArgument, twirp.Malformed, twirp.DeadlineExceeded, twirp.NotFound,
+	twirp.AlreadyExists, twirp.PermissionDenied, twirp.Unauthenticated, twirp.ResourceExhausted,
+	twirp.FailedPrecondition, twirp.Aborted, twirp.OutOfRange, twirp.Unimplemented, twirp.Internal,
+	twirp.Unavailable, twirp.DataLoss,
+}
+
+// Code returns the Twirp code of the first named error in the chain of err: the code of CodeOf,
+// the code for its catalog code as HTTP status (404 is NotFound), or the code its name ends with
+// ("user_not_found" is NotFound). Otherwise it is Unknown.
+func Code(err error) twirp.ErrorCode {
+	named := module.NamedError(err)
+	if named == nil {
+		return twirp.Unknown
+	}
+	e := module.EncodeError(named)
+	if CodeOf != nil {
+		if code, ok := CodeOf(e); ok {
+			return code
+		}
+	}
+	if code, ok := httpCodes[e.Code]; ok {
+		return code
+	}
+	for _, code := range codes {
+		if name := string(code); e.Name == name || strings.HasSuffix(e.Name, "_"+name) || strings.HasSuffix(e.Name, "."+name) {
+			return code
+		}
+	}
+	return twirp.Unknown
+}
+
+// Error returns a Twirp error with the Code of err, or nil for a nil err. Its message is the description
+// of the first named error in the chain of err, and its name, code, link and data are added as metadata.
+// The Twirp error wraps err, so errors.Is and errors.As keep working on the server side.
+func Error(err error) twirp.Error {
+	if err == nil {
+		return nil
+	}
+	code := Code(err)
+	named := module.NamedError(err)
+	if named == nil {
+		return twirp.WrapError(twirp.NewError(code, err.Error()), err)
+	}
+	e := module.EncodeError(named)
+	te := twirp.NewError(code, e.Desc).WithMeta(KeyName, e.Name)
+	if e.Code != 0 {
+		te = te.WithMeta(KeyCode, strconv.Itoa(e.Code))
+	}
+	if e.Link != "" {
+		te = te.WithMeta(KeyLink, e.Link)
+	}
+	if e.Data != nil {
+		if data, err := json.Marshal(e.Data); err == nil {
+			te = te.WithMeta(KeyData, string(data))
+		}
+	}
+	return twirp.WrapError(te, err)
+}
+
+// FromError reconstructs the RichError described by the metadata of the Twirp error in the chain of err.
+// Other errors are returned unchanged.
+func FromError(err error) error {
+	var te twirp.Error
+	if !errors.As(err, &te) {
+		return err
+	}
+	name := te.Meta(KeyName)
+	if name == "" {
+		return err
+	}
+	e := &rich.RichError{
+		Name: name,
+		Desc: te.Msg(),
+		Link: te.Meta(KeyLink),
+	}
+	e.Code, _ = strconv.Atoi(te.Meta(KeyCode))
+	if data := te.Meta(KeyData); data != "" {
+		json.Unmarshal([]byte(data), &e.Data)
+	}
+	return e
+}
@@ -0,0 +1,61 @@
+package twirperr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	rich "github.com/halliday/go-errors"
+	module "github.com/halliday/go-module"
+
+	"github.com/twitchtv/twirp"
+)
+
+func TestRoundTrip(t *testing.T) {
+	m := module.NewWithOptions("auth", "user_not_found;404;user %s not found;https://example.com/404\n")
+	err := fmt.Errorf("lookup: %w", m.NewError("user_not_found", "bob", "tenant", "acme"))
+
+	te := Error(err)
+	if te.Code() != twirp.NotFound || te.Msg() != "user bob not found" || !errors.Is(te, err) {
+		t.Fatalf("unexpected error %v", te)
+	}
+
+	received := FromError(te)
+	expected := &rich.RichError{
+		Name: "user_not_found",
+		Code: 404,
+		Desc: "user bob not found",
+		Link: "https://example.com/404",
+		Data: map[string]interface{}{"tenant": "acme", module.KeyFormatArgs: []interface{}{"bob"}},
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Fatalf("unexpected error %#v", received)
+	}
+
+	plain := twirp.NewError(twirp.Internal, "boom")
+	if FromError(plain) != plain {
+		t.Fatal("error without metadata was replaced")
+	}
+}
+
+func TestCode(t *testing.T) {
+	m := module.NewWithOptions("auth", "user_not_found;404;user not found\ndb.unavailable;0;database down\nlocked;7;account locked\n")
+	CodeOf = func(e *module.EncodedError) (twirp.ErrorCode, bool) {
+		return twirp.PermissionDenied, e.Code == 7
+	}
+	defer func() { CodeOf = nil }()
+	for err, expected := range map[error]twirp.ErrorCode{
+		errors.New("boom"):                        twirp.Unknown,
+		m.NewError("user_not_found"):              twirp.NotFound,
+		m.NewError("db.unavailable"):              twirp.Unavailable,
+		fmt.Errorf("x: %w", m.NewError("locked")): twirp.PermissionDenied,
+	} {
+		if code := Code(err); code != expected {
+			t.Errorf("Code(%v) = %v, expected %v", err, code, expected)
+		}
+	}
+	if Error(nil) != nil {
+		t.Fatal("error for nil error")
+	}
+}
@@ -0,0 +1,59 @@
+package module
+
+import (
+	"context"
+	"fmt"
+)
+
+// UnknownPolicy decides what happens with message names that are neither in the catalog
+// nor matched by a wildcard entry.
+type UnknownPolicy int
+
+const (
+	// UnknownPanic panics, to find typos early during development. This is the default.
+	UnknownPanic UnknownPolicy = iota
+	// UnknownError logs an Error "unknown_message" and continues like UnknownFallback.
+	UnknownError
+	// UnknownFallback uses the name as description. Since the number of placeholders is unknown,
+	// all arguments except for the context and the cause are stored in Data as "args",
+	// next to the name as "unknown_name".
+	UnknownFallback
+)
+
+const (
+	KeyUnknownName = "unknown_name"
+	KeyArgs        = "args"
+)
+
+func (m *Module) unknown(ctx context.Context, name string, args []interface{}) (code int, desc string, link string, tail []interface{}, _ context.Context, causedBy error) {
+	if m.Unknown == UnknownPanic {
+		panic("Module.lookup(\"" + name + "\"): not found")
+	}
+	var rest []interface{}
+	for _, arg := range args {
+		switch a := arg.(type) {
+		case context.Context:
+			if ctx == nil {
+				ctx = a
+				continue
+			}
+		case error:
+			if causedBy == nil {
+				causedBy = a
+				continue
+			}
+		}
+		rest = append(rest, arg)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tail = []interface{}{KeyUnknownName, name}
+	if len(rest) > 0 {
+		tail = append(tail, KeyArgs, rest)
+	}
+	if m.Unknown == UnknownError {
+		m.log(ctx, Error, "unknown_message", 0, fmt.Sprintf("unknown message %q", name), "", []interface{}{KeyUnknownName, name}, nil)
+	}
+	return 0, name, "", tail, ctx, causedBy
+}
@@ -0,0 +1,34 @@
+package module
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+)
+
+func TestUnknownPolicy(t *testing.T) {
+	var b bytes.Buffer
+	m := NewWithOptions("module", messages, WithLogger(log.New(&b, "", 0)), WithUnknownPolicy(UnknownFallback))
+
+	cause := errors.New("eof")
+	m.Warn("missing", 42, cause, "x")
+	if b.String() != "[WARN ] missing unknown_name=missing args=[42 x] (caused by eof)\n" {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+
+	b.Reset()
+	m.Unknown = UnknownError
+	m.Info("missing")
+	if b.String() != "[ERR  ] unknown message \"missing\" unknown_name=missing\n[INFO ] missing unknown_name=missing\n" {
+		t.Fatalf("unexpected log output %q", b.String())
+	}
+
+	m.Unknown = UnknownPanic
+	defer func() {
+		if recover() == nil {
+			t.Fatal("unknown name did not panic")
+		}
+	}()
+	m.Info("missing")
+}
@@ -0,0 +1,91 @@
+package module
+
+import "sync/atomic"
+
+// SetVerbosity sets the level for V. It is safe to call while the Module is in use.
+func (m *Module) SetVerbosity(n int) {
+	atomic.StoreInt32(&m.verbosity, int32(n))
+}
+
+func (m *Module) Verbosity() int {
+	return int(atomic.LoadInt32(&m.verbosity))
+}
+
+// V returns a Logger that only logs if the verbosity of the Module is at least n.
+// Messages that pass are still subject to the Mask.
+//
+//	m.V(2).Info("cache_miss", key)
+//	if v := m.V(3); v.Enabled() {
+//		v.Printf("cache state: %v", expensiveDump())
+//	}
+func (m *Module) V(n int) Verbose {
+	if m.Verbosity() >= n {
+		return Verbose{m}
+	}
+	return Verbose{}
+}
+
+// Verbose is the Logger returned by Module.V.
+type Verbose struct {
+	m *Module
+}
+
+var _ Logger = Verbose{}
+
+func (v Verbose) Enabled() bool {
+	return v.m != nil
+}
+
+func (v Verbose) Debug(name string, args ...interface{}) {
+	if v.m != nil {
+		v.m.Log(Debug, name, args...)
+	}
+}
+
+func (v Verbose) Trace(name string, args ...interface{}) {
+	if v.m != nil {
+		v.m.Log(Trace, name, args...)
+	}
+}
+
+func (v Verbose) Info(name string, args ...interface{}) {
+	if v.m != nil {
+		v.m.Log(Info, name, args...)
+	}
+}
+
+func (v Verbose) Warn(name string, args ...interface{}) {
+	if v.m != nil {
+		v.m.Log(Warn, name, args...)
+	}
+}
+
+func (v Verbose) Err(name string, args ...interface{}) {
+	if v.m != nil {
+		v.m.Log(Error, name, args...)
+	}
+}
+
+func (v Verbose) Log(level Level, name string, args ...interface{}) {
+	if v.m != nil {
+		v.m.Log(level, name, args...)
+	}
+}
+
+func (v Verbose) Printf(pattern string, args ...interface{}) {
+	if v.m != nil {
+		v.m.Printf(pattern, args...)
+	}
+}
+
+func (v Verbose) Print(msg string, args ...interface{}) {
+	if v.m != nil {
+		v.m.Print(msg, args...)
+	}
+}
+
+func (v Verbose) Report(err error) {
+	if v.m != nil {
+		v.m.Report(err)
+	}
+}
@@ -0,0 +1,25 @@
+package module
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestV(t *testing.T) {
+	var b bytes.Buffer
+	_, _, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+
+	if m.V(1).Enabled() {
+		t.Fatal("V(1) is enabled at verbosity 0")
+	}
+	m.V(1).Info("test")
+	m.SetVerbosity(2)
+	m.V(2).Info("test2")
+	m.V(3).Info("test3")
+
+	if b.String() != "[INFO ] This is a another test message\n" {
+		t.Fatalf("unexpected log output: %q", b.String())
+	}
+}
@@ -0,0 +1,16 @@
+package module
+
+import "context"
+
+type verboseContextKey struct{}
+
+// CtxVerbose returns a context that lets all messages logged with it pass the Mask,
+// including Debug and Trace messages. Use it to debug a single request without changing the level of the Module.
+func CtxVerbose(ctx context.Context) context.Context {
+	return context.WithValue(ctx, verboseContextKey{}, true)
+}
+
+func CtxIsVerbose(ctx context.Context) bool {
+	verbose, _ := ctx.Value(verboseContextKey{}).(bool)
+	return verbose
+}
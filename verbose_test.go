@@ -0,0 +1,26 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+)
+
+func TestCtxVerbose(t *testing.T) {
+	var b bytes.Buffer
+	_, _, m := New("module", messages)
+	m.Logger = log.New(&b, "", 0)
+	m.Mask = Error
+
+	m.Debug("test")
+	m.Info("test")
+	m.Debug("test", CtxVerbose(context.Background()))
+	m.Info("test2", CtxVerbose(context.Background()))
+	m.Trace("test")
+	m.Trace("test", CtxVerbose(context.Background()))
+
+	if b.String() != "[DEBUG] This is a test message\n[INFO ] This is a another test message\n[TRACE] This is a test message\n" {
+		t.Fatalf("unexpected log output: %q", b.String())
+	}
+}
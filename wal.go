@@ -0,0 +1,349 @@
+package module
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/halliday/go-errors"
+)
+
+// WALOptions configure a WAL.
+type WALOptions struct {
+	// SegmentSize starts a new segment file once the current one has SegmentSize bytes. Default 64 MiB.
+	SegmentSize int64
+}
+
+const defaultSegmentSize = 64 << 20
+
+// walExt is the extension of WAL segment files, which are named by their number like "00000001.wal".
+const walExt = ".wal"
+
+// walHeader is the size of the record header: the payload length and its CRC-32C, both big endian.
+const walHeader = 8
+
+var walTable = crc32.MakeTable(crc32.Castagnoli)
+
+// A WAL is a Sink for an AuditModule that makes every message durable before Write returns,
+// so an audit event that was acknowledged to the caller survives a crash:
+//
+//	wal, err := module.OpenWAL("/var/lib/app/audit", module.WALOptions{})
+//	audit := module.NewAudit("audit", messages, wal)
+//
+// Messages are written as checksummed JSON records to numbered segment files in a directory
+// and synced to disk. A record torn by a crash is cut off when the WAL is opened again.
+// Use ReadWAL to read the messages and VerifyWAL (or cmd/modulewal) to check the files.
+type WAL struct {
+	mu   sync.Mutex
+	dir  string
+	opts WALOptions
+	f    *os.File
+	seg  int
+	size int64
+	// err is set if a failed record could not be cut off, all later writes fail with it.
+	err error
+}
+
+// OpenWAL opens the WAL in dir, creating dir if necessary, and continues the last segment.
+func OpenWAL(dir string, opts WALOptions) (*WAL, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = defaultSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	segs, err := walSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	w := &WAL{dir: dir, opts: opts}
+	if len(segs) == 0 {
+		return w, w.create(1)
+	}
+	w.seg = segs[len(segs)-1]
+	path := w.segPath(w.seg)
+	valid, _, err := scanWAL(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	// cut off a record torn by a crash
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.f = f
+	w.size = valid
+	return w, nil
+}
+
+func (w *WAL) segPath(seg int) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%08d", seg)+walExt)
+}
+
+func (w *WAL) create(seg int) error {
+	f, err := os.OpenFile(w.segPath(seg), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(w.dir); err != nil {
+		f.Close()
+		return err
+	}
+	w.f = f
+	w.seg = seg
+	w.size = 0
+	return nil
+}
+
+// Write appends msg and syncs it to disk. If writing or syncing fails, the record is cut off again.
+// If that fails too, the WAL rejects all later writes, so that no record follows a torn one.
+func (w *WAL) Write(msg *Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	record := make([]byte, walHeader+len(payload))
+	binary.BigEndian.PutUint32(record, uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:], crc32.Checksum(payload, walTable))
+	copy(record[walHeader:], payload)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if w.size > 0 && w.size+int64(len(record)) > w.opts.SegmentSize {
+		if err := w.f.Close(); err != nil {
+			return err
+		}
+		w.f = nil
+		if err := w.create(w.seg + 1); err != nil {
+			return err
+		}
+	}
+	_, err = w.f.Write(record)
+	if err == nil {
+		err = w.f.Sync()
+	}
+	if err != nil {
+		w.cut()
+		return err
+	}
+	w.size += int64(len(record))
+	return nil
+}
+
+// cut removes a failed record after the valid ones, or marks the WAL as failed.
+func (w *WAL) cut() {
+	err := w.f.Truncate(w.size)
+	if err == nil {
+		_, err = w.f.Seek(w.size, io.SeekStart)
+	}
+	if err != nil {
+		w.err = fmt.Errorf("wal failed, a record could not be cut off: %w", err)
+	}
+}
+
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// ReadWAL calls fn for the messages of the WAL in dir in order, stopping at the first error.
+// A torn record at the end of the last segment is skipped, as it was never acknowledged.
+func ReadWAL(dir string, fn func(msg *Message) error) error {
+	segs, err := walSegments(dir)
+	if err != nil {
+		return err
+	}
+	for i, seg := range segs {
+		path := filepath.Join(dir, fmt.Sprintf("%08d", seg)+walExt)
+		_, torn, err := scanWAL(path, func(payload []byte) error {
+			var msg Message
+			if err := json.Unmarshal(payload, &msg); err != nil {
+				return err
+			}
+			return fn(&msg)
+		})
+		if err != nil {
+			return err
+		}
+		if torn && i != len(segs)-1 {
+			return fmt.Errorf("%s: torn record before the last segment", path)
+		}
+	}
+	return nil
+}
+
+// A WALReport is the result of VerifyWAL.
+type WALReport struct {
+	Segments int
+	Records  int
+	// Torn is set if the last record of the last segment is incomplete, as after a crash during Write.
+	// It was not acknowledged and is cut off when the WAL is opened.
+	Torn bool
+}
+
+// VerifyWAL checks the WAL in dir: the checksums of all records, that only the last record
+// may be torn, that segments are numbered without gaps, and that the sequence numbers
+// of each module and Epoch are contiguous without duplicates. Concurrently logged messages
+// may be written out of order, so the order of the records is not checked.
+func VerifyWAL(dir string) (report WALReport, err error) {
+	segs, err := walSegments(dir)
+	if err != nil {
+		return report, err
+	}
+	seqs := make(map[walKey][]uint64)
+	var problems errors.Multi
+	for i, seg := range segs {
+		path := filepath.Join(dir, fmt.Sprintf("%08d", seg)+walExt)
+		if i > 0 && seg != segs[i-1]+1 {
+			problems.Append(fmt.Errorf("%s: segment %d is missing", path, segs[i-1]+1))
+		}
+		offset := int64(0)
+		valid, torn, err := scanWAL(path, func(payload []byte) error {
+			var msg Message
+			if err := json.Unmarshal(payload, &msg); err != nil {
+				return fmt.Errorf("%s: offset %d: %w", path, offset, err)
+			}
+			key := walKey{msg.Module, msg.Epoch}
+			seqs[key] = append(seqs[key], msg.Seq)
+			offset += walHeader + int64(len(payload))
+			report.Records++
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+		report.Segments++
+		if torn {
+			if i != len(segs)-1 {
+				problems.Append(fmt.Errorf("%s: offset %d: torn or corrupt record", path, valid))
+			} else {
+				report.Torn = true
+			}
+		}
+	}
+	for key, s := range seqs {
+		sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+		for i := 1; i < len(s); i++ {
+			switch {
+			case s[i] == s[i-1]:
+				problems.Append(fmt.Errorf("%s: seq %d of module %q in epoch %q is duplicated", dir, s[i], key.module, key.epoch))
+			case s[i] != s[i-1]+1:
+				problems.Append(fmt.Errorf("%s: seq %d to %d of module %q in epoch %q are missing", dir, s[i-1]+1, s[i]-1, key.module, key.epoch))
+			}
+		}
+	}
+	return report, problems.Reduce()
+}
+
+type walKey struct {
+	module, epoch string
+}
+
+// walSegments returns the numbers of the segment files in dir, in ascending order.
+func walSegments(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segs []int
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, walExt) {
+			continue
+		}
+		if seg, err := strconv.Atoi(strings.TrimSuffix(name, walExt)); err == nil && seg > 0 {
+			segs = append(segs, seg)
+		}
+	}
+	sort.Ints(segs)
+	return segs, nil
+}
+
+// scanWAL calls fn with the payload of each valid record of the segment at path. It returns the size of
+// the valid records, and whether they are followed by a torn record that ends the file. A corrupt record
+// followed by more data is an error, since it is not explained by a crash during Write.
+func scanWAL(path string, fn func(payload []byte) error) (valid int64, torn bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, false, err
+	}
+	size := info.Size()
+	r := bufio.NewReader(f)
+	var header [walHeader]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return valid, false, nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				return valid, true, nil
+			}
+			return valid, false, err
+		}
+		n := int64(binary.BigEndian.Uint32(header[:]))
+		// records are never empty, a zero header is a tail that was allocated but not written
+		if n == 0 || n > size-valid-walHeader {
+			return valid, true, nil
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return valid, false, err
+		}
+		if crc32.Checksum(payload, walTable) != binary.BigEndian.Uint32(header[4:]) {
+			if valid+walHeader+n < size {
+				return valid, false, fmt.Errorf("%s: offset %d: corrupt record", path, valid)
+			}
+			return valid, true, nil
+		}
+		if fn != nil {
+			if err := fn(payload); err != nil {
+				return valid, false, err
+			}
+		}
+		valid += walHeader + n
+	}
+}
+
+// syncDir syncs the directory entries of dir, so that new files survive a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,189 @@
+package module
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWAL(t *testing.T) {
+	dir := t.TempDir()
+	wal, err := OpenWAL(dir, WALOptions{SegmentSize: 600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAudit("audit", messages, wal)
+	a.Logger = log.New(io.Discard, "", 0)
+	for i := 0; i < 5; i++ {
+		if err := a.Audit("test", "bob", "delete", "file", "A", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Audit("test", "bob", "delete", "file", "A", 5); err == nil {
+		t.Fatal("write to closed WAL was acknowledged")
+	}
+
+	segs, _ := walSegments(dir)
+	if len(segs) < 2 {
+		t.Fatalf("no rotation: %v", segs)
+	}
+	report, err := VerifyWAL(dir)
+	if err != nil || report.Records != 5 || report.Segments != len(segs) || report.Torn {
+		t.Fatalf("unexpected report %+v: %v", report, err)
+	}
+
+	// a record torn by a crash
+	last := filepath.Join(dir, fmt.Sprintf("%08d", segs[len(segs)-1])+walExt)
+	f, err := os.OpenFile(last, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 1, 0, 1, 2, 3, 4, '{'})
+	f.Close()
+	if report, err := VerifyWAL(dir); err != nil || !report.Torn || report.Records != 5 {
+		t.Fatalf("unexpected report of torn WAL %+v: %v", report, err)
+	}
+
+	// a restart starts again at seq 1 with a new epoch
+	defer func(epoch string) { Epoch = epoch }(Epoch)
+	Epoch = newEpoch() + "-restarted"
+	wal, err = OpenWAL(dir, WALOptions{SegmentSize: 600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewAudit("audit", messages, wal)
+	b.Logger = log.New(io.Discard, "", 0)
+	if err := b.Audit("test", "alice", "create", "file"); err != nil {
+		t.Fatal(err)
+	}
+	wal.Close()
+
+	var actors []interface{}
+	if err := ReadWAL(dir, func(msg *Message) error {
+		actors = append(actors, msg.Data[KeyActor])
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(actors) != 6 || actors[0] != "bob" || actors[5] != "alice" {
+		t.Fatalf("unexpected records %v", actors)
+	}
+	if report, err := VerifyWAL(dir); err != nil || report.Torn || report.Records != 6 {
+		t.Fatalf("unexpected report after reopening %+v: %v", report, err)
+	}
+
+	// corruption in the middle of a segment
+	first := filepath.Join(dir, "00000001"+walExt)
+	data, _ := os.ReadFile(first)
+	data[walHeader+2] ^= 0xff
+	os.WriteFile(first, data, 0o600)
+	if _, err := VerifyWAL(dir); err == nil {
+		t.Fatal("corruption was not found")
+	}
+	if _, err := OpenWAL(dir, WALOptions{}); err != nil {
+		t.Fatalf("corruption of an old segment prevents opening: %v", err)
+	}
+}
+
+func TestWALConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	wal, err := OpenWAL(dir, WALOptions{SegmentSize: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAudit("module", messages, wal)
+	a.Logger = log.New(io.Discard, "", 0)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				a.Audit("test", "bob", "delete", "file", "A", i)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if report, err := VerifyWAL(dir); err != nil || report.Records != 400 {
+		t.Fatalf("unexpected report %+v: %v", report, err)
+	}
+
+	// a gap in the sequence numbers
+	segs, _ := walSegments(dir)
+	os.Remove(filepath.Join(dir, fmt.Sprintf("%08d", segs[len(segs)-1])+walExt))
+	wal, err = OpenWAL(dir, WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Sinks = []Sink{wal}
+	a.Audit("test", "bob", "delete", "file")
+	wal.Close()
+	if _, err := VerifyWAL(dir); err == nil || !strings.Contains(err.Error(), "are missing") {
+		t.Fatalf("missing records were not found: %v", err)
+	}
+}
+
+func TestWALFailure(t *testing.T) {
+	dir := t.TempDir()
+	wal, err := OpenWAL(dir, WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAudit("audit", messages, wal)
+	a.Logger = log.New(io.Discard, "", 0)
+	if err := a.Audit("test", "bob", "delete", "file", "A", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// a file that can neither be written nor truncated
+	path := wal.f.Name()
+	wal.f.Close()
+	if wal.f, err = os.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Audit("test", "bob", "delete", "file", "A", 2); err == nil {
+		t.Fatal("failed write was acknowledged")
+	}
+	if wal.err == nil || a.Audit("test", "bob", "delete", "file", "A", 3) == nil {
+		t.Fatal("write after failure was acknowledged")
+	}
+	wal.Close()
+
+	// a tail allocated but not written by a crash
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(make([]byte, 64))
+	f.Close()
+	if report, err := VerifyWAL(dir); err != nil || !report.Torn || report.Records != 1 {
+		t.Fatalf("unexpected report of zero-filled WAL %+v: %v", report, err)
+	}
+	if wal, err = OpenWAL(dir, WALOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	a = NewAudit("audit", messages, wal)
+	a.Logger = log.New(io.Discard, "", 0)
+	if err := a.Audit("test", "alice", "create", "file"); err != nil {
+		t.Fatal(err)
+	}
+	wal.Close()
+	n := 0
+	if err := ReadWAL(dir, func(msg *Message) error {
+		n++
+		return nil
+	}); err != nil || n != 2 {
+		t.Fatalf("read %d records: %v", n, err)
+	}
+}